@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"lox-tw/ast"
+	"lox-tw/parser"
+	"lox-tw/scanner"
+)
+
+func runAst(arguments []string) int {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the tree as JSON instead of S-expressions")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: lox-tw ast [--json] file")
+		return 64
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		return 66
+	}
+
+	tokens, err := scanner.ScanTokens(string(content))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 65
+	}
+
+	stmts, err := parser.ParseTokensToStmts(tokens)
+	if err != nil {
+		return 65
+	}
+
+	if *asJSON {
+		data, err := ast.ToJSON(stmts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 70
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	for _, stmt := range stmts {
+		fmt.Println(ast.AnyPrinter{}.Print(stmt))
+	}
+	return 0
+}
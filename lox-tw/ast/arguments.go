@@ -0,0 +1,12 @@
+package ast
+
+// HasNamedArguments reports whether a call passes any of its arguments by
+// name, in which case they must be matched with the parameters of the callee.
+func HasNamedArguments[T any](call CallExpr[T]) bool {
+	for _, name := range call.Names {
+		if name != nil {
+			return true
+		}
+	}
+	return false
+}
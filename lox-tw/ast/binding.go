@@ -0,0 +1,20 @@
+package ast
+
+// Binding is what the resolver learned about the variable a VarExpr,
+// AssignExpr, ThisExpr or SuperExpr refers to. The parser gives every one of
+// these nodes a Binding of its own, so the resolver attaches its results to
+// the node itself, even when two nodes have the same text.
+type Binding struct {
+	// Variables that are not local are globals.
+	Local bool
+
+	// The number of environments to walk up from the one in use, and the
+	// slot of the variable in that environment, numbered in declaration
+	// order.
+	Depth int
+	Index int
+}
+
+func NewBinding() *Binding {
+	return &Binding{}
+}
@@ -0,0 +1,13 @@
+package ast
+
+// PropertyCache is an inline cache of a GetExpr, filled by the interpreter:
+// the method the property named last time it ran, and the class of the
+// object it was looked up in. The parser gives every GetExpr its own.
+type PropertyCache struct {
+	Class  any
+	Method any
+}
+
+func NewPropertyCache() *PropertyCache {
+	return &PropertyCache{}
+}
@@ -18,6 +18,7 @@ type ExprVisitor[T any] interface {
 	VisitLogicalExpr(expr LogicalExpr[T]) (T, error)
 	VisitLiteralExpr(expr LiteralExpr[T]) (T, error)
 	VisitSuperExpr(expr SuperExpr[T]) (T, error)
+	VisitInnerExpr(expr InnerExpr[T]) (T, error)
 	VisitNothingExpr(expr NothingExpr[T]) (T, error)
 	VisitVarExpr(expr VarExpr[T]) (T, error)
 	VisitAssignExpr(expr AssignExpr[T]) (T, error)
@@ -34,6 +35,7 @@ func (e GroupingExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 
 type TernaryExpr[T any] struct {
 	Condition Expr[T]
+	Question  token.Token
 	TrueExpr  Expr[T]
 	FalseExpr Expr[T]
 }
@@ -65,6 +67,8 @@ type CallExpr[T any] struct {
 	Callee      Expr[T]
 	Parenthesis token.Token
 	Arguments   []Expr[T]
+	Names       []*token.Token
+	Spreads     []*token.Token
 }
 
 func (e CallExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
@@ -74,6 +78,7 @@ func (e CallExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 type GetExpr[T any] struct {
 	Object Expr[T]
 	Name   token.Token
+	Cache  *PropertyCache
 }
 
 func (e GetExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
@@ -92,6 +97,7 @@ func (e SetExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 
 type ThisExpr[T any] struct {
 	Keyword token.Token
+	Binding *Binding
 }
 
 func (e ThisExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
@@ -109,6 +115,7 @@ func (e LogicalExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 }
 
 type LiteralExpr[T any] struct {
+	Token token.Token
 	Value any
 }
 
@@ -119,12 +126,24 @@ func (e LiteralExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 type SuperExpr[T any] struct {
 	Keyword token.Token
 	Method  token.Token
+	Binding *Binding
 }
 
 func (e SuperExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 	return visitor.VisitSuperExpr(e)
 }
 
+type InnerExpr[T any] struct {
+	Keyword     token.Token
+	Parenthesis token.Token
+	Arguments   []Expr[T]
+	Binding     *Binding
+}
+
+func (e InnerExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
+	return visitor.VisitInnerExpr(e)
+}
+
 type NothingExpr[T any] struct {
 }
 
@@ -133,7 +152,8 @@ func (e NothingExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 }
 
 type VarExpr[T any] struct {
-	Name token.Token
+	Name    token.Token
+	Binding *Binding
 }
 
 func (e VarExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
@@ -141,8 +161,9 @@ func (e VarExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 }
 
 type AssignExpr[T any] struct {
-	Name  token.Token
-	Value Expr[T]
+	Name    token.Token
+	Value   Expr[T]
+	Binding *Binding
 }
 
 func (e AssignExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
@@ -150,8 +171,15 @@ func (e AssignExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
 }
 
 type LambdaExpr[T any] struct {
-	Parameters []token.Token
-	Body       []Stmt[T]
+	Keyword        token.Token
+	Parameters     []token.Token
+	ParameterTypes []*token.Token
+	ReturnType     *token.Token
+	Defaults       []Expr[T]
+	Ellipsis       *token.Token
+	Body           []Stmt[T]
+	RightBrace     token.Token
+	Generator      bool
 }
 
 func (e LambdaExpr[T]) Accept(visitor ExprVisitor[T]) (T, error) {
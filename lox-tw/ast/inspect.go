@@ -0,0 +1,42 @@
+package ast
+
+import "reflect"
+
+// Inspect walks the statements depth-first, calling f with every statement
+// and expression, methods included. The children of a node are skipped when
+// f returns false.
+func Inspect(stmts []Stmt[any], f func(node any) bool) {
+	inspect(reflect.ValueOf(stmts), f)
+}
+
+func inspect(value reflect.Value, f func(node any) bool) {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if !value.IsNil() {
+			inspect(value.Elem(), f)
+		}
+	case reflect.Slice:
+		for i := range value.Len() {
+			inspect(value.Index(i), f)
+		}
+	case reflect.Struct:
+		if value.Type() == tokenType {
+			return
+		}
+		if _, ok := nodeTypes[nodeName(value.Type())]; ok && !f(value.Interface()) {
+			return
+		}
+		for i := range value.NumField() {
+			inspect(value.Field(i), f)
+		}
+	}
+}
+
+// Functions returns the methods, class methods, getters and setters of a
+// class, which are stored apart.
+func (s ClassStmt[T]) Functions() []FunctionStmt[T] {
+	functions := append([]FunctionStmt[T]{}, s.Methods...)
+	functions = append(functions, s.GlobalMethods...)
+	functions = append(functions, s.Getters...)
+	return append(functions, s.Setters...)
+}
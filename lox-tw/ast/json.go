@@ -0,0 +1,335 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"lox-tw/token"
+)
+
+// Span locates a node in the source. Start and End are byte offsets, End
+// being exclusive.
+type Span struct {
+	Start     uint `json:"start"`
+	End       uint `json:"end"`
+	StartLine uint `json:"startLine"`
+	EndLine   uint `json:"endLine"`
+}
+
+type jsonToken struct {
+	Type     string `json:"type"`
+	Lexeme   string `json:"lexeme"`
+	Literal  any    `json:"literal"`
+	Line     uint   `json:"line"`
+	Position uint   `json:"position"`
+}
+
+var nodeTypes = nodeTypesByName(
+	GroupingExpr[any]{}, TernaryExpr[any]{}, BinaryExpr[any]{}, UnaryExpr[any]{},
+	CallExpr[any]{}, GetExpr[any]{}, SetExpr[any]{}, ThisExpr[any]{},
+	LogicalExpr[any]{}, LiteralExpr[any]{}, SuperExpr[any]{}, InnerExpr[any]{}, NothingExpr[any]{},
+	VarExpr[any]{}, AssignExpr[any]{}, LambdaExpr[any]{},
+
+	VarStmt[any]{}, ExpressionStmt[any]{}, IfStmt[any]{}, WhileStmt[any]{}, ForInStmt[any]{},
+	PrintStmt[any]{}, ClassStmt[any]{}, TraitStmt[any]{}, BlockStmt[any]{}, BreakStmt[any]{},
+	FunctionStmt[any]{}, ReturnStmt[any]{}, YieldStmt[any]{}, AssertStmt[any]{},
+)
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+// Bindings and caches are left out of the JSON, they are filled by the
+// resolver and the interpreter and not part of the syntax.
+var runtimeTypes = map[reflect.Type]func() reflect.Value{
+	reflect.TypeOf(&Binding{}):       func() reflect.Value { return reflect.ValueOf(NewBinding()) },
+	reflect.TypeOf(&PropertyCache{}): func() reflect.Value { return reflect.ValueOf(NewPropertyCache()) },
+}
+
+// ToJSON serializes statements. Every node is an object with its "type",
+// the "span" it covers and one key per field.
+func ToJSON(stmts []Stmt[any]) ([]byte, error) {
+	value, _ := encode(reflect.ValueOf(stmts))
+	return json.MarshalIndent(value, "", "  ")
+}
+
+// FromJSON deserializes statements produced by ToJSON. Spans are ignored as
+// they can be computed back from the tokens.
+func FromJSON(data []byte) ([]Stmt[any], error) {
+	var stmts []Stmt[any]
+	value, err := decode(data, reflect.TypeOf(stmts))
+	if err != nil {
+		return nil, err
+	}
+
+	return value.Interface().([]Stmt[any]), nil
+}
+
+func nodeTypesByName(nodes ...any) map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	for _, node := range nodes {
+		t := reflect.TypeOf(node)
+		types[nodeName(t)] = t
+	}
+	return types
+}
+
+// Drops the type parameters, VarStmt[interface {}] becomes VarStmt.
+func nodeName(t reflect.Type) string {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	return name
+}
+
+func fieldName(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
+
+func tokenSpan(t token.Token) *Span {
+	startLine := t.Line - uint(strings.Count(t.Lexeme, "\n"))
+	return &Span{
+		Start:     t.Position - uint(len(t.Lexeme)),
+		End:       t.Position,
+		StartLine: startLine,
+		EndLine:   t.Line,
+	}
+}
+
+func mergeSpans(a, b *Span) *Span {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	merged := *a
+	if b.Start < merged.Start {
+		merged.Start, merged.StartLine = b.Start, b.StartLine
+	}
+	if b.End > merged.End {
+		merged.End, merged.EndLine = b.End, b.EndLine
+	}
+	return &merged
+}
+
+// object keeps the keys in insertion order when marshalled.
+type object []field
+
+type field struct {
+	name  string
+	value any
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(f.name)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func encode(value reflect.Value) (any, *Span) {
+	if value.Type() == tokenType {
+		t := value.Interface().(token.Token)
+		if t.Type == token.NOTHING {
+			return nil, nil
+		}
+
+		return jsonToken{
+			Type:     t.Type.String(),
+			Lexeme:   t.Lexeme,
+			Literal:  t.Literal,
+			Line:     t.Line,
+			Position: t.Position,
+		}, tokenSpan(t)
+	}
+
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() {
+			return nil, nil
+		}
+		if value.Elem().Kind() != reflect.Struct {
+			return value.Elem().Interface(), nil
+		}
+		return encode(value.Elem())
+	case reflect.Slice:
+		var span *Span
+		values := []any{}
+		for i := 0; i < value.Len(); i++ {
+			element, elementSpan := encode(value.Index(i))
+			values = append(values, element)
+			span = mergeSpans(span, elementSpan)
+		}
+		return values, span
+	case reflect.Struct:
+		var span *Span
+		node := object{{"type", nodeName(value.Type())}, {"span", nil}}
+		for i := 0; i < value.NumField(); i++ {
+			if _, ok := runtimeTypes[value.Field(i).Type()]; ok {
+				continue
+			}
+			fieldValue, fieldSpan := encode(value.Field(i))
+			node = append(node, field{fieldName(value.Type().Field(i).Name), fieldValue})
+			span = mergeSpans(span, fieldSpan)
+		}
+		node[1].value = span
+		return node, span
+	}
+
+	return value.Interface(), nil
+}
+
+func decode(data json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	if t == tokenType {
+		return decodeToken(data)
+	}
+
+	isNull := bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if isNull {
+			return reflect.Zero(t), nil
+		}
+		if t.NumMethod() == 0 {
+			var literal any
+			if err := json.Unmarshal(data, &literal); err != nil {
+				return reflect.Value{}, err
+			}
+			return reflectValue(literal, t), nil
+		}
+
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			return reflect.Value{}, err
+		}
+		nodeType, ok := nodeTypes[header.Type]
+		if !ok || !nodeType.Implements(t) {
+			return reflect.Value{}, fmt.Errorf("unexpected node type '%s'", header.Type)
+		}
+
+		node, err := decode(data, nodeType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value := reflect.New(t).Elem()
+		value.Set(node)
+		return value, nil
+	case reflect.Pointer:
+		if isNull {
+			return reflect.Zero(t), nil
+		}
+
+		element, err := decode(data, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		pointer := reflect.New(t.Elem())
+		pointer.Elem().Set(element)
+		return pointer, nil
+	case reflect.Slice:
+		if isNull {
+			return reflect.Zero(t), nil
+		}
+
+		var elements []json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return reflect.Value{}, err
+		}
+		slice := reflect.MakeSlice(t, 0, len(elements))
+		for _, element := range elements {
+			value, err := decode(element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice = reflect.Append(slice, value)
+		}
+		return slice, nil
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return reflect.Value{}, err
+		}
+		node := reflect.New(t).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			if allocate, ok := runtimeTypes[t.Field(i).Type]; ok {
+				node.Field(i).Set(allocate())
+				continue
+			}
+
+			raw, ok := fields[fieldName(t.Field(i).Name)]
+			if !ok {
+				continue
+			}
+
+			value, err := decode(raw, t.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			node.Field(i).Set(value)
+		}
+		return node, nil
+	case reflect.Bool:
+		var b bool
+		if err := json.Unmarshal(data, &b); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("unsupported field type %s", t)
+}
+
+func decodeToken(data json.RawMessage) (reflect.Value, error) {
+	var t *jsonToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return reflect.Value{}, err
+	}
+	if t == nil {
+		return reflect.ValueOf(token.Token{}), nil
+	}
+
+	tokenType, ok := token.ParseTokenType(t.Type)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown token type '%s'", t.Type)
+	}
+
+	return reflect.ValueOf(token.Token{
+		Type:     tokenType,
+		Lexeme:   t.Lexeme,
+		Literal:  t.Literal,
+		Line:     t.Line,
+		Position: t.Position,
+	}), nil
+}
+
+func reflectValue(value any, t reflect.Type) reflect.Value {
+	if value == nil {
+		return reflect.Zero(t)
+	}
+
+	result := reflect.New(t).Elem()
+	result.Set(reflect.ValueOf(value))
+	return result
+}
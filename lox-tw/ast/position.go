@@ -0,0 +1,79 @@
+package ast
+
+import "lox-tw/token"
+
+// FirstToken returns the earliest token stored in a statement. Keywords are
+// preferred, so for most statements this is the token the statement starts
+// with.
+func FirstToken[T any](stmt Stmt[T]) token.Token {
+	switch s := stmt.(type) {
+	case VarStmt[T]:
+		return s.Name
+	case ExpressionStmt[T]:
+		return FirstExprToken(s.Expression)
+	case IfStmt[T]:
+		return s.Keyword
+	case WhileStmt[T]:
+		return s.Keyword
+	case ForInStmt[T]:
+		return s.Keyword
+	case PrintStmt[T]:
+		return s.Keyword
+	case ClassStmt[T]:
+		return s.Name
+	case TraitStmt[T]:
+		return s.Name
+	case BlockStmt[T]:
+		return s.LeftBrace
+	case BreakStmt[T]:
+		return s.Keyword
+	case FunctionStmt[T]:
+		return s.Name
+	case ReturnStmt[T]:
+		return s.Keyword
+	case YieldStmt[T]:
+		return s.Keyword
+	case AssertStmt[T]:
+		return s.Keyword
+	}
+
+	return token.Token{}
+}
+
+// FirstExprToken returns the leftmost token stored in an expression.
+func FirstExprToken[T any](expr Expr[T]) token.Token {
+	switch e := expr.(type) {
+	case GroupingExpr[T]:
+		return FirstExprToken(e.Expression)
+	case TernaryExpr[T]:
+		return FirstExprToken(e.Condition)
+	case BinaryExpr[T]:
+		return FirstExprToken(e.Left)
+	case UnaryExpr[T]:
+		return e.Operator
+	case CallExpr[T]:
+		return FirstExprToken(e.Callee)
+	case GetExpr[T]:
+		return FirstExprToken(e.Object)
+	case SetExpr[T]:
+		return FirstExprToken(e.Object)
+	case ThisExpr[T]:
+		return e.Keyword
+	case LogicalExpr[T]:
+		return FirstExprToken(e.Left)
+	case LiteralExpr[T]:
+		return e.Token
+	case SuperExpr[T]:
+		return e.Keyword
+	case InnerExpr[T]:
+		return e.Keyword
+	case VarExpr[T]:
+		return e.Name
+	case AssignExpr[T]:
+		return e.Name
+	case LambdaExpr[T]:
+		return e.Keyword
+	}
+
+	return token.Token{}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"lox-tw/token"
 )
 
 type AnyPrinter struct{}
@@ -61,29 +63,66 @@ func (p AnyPrinter) VisitVarExpr(expr VarExpr[any]) (any, error) {
 }
 
 func (p AnyPrinter) VisitAssignExpr(expr AssignExpr[any]) (any, error) {
-	return fmt.Sprintf("(%s = %s)", expr.Name.Lexeme, expr.Value), nil
+	value, _ := expr.Value.Accept(p)
+	return fmt.Sprintf("(%s = %s)", expr.Name.Lexeme, value), nil
 }
 
 func (p AnyPrinter) VisitCallExpr(expr CallExpr[any]) (any, error) {
 	callee, _ := expr.Callee.Accept(p)
 	var arguments []string
-	for _, arg := range expr.Arguments {
+	for i, arg := range expr.Arguments {
 		argStr, _ := arg.Accept(p)
+		if i < len(expr.Names) && expr.Names[i] != nil {
+			argStr = fmt.Sprintf("%s:%v", expr.Names[i].Lexeme, argStr)
+		}
+		if i < len(expr.Spreads) && expr.Spreads[i] != nil {
+			argStr = fmt.Sprintf("...%v", argStr)
+		}
 		arguments = append(arguments, fmt.Sprintf("%v", argStr))
 	}
 	return fmt.Sprintf("(call %s (%s))", callee, strings.Join(arguments, " ")), nil
 }
 
+func (p AnyPrinter) VisitInnerExpr(expr InnerExpr[any]) (any, error) {
+	var arguments []string
+	for _, arg := range expr.Arguments {
+		argStr, _ := arg.Accept(p)
+		arguments = append(arguments, fmt.Sprintf("%v", argStr))
+	}
+	return fmt.Sprintf("(inner (%s))", strings.Join(arguments, " ")), nil
+}
+
 func (p AnyPrinter) VisitLambdaExpr(expr LambdaExpr[any]) (any, error) {
-	var parameters []string
-	for _, param := range expr.Parameters {
-		parameters = append(parameters, param.Lexeme)
+	return fmt.Sprintf("(lambda %s%s)", p.signature(expr.Parameters, expr.ParameterTypes, expr.Defaults, expr.Ellipsis, expr.ReturnType), p.printStmts(expr.Body)), nil
+}
+
+// signature prints parameters and return type, annotated ones as name:Type
+// and the ones with a default value as name=value.
+func (p AnyPrinter) signature(parameters []token.Token, parameterTypes []*token.Token, defaults []Expr[any], ellipsis *token.Token, returnType *token.Token) string {
+	var names []string
+	for i, param := range parameters {
+		var paramType *token.Token
+		if i < len(parameterTypes) {
+			paramType = parameterTypes[i]
+		}
+		name := typed(param.Lexeme, paramType)
+		if i < len(defaults) && defaults[i] != nil {
+			value, _ := defaults[i].Accept(p)
+			name += fmt.Sprintf("=%v", value)
+		}
+		if ellipsis != nil && i == len(parameters)-1 {
+			name = "..." + name
+		}
+		names = append(names, name)
 	}
-	var bodyStrings []string
-	for _, stmt := range expr.Body {
-		bodyStrings = append(bodyStrings, fmt.Sprintf("%v", stmt))
+	return typed("("+strings.Join(names, " ")+")", returnType)
+}
+
+func typed(name string, annotation *token.Token) string {
+	if annotation == nil {
+		return name
 	}
-	return fmt.Sprintf("(lambda (%s) {\n%s\n})", strings.Join(parameters, " "), strings.Join(bodyStrings, "\n")), nil
+	return name + ":" + annotation.Lexeme
 }
 
 func (p AnyPrinter) VisitGetExpr(expr GetExpr[any]) (any, error) {
@@ -102,5 +141,158 @@ func (p AnyPrinter) VisitThisExpr(expr ThisExpr[any]) (any, error) {
 }
 
 func (p AnyPrinter) VisitSuperExpr(expr SuperExpr[any]) (any, error) {
-	return "super", nil
+	return fmt.Sprintf("(super %s)", expr.Method.Lexeme), nil
+}
+
+// Print returns the S-expression of a statement.
+func (p AnyPrinter) Print(stmt Stmt[any]) string {
+	printer := &stmtPrinter{exprPrinter: p}
+	stmt.Accept(printer)
+	return printer.result
+}
+
+// printStmts prints each statement preceded by a space, ready to be appended
+// to the S-expression of the enclosing node.
+func (p AnyPrinter) printStmts(stmts []Stmt[any]) string {
+	var result string
+	for _, stmt := range stmts {
+		result += " " + p.Print(stmt)
+	}
+	return result
+}
+
+// Statement visitors only return an error, so the printed statement is kept
+// in result.
+type stmtPrinter struct {
+	exprPrinter AnyPrinter
+	result      string
+}
+
+func (p *stmtPrinter) expr(expr Expr[any]) string {
+	value, _ := expr.Accept(p.exprPrinter)
+	return fmt.Sprintf("%v", value)
+}
+
+func (p *stmtPrinter) VisitVarStmt(stmt VarStmt[any]) error {
+	if _, ok := stmt.Initializer.(NothingExpr[any]); ok || stmt.Initializer == nil {
+		p.result = fmt.Sprintf("(define %s)", typed(stmt.Name.Lexeme, stmt.VariableType))
+		return nil
+	}
+
+	p.result = fmt.Sprintf("(define %s %s)", typed(stmt.Name.Lexeme, stmt.VariableType), p.expr(stmt.Initializer))
+	return nil
+}
+
+func (p *stmtPrinter) VisitExpressionStmt(stmt ExpressionStmt[any]) error {
+	p.result = fmt.Sprintf("(; %s)", p.expr(stmt.Expression))
+	return nil
+}
+
+func (p *stmtPrinter) VisitIfStmt(stmt IfStmt[any]) error {
+	thenBranch := p.exprPrinter.Print(stmt.ThenBranch)
+	if stmt.ElseBranch == nil {
+		p.result = fmt.Sprintf("(if %s %s)", p.expr(stmt.Condition), thenBranch)
+		return nil
+	}
+
+	elseBranch := p.exprPrinter.Print(stmt.ElseBranch)
+	p.result = fmt.Sprintf("(if-else %s %s %s)", p.expr(stmt.Condition), thenBranch, elseBranch)
+	return nil
+}
+
+func (p *stmtPrinter) VisitWhileStmt(stmt WhileStmt[any]) error {
+	p.result = fmt.Sprintf("(while %s %s)", p.expr(stmt.Condition), p.exprPrinter.Print(stmt.Body))
+	return nil
+}
+
+func (p *stmtPrinter) VisitForInStmt(stmt ForInStmt[any]) error {
+	p.result = fmt.Sprintf("(for-in %s %s %s)", typed(stmt.Name.Lexeme, stmt.VariableType), p.expr(stmt.Iterable), p.exprPrinter.Print(stmt.Body))
+	return nil
+}
+
+func (p *stmtPrinter) VisitPrintStmt(stmt PrintStmt[any]) error {
+	p.result = fmt.Sprintf("(print %s)", p.expr(stmt.Expression))
+	return nil
+}
+
+func (p *stmtPrinter) VisitClassStmt(stmt ClassStmt[any]) error {
+	result := "(class " + stmt.Name.Lexeme
+	if stmt.Superclass != nil {
+		result += " < " + stmt.Superclass.Name.Lexeme
+	}
+	if len(stmt.Traits) > 0 {
+		result += " (with"
+		for _, trait := range stmt.Traits {
+			result += " " + trait.Name.Lexeme
+		}
+		result += ")"
+	}
+	for _, method := range stmt.Methods {
+		result += " " + p.exprPrinter.Print(method)
+	}
+	for _, method := range stmt.GlobalMethods {
+		result += " (class " + p.exprPrinter.Print(method) + ")"
+	}
+	for _, getter := range stmt.Getters {
+		result += " (get " + p.exprPrinter.Print(getter) + ")"
+	}
+	for _, setter := range stmt.Setters {
+		result += " (set " + p.exprPrinter.Print(setter) + ")"
+	}
+	p.result = result + ")"
+	return nil
+}
+
+func (p *stmtPrinter) VisitTraitStmt(stmt TraitStmt[any]) error {
+	result := "(trait " + stmt.Name.Lexeme
+	for _, method := range stmt.Methods {
+		result += " " + p.exprPrinter.Print(method)
+	}
+	p.result = result + ")"
+	return nil
+}
+
+func (p *stmtPrinter) VisitBlockStmt(stmt BlockStmt[any]) error {
+	p.result = fmt.Sprintf("(block%s)", p.exprPrinter.printStmts(stmt.Statements))
+	return nil
+}
+
+func (p *stmtPrinter) VisitBreakStmt(stmt BreakStmt[any]) error {
+	p.result = "(break)"
+	return nil
+}
+
+func (p *stmtPrinter) VisitFunctionStmt(stmt FunctionStmt[any]) error {
+	p.result = fmt.Sprintf("(fun %s %s%s)", stmt.Name.Lexeme, p.exprPrinter.signature(stmt.Parameters, stmt.ParameterTypes, stmt.Defaults, stmt.Ellipsis, stmt.ReturnType), p.exprPrinter.printStmts(stmt.Body))
+	return nil
+}
+
+func (p *stmtPrinter) VisitAssertStmt(stmt AssertStmt[any]) error {
+	if stmt.Message == nil {
+		p.result = fmt.Sprintf("(assert %s)", p.expr(stmt.Condition))
+		return nil
+	}
+
+	p.result = fmt.Sprintf("(assert %s %s)", p.expr(stmt.Condition), p.expr(stmt.Message))
+	return nil
+}
+
+func (p *stmtPrinter) VisitReturnStmt(stmt ReturnStmt[any]) error {
+	if stmt.Value == nil {
+		p.result = "(return)"
+		return nil
+	}
+
+	p.result = fmt.Sprintf("(return %s)", p.expr(stmt.Value))
+	return nil
+}
+
+func (p *stmtPrinter) VisitYieldStmt(stmt YieldStmt[any]) error {
+	if stmt.Value == nil {
+		p.result = "(yield)"
+		return nil
+	}
+
+	p.result = fmt.Sprintf("(yield %s)", p.expr(stmt.Value))
+	return nil
 }
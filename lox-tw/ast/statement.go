@@ -11,17 +11,22 @@ type StmtVisitor[T any] interface {
 	VisitExpressionStmt(stmt ExpressionStmt[T]) error
 	VisitIfStmt(stmt IfStmt[T]) error
 	VisitWhileStmt(stmt WhileStmt[T]) error
+	VisitForInStmt(stmt ForInStmt[T]) error
 	VisitPrintStmt(stmt PrintStmt[T]) error
 	VisitClassStmt(stmt ClassStmt[T]) error
+	VisitTraitStmt(stmt TraitStmt[T]) error
 	VisitBlockStmt(stmt BlockStmt[T]) error
 	VisitBreakStmt(stmt BreakStmt[T]) error
 	VisitFunctionStmt(stmt FunctionStmt[T]) error
 	VisitReturnStmt(stmt ReturnStmt[T]) error
+	VisitYieldStmt(stmt YieldStmt[T]) error
+	VisitAssertStmt(stmt AssertStmt[T]) error
 }
 
 type VarStmt[T any] struct {
-	Name        token.Token
-	Initializer Expr[T]
+	Name         token.Token
+	VariableType *token.Token
+	Initializer  Expr[T]
 }
 
 func (e VarStmt[T]) Accept(visitor StmtVisitor[T]) error {
@@ -37,6 +42,7 @@ func (e ExpressionStmt[T]) Accept(visitor StmtVisitor[T]) error {
 }
 
 type IfStmt[T any] struct {
+	Keyword    token.Token
 	Condition  Expr[T]
 	ThenBranch Stmt[T]
 	ElseBranch Stmt[T]
@@ -47,6 +53,7 @@ func (e IfStmt[T]) Accept(visitor StmtVisitor[T]) error {
 }
 
 type WhileStmt[T any] struct {
+	Keyword   token.Token
 	Condition Expr[T]
 	Body      Stmt[T]
 }
@@ -55,7 +62,20 @@ func (e WhileStmt[T]) Accept(visitor StmtVisitor[T]) error {
 	return visitor.VisitWhileStmt(e)
 }
 
+type ForInStmt[T any] struct {
+	Keyword      token.Token
+	Name         token.Token
+	VariableType *token.Token
+	Iterable     Expr[T]
+	Body         Stmt[T]
+}
+
+func (e ForInStmt[T]) Accept(visitor StmtVisitor[T]) error {
+	return visitor.VisitForInStmt(e)
+}
+
 type PrintStmt[T any] struct {
+	Keyword    token.Token
 	Expression Expr[T]
 }
 
@@ -66,16 +86,32 @@ func (e PrintStmt[T]) Accept(visitor StmtVisitor[T]) error {
 type ClassStmt[T any] struct {
 	Name          token.Token
 	Superclass    *VarExpr[T]
+	Traits        []VarExpr[T]
 	Methods       []FunctionStmt[T]
 	GlobalMethods []FunctionStmt[T]
+	Getters       []FunctionStmt[T]
+	Setters       []FunctionStmt[T]
+	RightBrace    token.Token
 }
 
 func (e ClassStmt[T]) Accept(visitor StmtVisitor[T]) error {
 	return visitor.VisitClassStmt(e)
 }
 
+type TraitStmt[T any] struct {
+	Name       token.Token
+	Methods    []FunctionStmt[T]
+	RightBrace token.Token
+}
+
+func (e TraitStmt[T]) Accept(visitor StmtVisitor[T]) error {
+	return visitor.VisitTraitStmt(e)
+}
+
 type BlockStmt[T any] struct {
+	LeftBrace  token.Token
 	Statements []Stmt[T]
+	RightBrace token.Token
 }
 
 func (e BlockStmt[T]) Accept(visitor StmtVisitor[T]) error {
@@ -83,6 +119,7 @@ func (e BlockStmt[T]) Accept(visitor StmtVisitor[T]) error {
 }
 
 type BreakStmt[T any] struct {
+	Keyword token.Token
 }
 
 func (e BreakStmt[T]) Accept(visitor StmtVisitor[T]) error {
@@ -90,9 +127,15 @@ func (e BreakStmt[T]) Accept(visitor StmtVisitor[T]) error {
 }
 
 type FunctionStmt[T any] struct {
-	Name       token.Token
-	Parameters []token.Token
-	Body       []Stmt[T]
+	Name           token.Token
+	Parameters     []token.Token
+	ParameterTypes []*token.Token
+	ReturnType     *token.Token
+	Defaults       []Expr[T]
+	Ellipsis       *token.Token
+	Body           []Stmt[T]
+	RightBrace     token.Token
+	Generator      bool
 }
 
 func (e FunctionStmt[T]) Accept(visitor StmtVisitor[T]) error {
@@ -107,3 +150,22 @@ type ReturnStmt[T any] struct {
 func (e ReturnStmt[T]) Accept(visitor StmtVisitor[T]) error {
 	return visitor.VisitReturnStmt(e)
 }
+
+type YieldStmt[T any] struct {
+	Keyword token.Token
+	Value   Expr[T]
+}
+
+func (e YieldStmt[T]) Accept(visitor StmtVisitor[T]) error {
+	return visitor.VisitYieldStmt(e)
+}
+
+type AssertStmt[T any] struct {
+	Keyword   token.Token
+	Condition Expr[T]
+	Message   Expr[T]
+}
+
+func (e AssertStmt[T]) Accept(visitor StmtVisitor[T]) error {
+	return visitor.VisitAssertStmt(e)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"lox-tw/bench"
+)
+
+func runBench(arguments []string) int {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	engine := flags.String("engine", "all", "run the benchmarks on the tree-walker (tree), the bytecode VM (vm) or both (all)")
+	duration := flags.Duration("time", 500*time.Millisecond, "time spent running each benchmark")
+	jsonOutput := flags.Bool("json", false, "print the results as JSON, to be saved as a baseline")
+	baselinePath := flags.String("baseline", "", "compare the results with the ones saved in `file`, failing on regressions")
+	threshold := flags.Float64("threshold", 0.1, "relative growth of the time or allocations of a benchmark counted as a regression")
+	flags.Parse(arguments)
+
+	if *engine != "all" && !slices.Contains(bench.Engines, *engine) {
+		fmt.Println("Usage: lox-tw bench [--engine tree|vm|all] [--time duration] [--json] [--baseline file] [--threshold ratio] [files...]")
+		return 64
+	}
+
+	engines := bench.Engines
+	if *engine != "all" {
+		engines = []string{*engine}
+	}
+
+	var baseline []bench.Result
+	if *baselinePath != "" {
+		file, err := os.Open(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			return 66
+		}
+		baseline, err = bench.ReadJSON(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			return 66
+		}
+	}
+
+	programs := bench.Programs
+	if flags.NArg() > 0 {
+		programs = nil
+		for _, path := range flags.Args() {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+				return 66
+			}
+			programs = append(programs, bench.Program{Name: filepath.ToSlash(path), Source: string(content)})
+		}
+	}
+
+	var results []bench.Result
+	for _, program := range programs {
+		for _, engine := range engines {
+			result, err := bench.Measure(program, engine, *duration)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s/%s: %v\n", program.Name, engine, err)
+				return 70
+			}
+			results = append(results, result)
+
+			if !*jsonOutput && baseline == nil {
+				fmt.Printf("%-24s %8d %14s/op %12d B/op %10d allocs/op\n", result.Name+"/"+result.Engine, result.Iterations, formatDuration(result.NsPerOp), result.BytesPerOp, result.AllocsPerOp)
+			}
+		}
+	}
+
+	if baseline == nil {
+		if *jsonOutput {
+			bench.WriteJSON(os.Stdout, results)
+		}
+		return 0
+	}
+
+	comparisons := bench.Compare(results, baseline, *threshold)
+	regressions := 0
+	for _, comparison := range comparisons {
+		if comparison.Regressed {
+			regressions++
+		}
+	}
+
+	if *jsonOutput {
+		bench.WriteJSON(os.Stdout, comparisons)
+	} else {
+		for _, comparison := range comparisons {
+			status := "ok  "
+			if comparison.Regressed {
+				status = "FAIL"
+			}
+			result := comparison.Result
+			fmt.Printf("%s %-24s %14s/op %+7.1f%% %10d allocs/op %+7.1f%%\n", status, result.Name+"/"+result.Engine, formatDuration(result.NsPerOp), comparison.TimeChange*100, result.AllocsPerOp, comparison.AllocsChange*100)
+		}
+		fmt.Printf("%d compared, %d regressed.\n", len(comparisons), regressions)
+	}
+
+	if regressions > 0 {
+		return 1
+	}
+	return 0
+}
+
+func formatDuration(nanoseconds float64) string {
+	return time.Duration(nanoseconds).String()
+}
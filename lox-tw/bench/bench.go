@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"lox-tw/interpreter"
+	"lox-tw/optimizer"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+	"lox-tw/vm"
+)
+
+// Engines are the ways programs can be run, as named by the --engine flag.
+var Engines = []string{"tree", "vm"}
+
+// Program is a Lox script to measure.
+type Program struct {
+	Name   string
+	Source string
+}
+
+// Result is the average cost of running a program once on an engine.
+type Result struct {
+	Name        string  `json:"name"`
+	Engine      string  `json:"engine"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"nsPerOp"`
+	BytesPerOp  uint64  `json:"bytesPerOp"`
+	AllocsPerOp uint64  `json:"allocsPerOp"`
+}
+
+// Prepare scans, parses, resolves and optimizes a program like lox-tw does
+// before running it, and returns a function running it once, on a fresh
+// engine each time.
+func Prepare(program Program, engine string) (func(stdout io.Writer) error, error) {
+	tokens, err := scanner.ScanTokens(program.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, parseErrors := parser.ParseTokensToStmtsWithErrors(tokens)
+	if len(parseErrors) > 0 {
+		return nil, errors.Join(parseErrors...)
+	}
+
+	if resolveErrors := resolver.NewResolver().Resolve(stmts); len(resolveErrors) > 0 {
+		return nil, errors.Join(resolveErrors...)
+	}
+	stmts = optimizer.Optimize(stmts)
+
+	switch engine {
+	case "tree":
+		return func(stdout io.Writer) error {
+			codeInterpreter := interpreter.NewInterpreter()
+			codeInterpreter.SetOutput(stdout)
+			for _, stmt := range stmts {
+				if err := codeInterpreter.Execute(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case "vm":
+		scripts, compileErrors := vm.Compile(stmts)
+		if len(compileErrors) > 0 {
+			return nil, errors.Join(compileErrors...)
+		}
+
+		return func(stdout io.Writer) error {
+			machine := vm.NewVM()
+			machine.SetOutput(stdout)
+			for _, script := range scripts {
+				if err := machine.Run(script); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown engine '%s'.", engine)
+}
+
+// Measure runs a program once to warm up, then again and again for at least
+// duration, and returns the average cost of a run.
+func Measure(program Program, engine string, duration time.Duration) (Result, error) {
+	run, err := Prepare(program, engine)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := run(io.Discard); err != nil {
+		return Result{}, err
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	iterations := 0
+	start := time.Now()
+	for iterations == 0 || time.Since(start) < duration {
+		if err := run(io.Discard); err != nil {
+			return Result{}, err
+		}
+		iterations++
+	}
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	return Result{
+		Name:        program.Name,
+		Engine:      engine,
+		Iterations:  iterations,
+		NsPerOp:     float64(elapsed.Nanoseconds()) / float64(iterations),
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(iterations),
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / uint64(iterations),
+	}, nil
+}
+
+// WriteJSON writes results, or comparisons, as indented JSON.
+func WriteJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// ReadJSON reads results written by WriteJSON, such as a saved baseline.
+func ReadJSON(r io.Reader) ([]Result, error) {
+	var results []Result
+	if err := json.NewDecoder(r).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,108 @@
+package bench
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func BenchmarkPrograms(b *testing.B) {
+	for _, program := range Programs {
+		for _, engine := range Engines {
+			b.Run(program.Name+"/"+engine, func(b *testing.B) {
+				run, err := Prepare(program, engine)
+				if err != nil {
+					b.Fatalf("Error preparing %s: %v", program.Name, err)
+				}
+
+				b.ReportAllocs()
+				for b.Loop() {
+					if err := run(io.Discard); err != nil {
+						b.Fatalf("Error running %s: %v", program.Name, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestProgramsRunTheSameOnEveryEngine(t *testing.T) {
+	for _, program := range Programs {
+		var outputs []string
+		for _, engine := range Engines {
+			run, err := Prepare(program, engine)
+			if err != nil {
+				t.Fatalf("Error preparing %s: %v", program.Name, err)
+			}
+
+			var output strings.Builder
+			if err := run(&output); err != nil {
+				t.Fatalf("Error running %s on %s: %v", program.Name, engine, err)
+			}
+			outputs = append(outputs, output.String())
+		}
+
+		if outputs[0] != outputs[1] {
+			t.Errorf("%s printed %q on the tree-walker and %q on the VM", program.Name, outputs[0], outputs[1])
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	result, err := Measure(Program{"loop", "for (var i = 0; i < 10; i = i + 1) {}"}, "vm", 0)
+	if err != nil {
+		t.Fatalf("Error measuring: %v", err)
+	}
+
+	if result.Name != "loop" || result.Engine != "vm" || result.Iterations != 1 || result.NsPerOp <= 0 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	if _, err := Measure(Program{"error", "print -nil;"}, "tree", 0); err == nil {
+		t.Errorf("Expected the runtime error of the program")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := []Result{
+		{Name: "fib", Engine: "tree", NsPerOp: 1000, AllocsPerOp: 100},
+		{Name: "fib", Engine: "vm", NsPerOp: 1000, AllocsPerOp: 0},
+		{Name: "loops", Engine: "tree", NsPerOp: 1000, AllocsPerOp: 100},
+	}
+	results := []Result{
+		{Name: "fib", Engine: "tree", NsPerOp: 1050, AllocsPerOp: 90},
+		{Name: "fib", Engine: "vm", NsPerOp: 900, AllocsPerOp: 2},
+		{Name: "loops", Engine: "tree", NsPerOp: 1200, AllocsPerOp: 100},
+		{Name: "strings", Engine: "tree", NsPerOp: 1000, AllocsPerOp: 100},
+	}
+
+	comparisons := Compare(results, baseline, 0.1)
+	if len(comparisons) != 3 {
+		t.Fatalf("Expected 3 comparisons, got %d", len(comparisons))
+	}
+
+	expected := []bool{false, true, true}
+	for i, comparison := range comparisons {
+		if comparison.Regressed != expected[i] {
+			t.Errorf("Expected %s/%s regressed to be %v, got %+v", comparison.Result.Name, comparison.Result.Engine, expected[i], comparison)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	results := []Result{{Name: "fib", Engine: "tree", Iterations: 3, NsPerOp: 1.5, BytesPerOp: 2, AllocsPerOp: 1}}
+
+	var buffer bytes.Buffer
+	if err := WriteJSON(&buffer, results); err != nil {
+		t.Fatalf("Error writing JSON: %v", err)
+	}
+	read, err := ReadJSON(&buffer)
+	if err != nil {
+		t.Fatalf("Error reading JSON: %v", err)
+	}
+
+	if len(read) != 1 || read[0] != results[0] {
+		t.Errorf("Expected %+v, got %+v", results, read)
+	}
+}
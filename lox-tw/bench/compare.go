@@ -0,0 +1,47 @@
+package bench
+
+// Comparison is a result next to the result of the same program on the same
+// engine in a baseline. Changes are relative, 0.1 meaning 10% more.
+type Comparison struct {
+	Result       Result  `json:"result"`
+	Baseline     Result  `json:"baseline"`
+	TimeChange   float64 `json:"timeChange"`
+	AllocsChange float64 `json:"allocsChange"`
+	Regressed    bool    `json:"regressed"`
+}
+
+// Compare pairs results with the baseline. A result regressed when its time
+// or its allocations grew by more than threshold. Results missing from the
+// baseline are left out.
+func Compare(results []Result, baseline []Result, threshold float64) []Comparison {
+	type key struct{ name, engine string }
+	baselines := make(map[key]Result)
+	for _, result := range baseline {
+		baselines[key{result.Name, result.Engine}] = result
+	}
+
+	var comparisons []Comparison
+	for _, result := range results {
+		old, ok := baselines[key{result.Name, result.Engine}]
+		if !ok {
+			continue
+		}
+
+		comparison := Comparison{
+			Result:       result,
+			Baseline:     old,
+			TimeChange:   change(old.NsPerOp, result.NsPerOp),
+			AllocsChange: change(float64(old.AllocsPerOp), float64(result.AllocsPerOp)),
+		}
+		comparison.Regressed = comparison.TimeChange > threshold || comparison.AllocsChange > threshold
+		comparisons = append(comparisons, comparison)
+	}
+
+	return comparisons
+}
+
+// change is relative to at least 1, for programs going from no allocation
+// to some to count as a regression without an infinite change.
+func change(old float64, new float64) float64 {
+	return (new - old) / max(old, 1)
+}
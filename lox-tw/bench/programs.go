@@ -0,0 +1,87 @@
+package bench
+
+// Programs is the suite run by lox-tw bench. Each program stresses one kind
+// of work and runs for a few milliseconds.
+var Programs = []Program{
+	{"fib", `fun fib(n) {
+    if (n < 2) return n;
+    return fib(n - 1) + fib(n - 2);
+}
+
+print fib(20);
+`},
+	{"loops", `var sum = 0;
+for (var i = 0; i < 200; i = i + 1) {
+    var j = 0;
+    while (j < 100) {
+        sum = sum + i * j;
+        j = j + 1;
+    }
+}
+print sum;
+`},
+	{"methods", `class Shape {
+    init(size) { this.size = size; }
+    scale(n) { this.size = this.size * n; return this; }
+}
+
+class Square < Shape {
+    area() { return this.size * this.size; }
+}
+
+var square = Square(1);
+var total = 0;
+for (var i = 0; i < 5000; i = i + 1) {
+    total = total + square.scale(1).area();
+}
+print total;
+`},
+	{"fields", `class Point {}
+
+var point = Point();
+point.x = 0;
+point.y = 0;
+for (var i = 0; i < 5000; i = i + 1) {
+    point.x = point.x + 1;
+    point.y = point.x - point.y;
+}
+print point.x + point.y;
+`},
+	{"strings", `var result = "";
+for (var i = 0; i < 2000; i = i + 1) {
+    result = result + "lox";
+    if (result == "never") print result;
+}
+print result == "";
+`},
+	{"closures", `fun makeCounter() {
+    var count = 0;
+    fun counter() {
+        count = count + 1;
+        return count;
+    }
+    return counter;
+}
+
+var total = 0;
+for (var i = 0; i < 1000; i = i + 1) {
+    var counter = makeCounter();
+    counter();
+    total = total + counter();
+}
+print total;
+`},
+	{"instantiation", `class Node {
+    init(value, next) {
+        this.value = value;
+        this.next = next;
+    }
+}
+
+var list = nil;
+for (var i = 0; i < 5000; i = i + 1) {
+    list = Node(i, list);
+}
+print list.value;
+`},
+}
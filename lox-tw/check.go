@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"lox-tw/checker"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+)
+
+func runCheck(arguments []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	types := flags.Bool("types", false, "also infer and check the types of the scripts")
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		fmt.Println("Usage: lox-tw check [--types] files...")
+		return 64
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			return 66
+		}
+
+		if errors := check(string(content), *types); len(errors) > 0 {
+			for _, err := range errors {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			}
+			status = 65
+		}
+	}
+
+	return status
+}
+
+// check returns the errors found in a script without running it. Types are
+// only checked once it scans, parses and resolves.
+func check(source string, types bool) []error {
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		return []error{err}
+	}
+
+	stmts, errors := parser.ParseTokensToStmtsWithErrors(tokens)
+	if len(errors) > 0 {
+		return errors
+	}
+
+	if errors := resolver.NewResolver().Resolve(stmts); len(errors) > 0 || !types {
+		return errors
+	}
+
+	return checker.NewChecker().Check(stmts)
+}
@@ -0,0 +1,297 @@
+package checker
+
+import (
+	"fmt"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+// Checker infers and checks the types of a program before it runs, from the
+// annotations of its variables, parameters and functions. Names without an
+// annotation take the type of their initializer, parameters without one and
+// anything the checker can't follow are Any, which is compatible with every
+// type.
+type Checker struct {
+	scopes []map[string]Type
+	errors []error
+
+	// The classes, traits and functions declared by statements, created
+	// before their scope is checked so they can be used before their
+	// declaration.
+	classes   map[token.Token]*Class
+	traits    map[token.Token]*Trait
+	functions map[token.Token]*Function
+
+	currentClass    *Class
+	currentFunction *functionContext
+}
+
+// functionContext tracks the function being checked: what its returns must
+// match, or what they returned so far when its return type is inferred.
+type functionContext struct {
+	function    *Function
+	declared    bool
+	returns     Type
+	returnsThis bool
+	enclosing   *functionContext
+}
+
+func NewChecker() *Checker {
+	globals := map[string]Type{
+		"clock": &Function{Name: "clock", Return: NUMBER},
+		"str":   &Function{Name: "str", Parameters: []Type{ANY}, MinArity: 1, Return: STRING},
+		"list":  &Function{Name: "list", Rest: ANY, Return: LIST},
+		"map":   &Function{Name: "map", Return: MAP},
+		"range": &Function{Name: "range", Parameters: []Type{NUMBER, NUMBER, NUMBER}, MinArity: 1, Return: RANGE},
+	}
+
+	return &Checker{
+		scopes:    []map[string]Type{globals},
+		classes:   make(map[token.Token]*Class),
+		traits:    make(map[token.Token]*Trait),
+		functions: make(map[token.Token]*Function),
+	}
+}
+
+// Check returns the type errors of statements that resolved without error.
+func (c *Checker) Check(stmts []ast.Stmt[any]) []error {
+	c.checkStatements(stmts)
+	return c.errors
+}
+
+func (c *Checker) error(name token.Token, format string, arguments ...any) {
+	c.errors = append(c.errors, &CheckerError{
+		Token:   name,
+		Message: fmt.Sprintf(format, arguments...),
+	})
+}
+
+func (c *Checker) beginScope() {
+	c.scopes = append(c.scopes, make(map[string]Type))
+}
+
+func (c *Checker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *Checker) define(name string, t Type) {
+	c.scopes[len(c.scopes)-1][name] = t
+}
+
+// lookup returns the type of a variable, Any for the ones it doesn't know of,
+// such as globals defined by the host.
+func (c *Checker) lookup(name string) Type {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if t, ok := c.scopes[i][name]; ok {
+			return t
+		}
+	}
+	return ANY
+}
+
+// checkStatements checks the statements of a scope, after declaring its
+// classes, traits and functions.
+func (c *Checker) checkStatements(stmts []ast.Stmt[any]) {
+	c.declare(stmts)
+	for _, stmt := range stmts {
+		stmt.Accept(c)
+	}
+}
+
+func (c *Checker) declare(stmts []ast.Stmt[any]) {
+	var classes []ast.ClassStmt[any]
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case ast.ClassStmt[any]:
+			class := NewClass(stmt.Name.Lexeme)
+			c.classes[stmt.Name] = class
+			c.define(stmt.Name.Lexeme, class)
+			classes = append(classes, stmt)
+		case ast.TraitStmt[any]:
+			trait := &Trait{Name: stmt.Name.Lexeme, Methods: make(map[string]*Function)}
+			c.traits[stmt.Name] = trait
+			c.define(stmt.Name.Lexeme, trait)
+		}
+	}
+
+	// Signatures refer to the classes, which must all be declared first.
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case ast.TraitStmt[any]:
+			for _, method := range stmt.Methods {
+				c.traits[stmt.Name].Methods[method.Name.Lexeme] = c.signature(method)
+			}
+		case ast.FunctionStmt[any]:
+			function := c.signature(stmt)
+			c.functions[stmt.Name] = function
+			c.define(stmt.Name.Lexeme, function)
+		}
+	}
+	for _, stmt := range classes {
+		c.declareMembers(stmt)
+	}
+}
+
+// declareMembers fills the class of a declaration with the signatures of its
+// methods and the names of its fields.
+func (c *Checker) declareMembers(stmt ast.ClassStmt[any]) {
+	class := c.classes[stmt.Name]
+	if stmt.Superclass != nil {
+		if superclass, ok := c.lookup(stmt.Superclass.Name.Lexeme).(*Class); ok && superclass != class {
+			class.Superclass = superclass
+		}
+	}
+
+	for _, trait := range stmt.Traits {
+		if trait, ok := c.lookup(trait.Name.Lexeme).(*Trait); ok {
+			for name, method := range trait.Methods {
+				class.Methods[name] = method
+			}
+		}
+	}
+	for _, method := range stmt.Methods {
+		class.Methods[method.Name.Lexeme] = c.signature(method)
+	}
+	for _, method := range stmt.GlobalMethods {
+		class.GlobalMethods[method.Name.Lexeme] = c.signature(method)
+	}
+	for _, getter := range stmt.Getters {
+		class.Getters[getter.Name.Lexeme] = c.signature(getter)
+	}
+	for _, setter := range stmt.Setters {
+		class.Setters[setter.Name.Lexeme] = c.signature(setter)
+	}
+	if init, ok := class.Methods["init"]; ok {
+		init.Return = Instance{class}
+	}
+
+	// Fields can't be annotated, they are of any type.
+	ast.Inspect(toStmts(stmt.Functions()), func(node any) bool {
+		if set, ok := node.(ast.SetExpr[any]); ok {
+			if _, ok := set.Object.(ast.ThisExpr[any]); ok {
+				class.Fields[set.Name.Lexeme] = ANY
+			}
+		}
+		return true
+	})
+}
+
+func toStmts(functions []ast.FunctionStmt[any]) []ast.Stmt[any] {
+	var stmts []ast.Stmt[any]
+	for _, function := range functions {
+		stmts = append(stmts, function)
+	}
+	return stmts
+}
+
+// signature returns the type of a function from its annotations, leaving the
+// return type to infer when it has none.
+func (c *Checker) signature(function ast.FunctionStmt[any]) *Function {
+	return c.functionType(function.Name.Lexeme, function.Parameters, function.ParameterTypes, function.Defaults, function.Ellipsis, function.ReturnType, function.Generator)
+}
+
+// functionType returns the type of a function from its annotations. The
+// annotation of a rest parameter types the arguments it collects.
+// Generators return a Generator, which their annotation can only repeat.
+func (c *Checker) functionType(name string, parameters []token.Token, parameterTypes []*token.Token, defaults []ast.Expr[any], ellipsis *token.Token, returnType *token.Token, generator bool) *Function {
+	function := &Function{Name: name, Parameters: []Type{}, Names: []string{}, generator: generator, annotated: returnType != nil}
+	for _, parameterType := range parameterTypes {
+		function.annotated = function.annotated || parameterType != nil
+	}
+	if ellipsis != nil {
+		function.Rest = c.annotation(parameterTypes[len(parameters)-1])
+		parameters = parameters[:len(parameters)-1]
+	}
+	for i, parameter := range parameters {
+		function.Parameters = append(function.Parameters, c.annotation(parameterTypes[i]))
+		function.Names = append(function.Names, parameter.Lexeme)
+		if i >= len(defaults) || defaults[i] == nil {
+			function.MinArity = i + 1
+		}
+	}
+	if returnType != nil {
+		function.Return = c.annotation(returnType)
+	}
+	if generator {
+		if function.Return != nil && function.Return != GENERATOR {
+			c.error(*returnType, "Generator '%s' can't return %s.", name, function.Return)
+		}
+		function.Return = GENERATOR
+	}
+	return function
+}
+
+// annotation returns the type named by an annotation, Any without one.
+func (c *Checker) annotation(name *token.Token) Type {
+	if name == nil {
+		return ANY
+	}
+
+	switch name.Lexeme {
+	case "Any":
+		return ANY
+	case "Number":
+		return NUMBER
+	case "String":
+		return STRING
+	case "Bool":
+		return BOOL
+	case "Nil":
+		return NIL
+	case "List":
+		return LIST
+	case "Map":
+		return MAP
+	case "Range":
+		return RANGE
+	case "Generator":
+		return GENERATOR
+	}
+
+	if class, ok := c.lookup(name.Lexeme).(*Class); ok {
+		return Instance{class}
+	}
+
+	c.error(*name, "Unknown type '%s'.", name.Lexeme)
+	return ANY
+}
+
+// checkFunction checks the body of a function, inferring its return type when
+// it isn't annotated.
+func (c *Checker) checkFunction(function *Function, parameters []token.Token, defaults []ast.Expr[any], body []ast.Stmt[any]) {
+	c.currentFunction = &functionContext{
+		function:    function,
+		declared:    function.Return != nil,
+		returnsThis: true,
+		enclosing:   c.currentFunction,
+	}
+
+	c.beginScope()
+	for i, parameter := range parameters {
+		if i >= len(function.Parameters) {
+			c.define(parameter.Lexeme, LIST)
+			continue
+		}
+
+		// Defaults are checked where they are evaluated, in the call.
+		if i < len(defaults) && defaults[i] != nil {
+			if value := c.check(defaults[i]); !assignable(value, function.Parameters[i]) {
+				c.error(parameter, "Can't assign %s to '%s' of type %s.", value, parameter.Lexeme, function.Parameters[i])
+			}
+		}
+		c.define(parameter.Lexeme, function.Parameters[i])
+	}
+	c.checkStatements(body)
+	c.endScope()
+
+	if !c.currentFunction.declared {
+		function.inferred = true
+		function.Return = c.currentFunction.returns
+		function.ReturnsThis = c.currentFunction.returns != nil && c.currentFunction.returnsThis
+		if !alwaysReturns(body) {
+			function.Return = join(function.Return, NIL)
+		}
+	}
+	c.currentFunction = c.currentFunction.enclosing
+}
@@ -0,0 +1,237 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"lox-tw/internal/testutil"
+)
+
+// check returns the type errors of a program, one per line.
+func check(t *testing.T, source string) string {
+	stmts := testutil.Parse(t, source)
+
+	var messages []string
+	for _, err := range NewChecker().Check(stmts) {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Annotations", `
+var a: Number = 1;
+var b: String = 2;
+var c: Unknown;
+a = "one";`, `[line 3] Error at 'b': Can't assign Number to 'b' of type String.
+[line 4] Error at 'Unknown': Unknown type 'Unknown'.
+[line 5] Error at 'a': Can't assign String to 'a' of type Number.`},
+		{"Unannotated variables", `
+var count = 0;
+count = count + 1;
+count = "many";
+var t = true;
+t = nil;
+var n: Number = t;`, ``},
+		{"Plain Lox", `
+class Node {
+    init(value) { this.value = value; this.next = nil; }
+}
+class Stack {
+    init() { this.top = nil; this.size = 0; }
+    push(value) { var node = Node(value); node.next = this.top; this.top = node; this.size = this.size + 1; }
+    pop() { var node = this.top; this.top = node.next; this.size = this.size - 1; return node.value; }
+}
+var stack = Stack();
+stack.push(1); stack.push("two");
+var value = stack.pop();
+value = value + "!";
+var found = false;
+if (stack.size > 0) found = stack.pop();
+found = nil;
+var f = fun (x) { return x; };
+f = "no longer a function";
+for (var i in range(3)) { i = str(i); }
+print value;`, ``},
+		{"Unannotated overrides", `
+class A { m(a) { print a; } }
+class B < A { m() {} }
+class C { v() { return nil; } }
+class D < C { v() { return 1; } }
+class E { v(): Number { return 1; } }
+class F < E { v() { return "one"; } }
+class G < E { v(x: Number): Number { return x; } }`, `[line 8] Error at 'v': Method 'v' of G is fun(Number): Number, which doesn't match fun(): Number from E.`},
+		{"Operators", `
+print 1 + 2; print "a" + "b"; print 1 + "a";
+print -"a"; print "a" < 1; print 2 * nil;
+print 1 == "a"; print !"a";`, `[line 2] Error at '+': Operands must be two numbers or two strings, got Number and String.
+[line 3] Error at '-': Operand must be a number, got String.
+[line 3] Error at '<': Operands must be numbers, got String and Number.
+[line 3] Error at '*': Operands must be numbers, got Number and Nil.`},
+		{"Functions", `
+fun greet(name: String): String { return "Hi " + name; }
+fun half(n: Number) { return n / 2; }
+var a: Number = greet("Ada");
+var b: Number = half(4);
+greet(1);
+greet();
+fun wrong(): Number { return "x"; }
+"a"();`, `[line 4] Error at 'a': Can't assign String to 'a' of type Number.
+[line 6] Error at '1': Argument 1 of 'greet' must be String, got Number.
+[line 7] Error at ')': Expected 1 arguments but got 0.
+[line 8] Error at 'return': Can't return String from 'wrong', which returns Number.
+[line 9] Error at ')': Can only call functions and classes, got String.`},
+		{"Defaults and named arguments", `
+fun greet(name: String, greeting: String = "Hello", times: Number = "x") {}
+greet("Ada");
+greet("Ada", times: 2);
+greet(times: 2);
+greet("a", "b", 3, 4);
+greet("Ada", mood: 1);
+greet("Ada", greeting: 1);
+str(value: 1);
+class A { m(a: Number) {} }
+class B < A { m(a: Number, b = 1) {} }
+class C < A { m(a: Number, b) {} }`, `[line 2] Error at 'times': Can't assign String to 'times' of type Number.
+[line 5] Error at ')': Missing argument 'name'.
+[line 6] Error at ')': Expected 1 to 3 arguments but got 4.
+[line 7] Error at 'mood': Unexpected argument 'mood'.
+[line 8] Error at '1': Argument 2 of 'greet' must be String, got Number.
+[line 9] Error at ')': Native functions don't take named arguments.
+[line 12] Error at 'm': Method 'm' of C is fun(Number, Any): Nil, which doesn't match fun(Number): Nil from A.`},
+		{"Rest parameters, spread arguments and lists", `
+fun sum(first: Number, ...rest: Number): Number { var count: Number = rest.length; return first; }
+sum(1, 2, "3");
+sum(...list(1, 2));
+sum(...1);
+sum();
+var xs: List = list(1, "a");
+var n: String = xs.length;
+xs.pop();
+fun one(a) {}
+one(1, 2, ...xs);
+one(...xs, 2);`, `[line 3] Error at '"3"': Argument 3 of 'sum' must be Number, got String.
+[line 5] Error at '...': Can only spread lists, got Number.
+[line 6] Error at ')': Expected at least 1 arguments but got 0.
+[line 8] Error at 'n': Can't assign Number to 'n' of type String.
+[line 9] Error at 'pop': Undefined property 'pop' on List.
+[line 11] Error at ')': Expected 1 arguments but got 2.`},
+		{"Generators", `
+fun count(n: Number) { yield n; return "dropped"; }
+fun typed(): Generator { yield 1; }
+fun wrong(): Number { yield 1; }
+var g: Generator = count(1);
+var done: Bool = g.done;
+var n: Number = g.next();
+var s: String = g.done;
+g.previous();`, `[line 4] Error at 'Number': Generator 'wrong' can't return Number.
+[line 8] Error at 's': Can't assign Bool to 's' of type String.
+[line 9] Error at 'previous': Undefined property 'previous' on Generator.`},
+		{"For-in loops", `
+for (var c in "ab") { var s: String = c; }
+for (var i: Number in range(3)) { var n: Number = i; var s: String = i; }
+for (var x: Number in list(1)) {}
+for (var x: String in range(1, 2, 3)) {}
+var ages: Map = map();
+var n: Number = ages.length;
+var b: Bool = ages.has("ann");
+for (var key in ages) { ages.remove(key); }
+class Box { iterator() { return this; } done { return true; } next() {} }
+for (var x in Box()) {}
+for (var x in 1) {}
+class Empty {}
+for (var x in Empty()) {}
+ages.clear();
+var r: Range = range("1");`, `[line 3] Error at 's': Can't assign Number to 's' of type String.
+[line 5] Error at 'x': Can't assign Number to 'x' of type String.
+[line 12] Error at 'for': Can't iterate over Number.
+[line 14] Error at 'for': Can't iterate over Empty.
+[line 15] Error at 'clear': Undefined property 'clear' on Map.
+[line 16] Error at '"1"': Argument 1 of 'range' must be Number, got String.`},
+		{"Inferred returns", `
+fun maybe(n) { if (n) return 1; }
+fun always(n) { if (n) return 1; else return 2; }
+var a: Number = maybe(true);
+var b: Number = always(true);
+var c: String = always(true);`, `[line 6] Error at 'c': Can't assign Number to 'c' of type String.`},
+		{"Use before declaration", `
+fun area(): Number { return make().size; }
+fun make(): Box { return Box(2); }
+class Box { init(size: Number) { this.size = size; } measure(): Number { return this.size; } }
+var s: String = make().measure();`, `[line 5] Error at 's': Can't assign Number to 's' of type String.`},
+		{"Classes", `
+class Point {
+    init(x: Number, y: Number) { this.x = x; this.y = y; this.label = nil; }
+    norm(): Number { return this.x * this.x + this.y * this.y; }
+}
+var p: Point = Point(1, 2);
+var n: Number = p.norm();
+p.x = "one";
+p.label = "origin";
+print p.z;
+Point("1", 2);
+var q: Point = 1;
+var r: Point = nil;
+print 1.x;`, `[line 10] Error at 'z': Undefined property 'z' on Point.
+[line 11] Error at '"1"': Argument 1 of 'init' must be Number, got String.
+[line 12] Error at 'q': Can't assign Number to 'q' of type Point.
+[line 14] Error at 'x': Only instances have properties, got Number.`},
+		{"Inheritance", `
+class Shape {
+    area(): Number { return 0; }
+    scale(n: Number) { return this; }
+}
+class Square < Shape {
+    init(side: Number) { this.side = side; }
+    area(): String { return "big"; }
+}
+class Circle < Shape {}
+var s: Shape = Square(1);
+var c: Circle = Shape();
+var t: Square = Square(1).scale(2);
+print Square(1).scale(2).side;`, `[line 8] Error at 'area': Method 'area' of Square is fun(): String, which doesn't match fun(): Number from Shape.
+[line 12] Error at 'c': Can't assign Shape to 'c' of type Circle.`},
+		{"Getters, setters and traits", `
+trait Named { name(): String { return "named"; } }
+class Temperature with Named {
+    celsius: Number { return 20; }
+    celsius=(value: Number) {}
+}
+var t: Temperature = Temperature();
+var a: String = t.celsius;
+t.celsius = "hot";
+var n: Number = t.name();`, `[line 8] Error at 'a': Can't assign Number to 'a' of type String.
+[line 9] Error at 'celsius': Can't assign String to 'celsius' of type Number.
+[line 10] Error at 'n': Can't assign String to 'n' of type Number.`},
+		{"Operator methods and toString", `
+class Money {
+    init(cents: Number) { this.cents = cents; }
+    __add__(other: Money): Money { return Money(this.cents + other.cents); }
+    toString() { return str(this.cents); }
+}
+var total: Money = Money(1) + Money(2);
+var text: String = "Total: " + total;
+Money(1) + 2;`, `[line 9] Error at '+': Argument 1 of '__add__' must be Money, got Number.`},
+		{"Untyped code", `
+fun add(a, b) { return a + b; }
+var x = add(1, 2) + "three";
+class Node { init(value) { this.value = value; this.next = nil; } }
+var node = Node(1);
+node.next = Node(2);
+node.extra = true;
+print node.next.value + node.extra;`, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errors := check(t, test.source); errors != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package checker
+
+import (
+	"fmt"
+	"lox-tw/token"
+)
+
+type CheckerError struct {
+	Token   token.Token
+	Message string
+}
+
+func (e *CheckerError) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
@@ -0,0 +1,322 @@
+package checker
+
+import (
+	"lox-tw/ast"
+	"lox-tw/token"
+	"lox-tw/utils"
+)
+
+// The methods instances implement operators with, as the interpreters call
+// them.
+var operatorMethods = map[token.TokenType]string{
+	token.PLUS:          "__add__",
+	token.MINUS:         "__sub__",
+	token.STAR:          "__mul__",
+	token.SLASH:         "__div__",
+	token.GREATER:       "__gt__",
+	token.GREATER_EQUAL: "__ge__",
+	token.LESS:          "__lt__",
+	token.LESS_EQUAL:    "__le__",
+}
+
+func (c *Checker) VisitAssignExpr(expr ast.AssignExpr[any]) (any, error) {
+	value := c.check(expr.Value)
+	if variable := c.lookup(expr.Name.Lexeme); !assignable(value, variable) {
+		c.error(expr.Name, "Can't assign %s to '%s' of type %s.", value, expr.Name.Lexeme, variable)
+	}
+	return value, nil
+}
+
+func (c *Checker) VisitGroupingExpr(expr ast.GroupingExpr[any]) (any, error) {
+	return c.check(expr.Expression), nil
+}
+
+func (c *Checker) VisitTernaryExpr(expr ast.TernaryExpr[any]) (any, error) {
+	c.check(expr.Condition)
+	return join(c.check(expr.TrueExpr), c.check(expr.FalseExpr)), nil
+}
+
+func (c *Checker) VisitBinaryExpr(expr ast.BinaryExpr[any]) (any, error) {
+	left := c.check(expr.Left)
+	right := c.check(expr.Right)
+
+	switch expr.Operator.Type {
+	case token.COMMA:
+		return right, nil
+	case token.EQUAL_EQUAL, token.BANG_EQUAL:
+		return BOOL, nil
+	}
+
+	if result, ok := c.checkOperator(expr.Operator, left, right); ok {
+		return result, nil
+	}
+	if left == ANY || right == ANY {
+		return ANY, nil
+	}
+
+	switch expr.Operator.Type {
+	case token.PLUS:
+		if left == NUMBER && right == NUMBER {
+			return NUMBER, nil
+		}
+		if left == STRING && (right == STRING || hasToString(right)) || right == STRING && hasToString(left) {
+			return STRING, nil
+		}
+		c.error(expr.Operator, "Operands must be two numbers or two strings, got %s and %s.", left, right)
+		return ANY, nil
+	case token.MINUS, token.STAR, token.SLASH:
+		if left != NUMBER || right != NUMBER {
+			c.error(expr.Operator, "Operands must be numbers, got %s and %s.", left, right)
+		}
+		return NUMBER, nil
+	}
+
+	if left != NUMBER || right != NUMBER {
+		c.error(expr.Operator, "Operands must be numbers, got %s and %s.", left, right)
+	}
+	return BOOL, nil
+}
+
+// checkOperator checks a binary operator instances implement with a method,
+// returning what the method returns.
+func (c *Checker) checkOperator(operator token.Token, left, right Type) (Type, bool) {
+	instance, ok := left.(Instance)
+	if !ok {
+		return nil, false
+	}
+
+	method := instance.Class.FindMethod(operatorMethods[operator.Type])
+	if method == nil {
+		return nil, false
+	}
+
+	if method.MinArity > 1 || method.parameter(0) == nil {
+		c.error(operator, "%s", utils.ArityMessage(method.MinArity, method.maxArity(), 1))
+	} else if !assignable(right, method.parameter(0)) {
+		c.error(operator, "Argument 1 of '%s' must be %s, got %s.", method.Name, method.parameter(0), right)
+	}
+	return method.returns(), true
+}
+
+func hasToString(t Type) bool {
+	instance, ok := t.(Instance)
+	return ok && instance.Class.FindMethod("toString") != nil
+}
+
+func (c *Checker) VisitUnaryExpr(expr ast.UnaryExpr[any]) (any, error) {
+	right := c.check(expr.Right)
+	if expr.Operator.Type == token.BANG {
+		return BOOL, nil
+	}
+
+	if instance, ok := right.(Instance); ok {
+		if method := instance.Class.FindMethod("__neg__"); method != nil {
+			return method.returns(), nil
+		}
+	}
+	if right != NUMBER && right != ANY {
+		c.error(expr.Operator, "Operand must be a number, got %s.", right)
+	}
+	return NUMBER, nil
+}
+
+func (c *Checker) VisitLogicalExpr(expr ast.LogicalExpr[any]) (any, error) {
+	return join(c.check(expr.Left), c.check(expr.Right)), nil
+}
+
+func (c *Checker) VisitLiteralExpr(expr ast.LiteralExpr[any]) (any, error) {
+	switch expr.Value.(type) {
+	case float64:
+		return NUMBER, nil
+	case string:
+		return STRING, nil
+	case bool:
+		return BOOL, nil
+	case nil:
+		return NIL, nil
+	}
+	return ANY, nil
+}
+
+func (c *Checker) VisitNothingExpr(expr ast.NothingExpr[any]) (any, error) {
+	return NIL, nil
+}
+
+func (c *Checker) VisitCallExpr(expr ast.CallExpr[any]) (any, error) {
+	switch callee := c.check(expr.Callee).(type) {
+	case *Function:
+		c.checkArguments(callee, expr)
+		return callee.returns(), nil
+	case *Class:
+		init := callee.FindMethod("init")
+		if init == nil {
+			init = &Function{Name: "init", Parameters: []Type{}, Names: []string{}}
+		}
+		c.checkArguments(init, expr)
+		return Instance{callee}, nil
+	case Primitive:
+		for _, argument := range expr.Arguments {
+			c.check(argument)
+		}
+		if callee != ANY {
+			c.error(expr.Parenthesis, "Can only call functions and classes, got %s.", callee)
+		}
+		return ANY, nil
+	default:
+		for _, argument := range expr.Arguments {
+			c.check(argument)
+		}
+		c.error(expr.Parenthesis, "Can only call functions and classes, got %s.", callee)
+		return ANY, nil
+	}
+}
+
+func (c *Checker) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
+	function := c.functionType("<lambda>", expr.Parameters, expr.ParameterTypes, expr.Defaults, expr.Ellipsis, expr.ReturnType, expr.Generator)
+	c.checkFunction(function, expr.Parameters, expr.Defaults, expr.Body)
+	return function, nil
+}
+
+func (c *Checker) VisitVarExpr(expr ast.VarExpr[any]) (any, error) {
+	return c.lookup(expr.Name.Lexeme), nil
+}
+
+func (c *Checker) VisitGetExpr(expr ast.GetExpr[any]) (any, error) {
+	switch object := c.check(expr.Object).(type) {
+	case Instance:
+		name := expr.Name.Lexeme
+		if field, ok := object.Class.FindField(name); ok {
+			return field, nil
+		}
+		if getter := object.Class.FindGetter(name); getter != nil {
+			return getter.returns(), nil
+		}
+		if method := object.Class.FindMethod(name); method != nil {
+			if method.ReturnsThis {
+				bound := *method
+				bound.Return, bound.ReturnsThis = object, false
+				return &bound, nil
+			}
+			return method, nil
+		}
+		c.error(expr.Name, "Undefined property '%s' on %s.", name, object)
+		return ANY, nil
+	case *Class:
+		if method := object.FindGlobalMethod(expr.Name.Lexeme); method != nil {
+			return method, nil
+		}
+		return ANY, nil
+	case Primitive:
+		if object == LIST {
+			return c.listMember(expr.Name), nil
+		}
+		if object == MAP {
+			return c.mapMember(expr.Name), nil
+		}
+		if object == GENERATOR {
+			return c.generatorMember(expr.Name), nil
+		}
+		if object != ANY {
+			c.error(expr.Name, "Only instances have properties, got %s.", object)
+		}
+		return ANY, nil
+	default:
+		c.error(expr.Name, "Only instances have properties, got %s.", object)
+		return ANY, nil
+	}
+}
+
+// listMember returns the type of the length or of a method of lists.
+func (c *Checker) listMember(name token.Token) Type {
+	switch name.Lexeme {
+	case "length":
+		return NUMBER
+	case "get":
+		return &Function{Name: "get", Parameters: []Type{NUMBER}, MinArity: 1, Return: ANY}
+	case "set":
+		return &Function{Name: "set", Parameters: []Type{NUMBER, ANY}, MinArity: 2, Return: ANY}
+	case "push":
+		return &Function{Name: "push", Parameters: []Type{ANY}, MinArity: 1, Return: NIL}
+	}
+	c.error(name, "Undefined property '%s' on %s.", name.Lexeme, LIST)
+	return ANY
+}
+
+// mapMember returns the type of the length or of a method of maps.
+func (c *Checker) mapMember(name token.Token) Type {
+	switch name.Lexeme {
+	case "length":
+		return NUMBER
+	case "get", "remove":
+		return &Function{Name: name.Lexeme, Parameters: []Type{ANY}, MinArity: 1, Return: ANY}
+	case "set":
+		return &Function{Name: "set", Parameters: []Type{ANY, ANY}, MinArity: 2, Return: ANY}
+	case "has":
+		return &Function{Name: "has", Parameters: []Type{ANY}, MinArity: 1, Return: BOOL}
+	}
+	c.error(name, "Undefined property '%s' on %s.", name.Lexeme, MAP)
+	return ANY
+}
+
+// generatorMember returns the type of the members of generators.
+func (c *Checker) generatorMember(name token.Token) Type {
+	switch name.Lexeme {
+	case "next":
+		return &Function{Name: "next", Parameters: []Type{}, Return: ANY}
+	case "done":
+		return BOOL
+	}
+	c.error(name, "Undefined property '%s' on %s.", name.Lexeme, GENERATOR)
+	return ANY
+}
+
+func (c *Checker) VisitSetExpr(expr ast.SetExpr[any]) (any, error) {
+	object := c.check(expr.Object)
+	value := c.check(expr.Value)
+
+	switch object := object.(type) {
+	case Instance:
+		name := expr.Name.Lexeme
+		if setter := object.Class.FindSetter(name); setter != nil {
+			if len(setter.Parameters) == 1 && !assignable(value, setter.Parameters[0]) {
+				c.error(expr.Name, "Can't assign %s to '%s' of type %s.", value, name, setter.Parameters[0])
+			}
+			return value, nil
+		}
+
+		if _, ok := object.Class.FindField(name); !ok {
+			object.Class.Fields[name] = ANY
+		}
+	case *Class:
+	case Primitive:
+		if object != ANY {
+			c.error(expr.Name, "Only instances have fields, got %s.", object)
+		}
+	default:
+		c.error(expr.Name, "Only instances have fields, got %s.", object)
+	}
+	return value, nil
+}
+
+func (c *Checker) VisitThisExpr(expr ast.ThisExpr[any]) (any, error) {
+	return c.lookup("this"), nil
+}
+
+func (c *Checker) VisitInnerExpr(expr ast.InnerExpr[any]) (any, error) {
+	for _, argument := range expr.Arguments {
+		c.check(argument)
+	}
+	return ANY, nil
+}
+
+func (c *Checker) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
+	if c.currentClass == nil || c.currentClass.Superclass == nil {
+		return ANY, nil
+	}
+
+	if method := c.currentClass.Superclass.FindMethod(expr.Method.Lexeme); method != nil {
+		return method, nil
+	}
+	c.error(expr.Method, "Undefined property '%s' on %s.", expr.Method.Lexeme, c.currentClass.Superclass.Name)
+	return ANY, nil
+}
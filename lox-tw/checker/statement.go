@@ -0,0 +1,320 @@
+package checker
+
+import (
+	"slices"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+	"lox-tw/utils"
+)
+
+func (c *Checker) VisitVarStmt(stmt ast.VarStmt[any]) error {
+	value := c.check(stmt.Initializer)
+	if stmt.VariableType == nil {
+		c.define(stmt.Name.Lexeme, ANY)
+		return nil
+	}
+
+	declared := c.annotation(stmt.VariableType)
+	if _, ok := stmt.Initializer.(ast.NothingExpr[any]); !ok && !assignable(value, declared) {
+		c.error(stmt.Name, "Can't assign %s to '%s' of type %s.", value, stmt.Name.Lexeme, declared)
+	}
+	c.define(stmt.Name.Lexeme, declared)
+	return nil
+}
+
+func (c *Checker) VisitExpressionStmt(stmt ast.ExpressionStmt[any]) error {
+	c.check(stmt.Expression)
+	return nil
+}
+
+func (c *Checker) VisitIfStmt(stmt ast.IfStmt[any]) error {
+	c.check(stmt.Condition)
+	stmt.ThenBranch.Accept(c)
+	if stmt.ElseBranch != nil {
+		stmt.ElseBranch.Accept(c)
+	}
+	return nil
+}
+
+func (c *Checker) VisitWhileStmt(stmt ast.WhileStmt[any]) error {
+	c.check(stmt.Condition)
+	stmt.Body.Accept(c)
+	return nil
+}
+
+// VisitForInStmt checks the elements of the iterable against the annotation
+// of the loop variable, which is of any type without one.
+func (c *Checker) VisitForInStmt(stmt ast.ForInStmt[any]) error {
+	element := c.element(stmt.Keyword, c.check(stmt.Iterable))
+
+	c.beginScope()
+	defer c.endScope()
+	if stmt.VariableType == nil {
+		c.define(stmt.Name.Lexeme, ANY)
+	} else {
+		declared := c.annotation(stmt.VariableType)
+		if !assignable(element, declared) {
+			c.error(stmt.Name, "Can't assign %s to '%s' of type %s.", element, stmt.Name.Lexeme, declared)
+		}
+		c.define(stmt.Name.Lexeme, declared)
+	}
+	stmt.Body.Accept(c)
+	return nil
+}
+
+// element returns the type of the elements of an iterable: the characters of
+// strings and the numbers of ranges, Any for the others.
+func (c *Checker) element(keyword token.Token, iterable Type) Type {
+	switch iterable {
+	case STRING:
+		return STRING
+	case RANGE:
+		return NUMBER
+	case ANY, LIST, MAP, GENERATOR:
+		return ANY
+	}
+	if instance, ok := iterable.(Instance); ok && instance.Class.FindMethod("iterator") != nil {
+		return ANY
+	}
+
+	c.error(keyword, "Can't iterate over %s.", iterable)
+	return ANY
+}
+
+func (c *Checker) VisitPrintStmt(stmt ast.PrintStmt[any]) error {
+	c.check(stmt.Expression)
+	return nil
+}
+
+func (c *Checker) VisitClassStmt(stmt ast.ClassStmt[any]) error {
+	class := c.classes[stmt.Name]
+	if stmt.Superclass != nil {
+		if superclass := c.check(*stmt.Superclass); superclass != ANY && class.Superclass == nil {
+			c.error(stmt.Superclass.Name, "Superclass must be a class, got %s.", superclass)
+		}
+	}
+	for _, trait := range stmt.Traits {
+		if t := c.check(trait); t != ANY {
+			if _, ok := t.(*Trait); !ok {
+				c.error(trait.Name, "Can only compose traits, got %s.", t)
+			}
+		}
+	}
+
+	enclosingClass := c.currentClass
+	c.currentClass = class
+
+	// The initializer goes first, its assignments type the fields.
+	c.beginScope()
+	c.define("this", Instance{class})
+	for _, method := range stmt.Methods {
+		if method.Name.Lexeme == "init" {
+			c.checkFunction(class.Methods["init"], method.Parameters, method.Defaults, method.Body)
+		}
+	}
+	for _, method := range stmt.Methods {
+		if method.Name.Lexeme != "init" {
+			c.checkFunction(class.Methods[method.Name.Lexeme], method.Parameters, method.Defaults, method.Body)
+		}
+	}
+	for _, getter := range stmt.Getters {
+		c.checkFunction(class.Getters[getter.Name.Lexeme], nil, nil, getter.Body)
+	}
+	for _, setter := range stmt.Setters {
+		c.checkFunction(class.Setters[setter.Name.Lexeme], setter.Parameters, setter.Defaults, setter.Body)
+	}
+	c.endScope()
+
+	c.beginScope()
+	c.define("this", class)
+	for _, method := range stmt.GlobalMethods {
+		c.checkFunction(class.GlobalMethods[method.Name.Lexeme], method.Parameters, method.Defaults, method.Body)
+	}
+	c.endScope()
+
+	c.currentClass = enclosingClass
+
+	c.checkOverrides(class, stmt.Methods)
+	return nil
+}
+
+// checkOverrides reports the methods that can't be used in place of the
+// method of a superclass they override. Only annotations are compared, so
+// methods without any are left alone and inferred return types are Any.
+func (c *Checker) checkOverrides(class *Class, methods []ast.FunctionStmt[any]) {
+	if class.Superclass == nil {
+		return
+	}
+
+	for _, method := range methods {
+		name := method.Name.Lexeme
+		overridden := class.Superclass.FindMethod(name)
+		if name == "init" || overridden == nil {
+			continue
+		}
+		if !class.Methods[name].annotated && !overridden.annotated {
+			continue
+		}
+
+		if !assignable(class.Methods[name].declared(), overridden.declared()) {
+			c.error(method.Name, "Method '%s' of %s is %s, which doesn't match %s from %s.",
+				name, class.Name, class.Methods[name], overridden, class.Superclass.Name)
+		}
+	}
+}
+
+func (c *Checker) VisitTraitStmt(stmt ast.TraitStmt[any]) error {
+	trait := c.traits[stmt.Name]
+
+	// Traits can be composed into any class, 'this' is unknown.
+	c.beginScope()
+	c.define("this", ANY)
+	for _, method := range stmt.Methods {
+		c.checkFunction(trait.Methods[method.Name.Lexeme], method.Parameters, method.Defaults, method.Body)
+	}
+	c.endScope()
+	return nil
+}
+
+func (c *Checker) VisitBlockStmt(stmt ast.BlockStmt[any]) error {
+	c.beginScope()
+	c.checkStatements(stmt.Statements)
+	c.endScope()
+	return nil
+}
+
+func (c *Checker) VisitBreakStmt(stmt ast.BreakStmt[any]) error {
+	return nil
+}
+
+func (c *Checker) VisitFunctionStmt(stmt ast.FunctionStmt[any]) error {
+	c.checkFunction(c.functions[stmt.Name], stmt.Parameters, stmt.Defaults, stmt.Body)
+	return nil
+}
+
+func (c *Checker) VisitAssertStmt(stmt ast.AssertStmt[any]) error {
+	c.check(stmt.Condition)
+	if stmt.Message != nil {
+		c.check(stmt.Message)
+	}
+	return nil
+}
+
+func (c *Checker) VisitYieldStmt(stmt ast.YieldStmt[any]) error {
+	if stmt.Value != nil {
+		c.check(stmt.Value)
+	}
+	return nil
+}
+
+func (c *Checker) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
+	var value Type = NIL
+	if stmt.Value != nil {
+		value = c.check(stmt.Value)
+	}
+
+	// Generators drop the values they return.
+	function := c.currentFunction
+	if function == nil || function.function.generator {
+		return nil
+	}
+
+	if !function.declared {
+		function.returns = join(function.returns, value)
+		if _, ok := stmt.Value.(ast.ThisExpr[any]); !ok {
+			function.returnsThis = false
+		}
+	} else if !assignable(value, function.function.Return) {
+		c.error(stmt.Keyword, "Can't return %s from '%s', which returns %s.", value, function.function.Name, function.function.Return)
+	}
+	return nil
+}
+
+// alwaysReturns reports whether statements can't complete without running a
+// return statement, in which case the function doesn't return nil by falling
+// off its end.
+func alwaysReturns(stmts []ast.Stmt[any]) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case ast.ReturnStmt[any]:
+			return true
+		case ast.BlockStmt[any]:
+			if alwaysReturns(stmt.Statements) {
+				return true
+			}
+		case ast.IfStmt[any]:
+			if stmt.ElseBranch != nil && alwaysReturns([]ast.Stmt[any]{stmt.ThenBranch}) && alwaysReturns([]ast.Stmt[any]{stmt.ElseBranch}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check returns the type of an expression.
+func (c *Checker) check(expr ast.Expr[any]) Type {
+	t, _ := expr.Accept(c)
+	return t.(Type)
+}
+
+// checkArguments checks the arguments of a call against the parameters they
+// are passed to, by position or by name. The positions of the arguments
+// following a spread list are unknown, so are their parameters.
+func (c *Checker) checkArguments(callee *Function, expr ast.CallExpr[any]) {
+	var types []Type
+	spread := len(expr.Arguments)
+	for i, argument := range expr.Arguments {
+		types = append(types, c.check(argument))
+		if i < len(expr.Spreads) && expr.Spreads[i] != nil {
+			if types[i] != LIST && types[i] != ANY {
+				c.error(*expr.Spreads[i], "Can only spread lists, got %s.", types[i])
+			}
+			spread = min(spread, i)
+		}
+	}
+
+	named := ast.HasNamedArguments(expr)
+	tooMany := callee.Rest == nil && spread > len(callee.Parameters)
+	tooFew := !named && spread == len(expr.Arguments) && len(expr.Arguments) < callee.MinArity
+	if tooMany || tooFew {
+		c.error(expr.Parenthesis, "%s", utils.ArityMessage(callee.MinArity, callee.maxArity(), spread))
+		return
+	}
+	if named && callee.Names == nil {
+		c.error(expr.Parenthesis, "Native functions don't take named arguments.")
+		return
+	}
+
+	passed := make([]bool, len(callee.Parameters))
+	for i, argument := range expr.Arguments {
+		index := i
+		if i >= spread && (i >= len(expr.Names) || expr.Names[i] == nil) {
+			continue
+		}
+		if i < len(expr.Names) && expr.Names[i] != nil {
+			name := expr.Names[i]
+			if index = slices.Index(callee.Names, name.Lexeme); index == -1 {
+				c.error(*name, "Unexpected argument '%s'.", name.Lexeme)
+				continue
+			}
+			if passed[index] {
+				c.error(*name, "Argument '%s' is passed twice.", name.Lexeme)
+				continue
+			}
+		}
+		if index < len(passed) {
+			passed[index] = true
+		}
+
+		if !assignable(types[i], callee.parameter(index)) {
+			c.error(ast.FirstExprToken(argument), "Argument %d of '%s' must be %s, got %s.", index+1, callee.Name, callee.parameter(index), types[i])
+		}
+	}
+
+	for i := range callee.MinArity {
+		if !passed[i] && spread == len(expr.Arguments) {
+			c.error(expr.Parenthesis, "Missing argument '%s'.", callee.Names[i])
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package checker
+
+import "strings"
+
+// Type is the static type of an expression. Primitives, instances and class
+// types compare with ==, functions structurally with assignable.
+type Type interface {
+	String() string
+}
+
+type Primitive string
+
+func (p Primitive) String() string {
+	return string(p)
+}
+
+const (
+	ANY    Primitive = "Any"
+	NUMBER Primitive = "Number"
+	STRING Primitive = "String"
+	BOOL   Primitive = "Bool"
+	NIL    Primitive = "Nil"
+	LIST   Primitive = "List"
+	MAP    Primitive = "Map"
+	RANGE  Primitive = "Range"
+	// The type of what calling a generator function returns.
+	GENERATOR Primitive = "Generator"
+)
+
+// Function is the type of functions, methods and lambdas. A nil Return is not
+// inferred yet, calls made meanwhile return Any. Methods that only return
+// 'this' return the type of the instance they are called on, which may be a
+// subclass of theirs. The parameters from MinArity on have a default value,
+// and Names are nil for natives, which don't take named arguments. Variadic
+// functions take any number of arguments more, of type Rest.
+type Function struct {
+	Name        string
+	Parameters  []Type
+	Names       []string
+	MinArity    int
+	Rest        Type
+	Return      Type
+	ReturnsThis bool
+
+	// Set for generators, which return a Generator whatever their body
+	// returns.
+	generator bool
+	// Whether any parameter or the return type is annotated, and whether the
+	// return type was inferred from the body instead.
+	annotated bool
+	inferred  bool
+}
+
+func (f *Function) String() string {
+	var parameters []string
+	for _, parameter := range f.Parameters {
+		parameters = append(parameters, parameter.String())
+	}
+	if f.Rest != nil {
+		parameters = append(parameters, "..."+f.Rest.String())
+	}
+	return "fun(" + strings.Join(parameters, ", ") + "): " + f.returns().String()
+}
+
+// maxArity returns the most arguments the function takes, -1 when it is
+// variadic.
+func (f *Function) maxArity() int {
+	if f.Rest != nil {
+		return -1
+	}
+	return len(f.Parameters)
+}
+
+// parameter returns the type of the argument at an index, nil past the
+// parameters of a function that isn't variadic.
+func (f *Function) parameter(index int) Type {
+	if index < len(f.Parameters) {
+		return f.Parameters[index]
+	}
+	return f.Rest
+}
+
+func (f *Function) returns() Type {
+	if f.Return == nil {
+		return ANY
+	}
+	return f.Return
+}
+
+// declared returns the type of the function as its annotations give it, an
+// inferred return type being Any.
+func (f *Function) declared() *Function {
+	if !f.inferred {
+		return f
+	}
+	declared := *f
+	declared.Return = ANY
+	return &declared
+}
+
+// Class is the type of a class itself, the type of its instances being
+// Instance. Fields are the properties assigned through 'this' in its methods
+// and the ones assigned to its instances elsewhere, all of any type.
+type Class struct {
+	Name          string
+	Superclass    *Class
+	Methods       map[string]*Function
+	GlobalMethods map[string]*Function
+	Getters       map[string]*Function
+	Setters       map[string]*Function
+	Fields        map[string]Type
+}
+
+func NewClass(name string) *Class {
+	return &Class{
+		Name:          name,
+		Methods:       make(map[string]*Function),
+		GlobalMethods: make(map[string]*Function),
+		Getters:       make(map[string]*Function),
+		Setters:       make(map[string]*Function),
+		Fields:        make(map[string]Type),
+	}
+}
+
+func (c *Class) String() string {
+	return "class " + c.Name
+}
+
+// Those look up a member in the class and its superclasses.
+
+func (c *Class) FindMethod(name string) *Function {
+	for class := c; class != nil; class = class.Superclass {
+		if method, ok := class.Methods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+func (c *Class) FindGlobalMethod(name string) *Function {
+	for class := c; class != nil; class = class.Superclass {
+		if method, ok := class.GlobalMethods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+func (c *Class) FindGetter(name string) *Function {
+	for class := c; class != nil; class = class.Superclass {
+		if getter, ok := class.Getters[name]; ok {
+			return getter
+		}
+	}
+	return nil
+}
+
+func (c *Class) FindSetter(name string) *Function {
+	for class := c; class != nil; class = class.Superclass {
+		if setter, ok := class.Setters[name]; ok {
+			return setter
+		}
+	}
+	return nil
+}
+
+func (c *Class) FindField(name string) (Type, bool) {
+	for class := c; class != nil; class = class.Superclass {
+		if field, ok := class.Fields[name]; ok {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Class) isSubclassOf(other *Class) bool {
+	for class := c; class != nil; class = class.Superclass {
+		if class == other {
+			return true
+		}
+	}
+	return false
+}
+
+type Instance struct {
+	Class *Class
+}
+
+func (i Instance) String() string {
+	return i.Class.Name
+}
+
+type Trait struct {
+	Name    string
+	Methods map[string]*Function
+}
+
+func (t *Trait) String() string {
+	return "trait " + t.Name
+}
+
+// assignable reports whether a value of type from can be stored where a value
+// of type to is expected. Any goes both ways, and nil is a valid instance of
+// every class.
+func assignable(from, to Type) bool {
+	if from == ANY || to == ANY || from == to {
+		return true
+	}
+
+	switch to := to.(type) {
+	case Instance:
+		if from == NIL {
+			return true
+		}
+		from, ok := from.(Instance)
+		return ok && from.Class.isSubclassOf(to.Class)
+	case *Class:
+		from, ok := from.(*Class)
+		return ok && from.isSubclassOf(to)
+	case *Function:
+		// The function must take every call the other one does.
+		from, ok := from.(*Function)
+		if !ok || from.MinArity > to.MinArity || to.Rest != nil && from.Rest == nil {
+			return false
+		}
+		for i := range to.Parameters {
+			if from.parameter(i) == nil || !assignable(to.Parameters[i], from.parameter(i)) {
+				return false
+			}
+		}
+		if to.Rest != nil && !assignable(to.Rest, from.Rest) {
+			return false
+		}
+		return assignable(from.returns(), to.returns())
+	}
+
+	return false
+}
+
+// join returns the type of a value that is either of a or of b.
+func join(a, b Type) Type {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case assignable(a, b) && a != ANY:
+		return b
+	case assignable(b, a) && b != ANY:
+		return a
+	}
+	return ANY
+}
@@ -0,0 +1,236 @@
+package coverage
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"lox-tw/ast"
+	"lox-tw/interpreter"
+	"lox-tw/token"
+)
+
+// Branch counts how many times each side of a condition was taken, see
+// interpreter.Tracer for which side is which.
+type Branch struct {
+	Site  token.Token
+	Taken [2]int
+}
+
+type Function struct {
+	Name  string
+	Line  uint
+	Calls int
+}
+
+// Coverage records what a program runs. It hooks into the interpreter as its
+// Tracer. Every line holding a statement, every condition and every function
+// is known upfront, so what never ran is reported too.
+type Coverage struct {
+	Lines     map[uint]int
+	Branches  map[token.Token]*Branch
+	Functions map[string]*Function
+
+	// The positions of the statements that count as running their line. On
+	// a line holding a function body, only the statements of the body do,
+	// not the declaration around it.
+	counted map[uint]bool
+}
+
+// span is the part of the source between the name of a function and the
+// end of its body.
+type span struct {
+	start, end uint
+}
+
+func NewCoverage(stmts []ast.Stmt[any]) *Coverage {
+	c := &Coverage{
+		Lines:     make(map[uint]int),
+		Branches:  make(map[token.Token]*Branch),
+		Functions: make(map[string]*Function),
+		counted:   make(map[uint]bool),
+	}
+
+	var statements []token.Token
+	var bodies []span
+	var visit func(node any) bool
+	method := func(name string, method ast.FunctionStmt[any]) {
+		c.addFunction(name, method.Name.Line)
+		bodies = append(bodies, span{method.Name.Position, method.RightBrace.Position})
+		ast.Inspect(method.Body, visit)
+	}
+	visit = func(node any) bool {
+		if stmt, ok := node.(ast.Stmt[any]); ok {
+			statements = append(statements, ast.FirstToken(stmt))
+			c.Lines[ast.FirstToken(stmt).Line] = 0
+		}
+
+		switch node := node.(type) {
+		case ast.IfStmt[any]:
+			c.Branches[node.Keyword] = &Branch{Site: node.Keyword}
+		case ast.TernaryExpr[any]:
+			c.Branches[node.Question] = &Branch{Site: node.Question}
+		case ast.LogicalExpr[any]:
+			c.Branches[node.Operator] = &Branch{Site: node.Operator}
+		case ast.FunctionStmt[any]:
+			c.addFunction(node.Name.Lexeme, node.Name.Line)
+			bodies = append(bodies, span{node.Name.Position, node.RightBrace.Position})
+		case ast.LambdaExpr[any]:
+			c.addFunction("<lambda>", node.Keyword.Line)
+			bodies = append(bodies, span{node.Keyword.Position, node.RightBrace.Position})
+		case ast.ClassStmt[any]:
+			// Methods are not statements that run, only their bodies are.
+			// Setters end with '=', like the interpreter names them.
+			for _, function := range slices.Concat(node.Methods, node.GlobalMethods, node.Getters) {
+				method(node.Name.Lexeme+"."+function.Name.Lexeme, function)
+			}
+			for _, setter := range node.Setters {
+				method(node.Name.Lexeme+"."+setter.Name.Lexeme+"=", setter)
+			}
+			return false
+		case ast.TraitStmt[any]:
+			for _, function := range node.Methods {
+				method(node.Name.Lexeme+"."+function.Name.Lexeme, function)
+			}
+			return false
+		}
+
+		return true
+	}
+	ast.Inspect(stmts, visit)
+
+	c.countInnermost(statements, bodies)
+	return c
+}
+
+// countInnermost keeps, for each line, the statements nested in the most
+// function bodies, so that declaring a function doesn't count as running
+// the body written on the same line.
+func (c *Coverage) countInnermost(statements []token.Token, bodies []span) {
+	depths := make(map[uint]int)
+	lineDepths := make(map[uint]int)
+	for _, statement := range statements {
+		for _, body := range bodies {
+			if body.start < statement.Position && statement.Position <= body.end {
+				depths[statement.Position]++
+			}
+		}
+		lineDepths[statement.Line] = max(lineDepths[statement.Line], depths[statement.Position])
+	}
+
+	for _, statement := range statements {
+		if depths[statement.Position] == lineDepths[statement.Line] {
+			c.counted[statement.Position] = true
+		}
+	}
+}
+
+func functionKey(name string, line uint) string {
+	return fmt.Sprintf("%s:%d", name, line)
+}
+
+func (c *Coverage) addFunction(name string, line uint) {
+	c.Functions[functionKey(name, line)] = &Function{Name: name, Line: line}
+}
+
+func (c *Coverage) BeforeStmt(i interpreter.Interpreter, stmt ast.Stmt[any]) error {
+	if first := ast.FirstToken(stmt); c.counted[first.Position] {
+		c.Lines[first.Line]++
+	}
+	return nil
+}
+
+func (c *Coverage) Branch(site token.Token, branch int) {
+	if b, ok := c.Branches[site]; ok {
+		b.Taken[branch]++
+	}
+}
+
+func (c *Coverage) EnterCall(name string, line uint) {
+	if function, ok := c.Functions[functionKey(name, line)]; ok {
+		function.Calls++
+	}
+}
+
+func (c *Coverage) ExitCall() {}
+
+// SortedLines returns the lines holding statements, in order.
+func (c *Coverage) SortedLines() []uint {
+	lines := make([]uint, 0, len(c.Lines))
+	for line := range c.Lines {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(a, b int) bool { return lines[a] < lines[b] })
+	return lines
+}
+
+// SortedBranches returns the conditions in source order.
+func (c *Coverage) SortedBranches() []*Branch {
+	branches := make([]*Branch, 0, len(c.Branches))
+	for _, branch := range c.Branches {
+		branches = append(branches, branch)
+	}
+	sort.Slice(branches, func(a, b int) bool {
+		return branches[a].Site.Position < branches[b].Site.Position
+	})
+	return branches
+}
+
+// SortedFunctions returns the functions in source order.
+func (c *Coverage) SortedFunctions() []*Function {
+	functions := make([]*Function, 0, len(c.Functions))
+	for _, function := range c.Functions {
+		functions = append(functions, function)
+	}
+	sort.Slice(functions, func(a, b int) bool {
+		if functions[a].Line != functions[b].Line {
+			return functions[a].Line < functions[b].Line
+		}
+		return functions[a].Name < functions[b].Name
+	})
+	return functions
+}
+
+// Summary counts what ran out of what could have.
+type Summary struct {
+	Lines, LinesHit         int
+	Branches, BranchesHit   int
+	Functions, FunctionsHit int
+}
+
+func (c *Coverage) Summary() Summary {
+	var s Summary
+	for _, hits := range c.Lines {
+		s.Lines++
+		if hits > 0 {
+			s.LinesHit++
+		}
+	}
+	for _, branch := range c.Branches {
+		s.Branches += 2
+		for _, taken := range branch.Taken {
+			if taken > 0 {
+				s.BranchesHit++
+			}
+		}
+	}
+	for _, function := range c.Functions {
+		s.Functions++
+		if function.Calls > 0 {
+			s.FunctionsHit++
+		}
+	}
+	return s
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("Lines: %s, branches: %s, functions: %s",
+		ratio(s.LinesHit, s.Lines), ratio(s.BranchesHit, s.Branches), ratio(s.FunctionsHit, s.Functions))
+}
+
+func ratio(hit, total int) string {
+	if total == 0 {
+		return "0/0"
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%)", hit, total, 100*float64(hit)/float64(total))
+}
@@ -0,0 +1,144 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"lox-tw/internal/testutil"
+)
+
+const SOURCE = `fun classify(n) {
+    if (n < 0) {
+        return "negative";
+    }
+    return n == 0 ? "zero" : "positive";
+}
+class A {
+    ok() { return true and nil; }
+    never() { print "never"; }
+}
+classify(1);
+classify(0);
+A().ok() or "fallback";
+`
+
+func cover(t *testing.T, source string) *Coverage {
+	stmts := testutil.Parse(t, source)
+	c := NewCoverage(stmts)
+	testutil.Execute(t, stmts, c)
+	return c
+}
+
+func TestLCOV(t *testing.T) {
+	var lcov strings.Builder
+	cover(t, SOURCE).WriteLCOV(&lcov, "test.lox")
+
+	expected := `TN:
+SF:test.lox
+FN:1,classify
+FN:8,A.ok
+FN:9,A.never
+FNDA:2,classify
+FNDA:1,A.ok
+FNDA:0,A.never
+FNF:3
+FNH:2
+BRDA:2,0,0,0
+BRDA:2,0,1,2
+BRDA:5,0,0,1
+BRDA:5,0,1,1
+BRDA:8,0,0,0
+BRDA:8,0,1,1
+BRDA:13,0,0,0
+BRDA:13,0,1,1
+BRF:8
+BRH:5
+DA:1,1
+DA:2,2
+DA:3,0
+DA:5,2
+DA:7,1
+DA:8,1
+DA:9,0
+DA:11,1
+DA:12,1
+DA:13,1
+LF:10
+LH:8
+end_of_record
+`
+	if lcov.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, lcov.String())
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var html strings.Builder
+	cover(t, SOURCE).WriteHTML(&html, "test.lox", SOURCE)
+
+	for _, expected := range []string{
+		`<p>Lines: 8/10 (80.0%), branches: 5/8 (62.5%), functions: 2/3 (66.7%)</p>`,
+		`<tr class="partial"><td class="number">2</td><td class="hits">2x</td><td>    if (n &lt; 0) {</td><td class="branches">branches: if 0/2</td></tr>`,
+		`<tr class="missed"><td class="number">3</td><td class="hits">0x</td>`,
+		`<tr class="covered"><td class="number">5</td><td class="hits">2x</td>`,
+		`<tr class=""><td class="number">4</td><td class="hits"></td>`,
+	} {
+		if !strings.Contains(html.String(), expected) {
+			t.Errorf("Expected the report to contain:\n%s\nGot:\n%s", expected, html.String())
+		}
+	}
+}
+
+func TestOneLineFunctions(t *testing.T) {
+	c := cover(t, `fun never() { print "never"; }
+var lambda = fun () { return 1; };
+fun called() { return 1; } called();
+fun outer() { return fun () { print "inner"; }; } outer();
+print "end";
+`)
+
+	expected := map[uint]int{1: 0, 2: 0, 3: 1, 4: 0, 5: 1}
+	for line, hits := range expected {
+		if c.Lines[line] != hits {
+			t.Errorf("Expected line %d to be hit %d times, got %d", line, hits, c.Lines[line])
+		}
+	}
+}
+
+// A generator is called when its body runs, not when calling it creates it.
+func TestGenerators(t *testing.T) {
+	var lcov strings.Builder
+	cover(t, `fun count(n) {
+    for (var i in range(n)) yield i;
+}
+for (var x in count(2)) print x;
+fun never() { yield 1; }
+never();
+`).WriteLCOV(&lcov, "test.lox")
+
+	for _, expected := range []string{"FNDA:3,count\n", "FNDA:0,never\n", "DA:2,3\n", "DA:5,0\n"} {
+		if !strings.Contains(lcov.String(), expected) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", expected, lcov.String())
+		}
+	}
+}
+
+// A setter is reported apart from the getter of the same property, LCOV tools
+// merging the calls of functions with the same name.
+func TestGettersAndSetters(t *testing.T) {
+	var lcov strings.Builder
+	cover(t, `class A {
+    v { return this._v; }
+    v=(x) { this._v = x; }
+}
+var a = A();
+a.v = 1; a.v = 2;
+print a.v;
+`).WriteLCOV(&lcov, "test.lox")
+
+	for _, expected := range []string{"FN:2,A.v\n", "FN:3,A.v=\n", "FNDA:1,A.v\n", "FNDA:2,A.v=\n"} {
+		if !strings.Contains(lcov.String(), expected) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", expected, lcov.String())
+		}
+	}
+}
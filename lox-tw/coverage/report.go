@@ -0,0 +1,137 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteLCOV writes the coverage of a script in the LCOV tracefile format,
+// read by genhtml and most editors and CI services.
+func (c *Coverage) WriteLCOV(w io.Writer, path string) error {
+	var lcov strings.Builder
+	fmt.Fprintf(&lcov, "TN:\nSF:%s\n", path)
+
+	summary := c.Summary()
+
+	functions := c.SortedFunctions()
+	for _, function := range functions {
+		fmt.Fprintf(&lcov, "FN:%d,%s\n", function.Line, lcovName(function))
+	}
+	for _, function := range functions {
+		fmt.Fprintf(&lcov, "FNDA:%d,%s\n", function.Calls, lcovName(function))
+	}
+	fmt.Fprintf(&lcov, "FNF:%d\nFNH:%d\n", summary.Functions, summary.FunctionsHit)
+
+	// Blocks number the conditions of a line, from left to right.
+	block, previousLine := 0, uint(0)
+	for _, branch := range c.SortedBranches() {
+		if branch.Site.Line == previousLine {
+			block++
+		} else {
+			block, previousLine = 0, branch.Site.Line
+		}
+
+		for i, taken := range branch.Taken {
+			count := fmt.Sprint(taken)
+			if c.Lines[branch.Site.Line] == 0 {
+				count = "-"
+			}
+			fmt.Fprintf(&lcov, "BRDA:%d,%d,%d,%s\n", branch.Site.Line, block, i, count)
+		}
+	}
+	fmt.Fprintf(&lcov, "BRF:%d\nBRH:%d\n", summary.Branches, summary.BranchesHit)
+
+	for _, line := range c.SortedLines() {
+		fmt.Fprintf(&lcov, "DA:%d,%d\n", line, c.Lines[line])
+	}
+	fmt.Fprintf(&lcov, "LF:%d\nLH:%d\nend_of_record\n", summary.Lines, summary.LinesHit)
+
+	_, err := io.WriteString(w, lcov.String())
+	return err
+}
+
+// Function names must be unique in LCOV, lambdas are told apart by their
+// line.
+func lcovName(function *Function) string {
+	if function.Name == "<lambda>" {
+		return functionKey(function.Name, function.Line)
+	}
+	return function.Name
+}
+
+type htmlLine struct {
+	Number   int
+	Text     string
+	Class    string
+	Hits     string
+	Branches string
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage of {{.Path}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 8px; white-space: pre; }
+.number, .hits { text-align: right; color: #777; }
+.covered { background: #dfd; }
+.partial { background: #ffd; }
+.missed { background: #fdd; }
+.branches { color: #777; }
+</style>
+</head>
+<body>
+<h1>{{.Path}}</h1>
+<p>{{.Summary}}</p>
+<table>
+{{range .Lines}}<tr class="{{.Class}}"><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td>{{.Text}}</td><td class="branches">{{.Branches}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the source of a script annotated with the hits of every
+// line. Lines where a branch never ran are highlighted as partially covered.
+func (c *Coverage) WriteHTML(w io.Writer, path string, source string) error {
+	branchesByLine := make(map[uint][]*Branch)
+	for _, branch := range c.SortedBranches() {
+		branchesByLine[branch.Site.Line] = append(branchesByLine[branch.Site.Line], branch)
+	}
+
+	var lines []htmlLine
+	for i, text := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		line := htmlLine{Number: i + 1, Text: text}
+
+		if hits, ok := c.Lines[uint(i+1)]; ok {
+			line.Hits = fmt.Sprintf("%dx", hits)
+			line.Class = "covered"
+			if hits == 0 {
+				line.Class = "missed"
+			}
+		}
+
+		var taken []string
+		for _, branch := range branchesByLine[uint(i+1)] {
+			taken = append(taken, fmt.Sprintf("%s %d/%d", branch.Site.Lexeme, branch.Taken[0], branch.Taken[1]))
+			if line.Class == "covered" && (branch.Taken[0] == 0 || branch.Taken[1] == 0) {
+				line.Class = "partial"
+			}
+		}
+		if len(taken) > 0 {
+			line.Branches = "branches: " + strings.Join(taken, ", ")
+		}
+
+		lines = append(lines, line)
+	}
+
+	return htmlReport.Execute(w, map[string]any{
+		"Path":    path,
+		"Summary": c.Summary().String(),
+		"Lines":   lines,
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"lox-tw/debugger"
+)
+
+func runDebug(arguments []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	dap := flags.Bool("dap", false, "serve the Debug Adapter Protocol over stdio")
+	flags.Parse(arguments)
+
+	if *dap {
+		if flags.NArg() != 0 {
+			fmt.Println("Usage: lox-tw debug --dap")
+			return 64
+		}
+
+		if err := debugger.NewAdapter(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: lox-tw debug [--dap | file]")
+		return 64
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		return 66
+	}
+
+	program, errors := debugger.Load(string(content))
+	if len(errors) > 0 {
+		for _, err := range errors {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return 65
+	}
+
+	if err := debugger.NewTerminal(program, os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 70
+	}
+	return 0
+}
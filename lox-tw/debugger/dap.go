@@ -0,0 +1,466 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strconv"
+	"sync"
+
+	"lox-tw/interpreter"
+)
+
+// The subset of the Debug Adapter Protocol used by the adapter, see
+// https://microsoft.github.io/debug-adapter-protocol/specification
+
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    *bool           `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	Body       any             `json:"body,omitempty"`
+}
+
+type LaunchArguments struct {
+	Program     string `json:"program"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+}
+
+type SetBreakpointsArguments struct {
+	Breakpoints []struct {
+		Line uint `json:"line"`
+	} `json:"breakpoints"`
+}
+
+type Breakpoint struct {
+	Verified bool `json:"verified"`
+	Line     uint `json:"line"`
+}
+
+type Source struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type StackFrame struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Source Source `json:"source"`
+	Line   uint   `json:"line"`
+	Column int    `json:"column"`
+}
+
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// The adapter only ever runs one thread.
+const THREAD_ID = 1
+
+// Adapter serves the Debug Adapter Protocol, so editors can debug Lox
+// scripts. Messages are JSON objects framed by a Content-Length header.
+type Adapter struct {
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+	writer     io.Writer
+	seq        int
+
+	path        string
+	program     *Program
+	stopOnEntry bool
+	debugger    *Debugger
+	running     bool
+	finished    chan struct{}
+
+	// Guards the fields below, shared with the program's goroutine.
+	mutex    sync.Mutex
+	stopped  bool
+	quitting bool
+	resume   chan Action
+	scopes   []*interpreter.Environment
+}
+
+func NewAdapter(in io.Reader, out io.Writer) *Adapter {
+	a := &Adapter{
+		reader:   bufio.NewReader(in),
+		writer:   out,
+		finished: make(chan struct{}),
+		resume:   make(chan Action, 1),
+	}
+	a.debugger = NewDebugger(a.stop, outputWriter{a})
+	return a
+}
+
+// Serve handles requests until the client disconnects or closes the input.
+func (a *Adapter) Serve() error {
+	for {
+		msg, err := a.read()
+		if err == io.EOF {
+			a.quit()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Type != "request" {
+			continue
+		}
+
+		body, err := a.handle(msg)
+		success := err == nil
+		response := dapMessage{Type: "response", RequestSeq: msg.Seq, Command: msg.Command, Success: &success, Body: body}
+		if err != nil {
+			response.Message = err.Error()
+		}
+		if err := a.write(response); err != nil {
+			return err
+		}
+
+		switch msg.Command {
+		case "initialize":
+			if err := a.event("initialized", nil); err != nil {
+				return err
+			}
+		case "disconnect", "terminate":
+			return nil
+		}
+	}
+}
+
+func (a *Adapter) read() (dapMessage, error) {
+	headers, err := textproto.NewReader(a.reader).ReadMIMEHeader()
+	if err != nil {
+		return dapMessage{}, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return dapMessage{}, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(a.reader, body); err != nil {
+		return dapMessage{}, err
+	}
+
+	var msg dapMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return dapMessage{}, err
+	}
+	return msg, nil
+}
+
+// write may be called from the program's goroutine, to send events.
+func (a *Adapter) write(msg dapMessage) error {
+	a.writeMutex.Lock()
+	defer a.writeMutex.Unlock()
+
+	a.seq++
+	msg.Seq = a.seq
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (a *Adapter) event(event string, body any) error {
+	return a.write(dapMessage{Type: "event", Event: event, Body: body})
+}
+
+// outputWriter sends what the program prints as output events, the adapter's
+// stdout being the protocol channel.
+type outputWriter struct {
+	adapter *Adapter
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	err := w.adapter.event("output", map[string]any{"category": "stdout", "output": string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (a *Adapter) handle(msg dapMessage) (any, error) {
+	switch msg.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+	case "launch":
+		var arguments LaunchArguments
+		return handleArguments(msg, &arguments, func() (any, error) {
+			return nil, a.launch(arguments)
+		})
+	case "setBreakpoints":
+		var arguments SetBreakpointsArguments
+		return handleArguments(msg, &arguments, func() (any, error) {
+			lines := []uint{}
+			breakpoints := []Breakpoint{}
+			for _, breakpoint := range arguments.Breakpoints {
+				lines = append(lines, breakpoint.Line)
+				breakpoints = append(breakpoints, Breakpoint{Verified: true, Line: breakpoint.Line})
+			}
+			a.debugger.SetBreakpoints(lines)
+			return map[string]any{"breakpoints": breakpoints}, nil
+		})
+	case "configurationDone":
+		return nil, a.start()
+	case "threads":
+		return map[string]any{"threads": []map[string]any{{"id": THREAD_ID, "name": "main"}}}, nil
+	case "stackTrace":
+		return a.stackTrace()
+	case "scopes":
+		var arguments struct {
+			FrameID int `json:"frameId"`
+		}
+		return handleArguments(msg, &arguments, func() (any, error) {
+			return a.scopesOf(arguments.FrameID)
+		})
+	case "variables":
+		var arguments struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		return handleArguments(msg, &arguments, func() (any, error) {
+			return a.variables(arguments.VariablesReference)
+		})
+	case "continue":
+		return map[string]any{"allThreadsContinued": true}, a.resumeWith(CONTINUE)
+	case "next":
+		return nil, a.resumeWith(STEP_OVER)
+	case "stepIn":
+		return nil, a.resumeWith(STEP_IN)
+	case "stepOut":
+		return nil, a.resumeWith(STEP_OUT)
+	case "pause":
+		a.debugger.Pause()
+		return nil, nil
+	case "evaluate":
+		var arguments struct {
+			Expression string `json:"expression"`
+			FrameID    int    `json:"frameId"`
+		}
+		return handleArguments(msg, &arguments, func() (any, error) {
+			return a.evaluate(arguments.Expression, arguments.FrameID)
+		})
+	case "disconnect", "terminate":
+		a.quit()
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported request '%s'.", msg.Command)
+}
+
+func handleArguments[T any](msg dapMessage, arguments *T, handler func() (any, error)) (any, error) {
+	if len(msg.Arguments) > 0 {
+		if err := json.Unmarshal(msg.Arguments, arguments); err != nil {
+			return nil, err
+		}
+	}
+
+	return handler()
+}
+
+func (a *Adapter) launch(arguments LaunchArguments) error {
+	content, err := os.ReadFile(arguments.Program)
+	if err != nil {
+		return err
+	}
+
+	program, errors := Load(string(content))
+	if len(errors) > 0 {
+		return errors[0]
+	}
+
+	a.path, a.program, a.stopOnEntry = arguments.Program, program, arguments.StopOnEntry
+	return nil
+}
+
+// start runs the launched program on its own goroutine, so requests are
+// still answered while it runs.
+func (a *Adapter) start() error {
+	if a.program == nil {
+		return fmt.Errorf("No program was launched.")
+	}
+	if a.running {
+		return nil
+	}
+	a.running = true
+
+	go func() {
+		defer close(a.finished)
+
+		exitCode := 0
+		err := a.debugger.Run(a.program.Stmts, a.stopOnEntry)
+		if _, ok := err.(*QuitError); !ok && err != nil {
+			a.event("output", map[string]any{"category": "stderr", "output": err.Error() + "\n"})
+			exitCode = 70
+		}
+
+		a.event("exited", map[string]any{"exitCode": exitCode})
+		a.event("terminated", nil)
+	}()
+
+	return nil
+}
+
+// stop is called on the program's goroutine when it pauses, and waits for
+// the client to resume it.
+func (a *Adapter) stop(reason string) Action {
+	a.mutex.Lock()
+	if a.quitting {
+		a.mutex.Unlock()
+		return QUIT
+	}
+	a.stopped = true
+	a.scopes = nil
+	a.mutex.Unlock()
+
+	a.event("stopped", map[string]any{"reason": reason, "threadId": THREAD_ID, "allThreadsStopped": true})
+	return <-a.resume
+}
+
+func (a *Adapter) resumeWith(action Action) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.stopped {
+		return fmt.Errorf("The program is not paused.")
+	}
+	a.stopped = false
+	a.resume <- action
+	return nil
+}
+
+// quit ends the program if it is running, and waits for it to finish.
+func (a *Adapter) quit() {
+	if !a.running {
+		return
+	}
+
+	a.debugger.Stop()
+	a.mutex.Lock()
+	a.quitting = true
+	if a.stopped {
+		a.stopped = false
+		a.resume <- QUIT
+	}
+	a.mutex.Unlock()
+
+	<-a.finished
+}
+
+// paused returns the call stack, which can only be inspected while the
+// program is paused.
+func (a *Adapter) paused() ([]Frame, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.stopped {
+		return nil, fmt.Errorf("The program is not paused.")
+	}
+	return a.debugger.Frames(), nil
+}
+
+func (a *Adapter) stackTrace() (any, error) {
+	frames, err := a.paused()
+	if err != nil {
+		return nil, err
+	}
+
+	stackFrames := []StackFrame{}
+	for i, frame := range frames {
+		stackFrames = append(stackFrames, StackFrame{
+			ID:     i,
+			Name:   frame.Name,
+			Source: Source{Name: a.path, Path: a.path},
+			Line:   frame.Line,
+			Column: 1,
+		})
+	}
+	return map[string]any{"stackFrames": stackFrames, "totalFrames": len(stackFrames)}, nil
+}
+
+// scopesOf lists the environment chain of a frame. Each environment gets a
+// variables reference, valid until the program resumes.
+func (a *Adapter) scopesOf(frameID int) (any, error) {
+	frames, err := a.paused()
+	if err != nil {
+		return nil, err
+	}
+	if frameID < 0 || frameID >= len(frames) {
+		return nil, fmt.Errorf("Unknown frame %d.", frameID)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	scopes := []Scope{}
+	for env := frames[frameID].Environment; env != nil; env = env.Enclosing() {
+		a.scopes = append(a.scopes, env)
+		scope := Scope{Name: "Locals", VariablesReference: len(a.scopes)}
+		if env.IsGlobal() {
+			scope.Name, scope.Expensive = "Globals", true
+		} else if len(scopes) > 0 {
+			scope.Name = "Enclosing"
+		}
+		scopes = append(scopes, scope)
+	}
+	return map[string]any{"scopes": scopes}, nil
+}
+
+func (a *Adapter) variables(reference int) (any, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if reference <= 0 || reference > len(a.scopes) {
+		return nil, fmt.Errorf("Unknown variables reference %d.", reference)
+	}
+
+	env := a.scopes[reference-1]
+	variables := []Variable{}
+	for _, name := range env.Names() {
+		value, _ := env.GetAtByLexeme(0, name)
+		variables = append(variables, Variable{Name: name, Value: interpreter.Stringify(value)})
+	}
+	return map[string]any{"variables": variables}, nil
+}
+
+func (a *Adapter) evaluate(expression string, frameID int) (any, error) {
+	frames, err := a.paused()
+	if err != nil {
+		return nil, err
+	}
+	if frameID < 0 || frameID >= len(frames) {
+		return nil, fmt.Errorf("Unknown frame %d.", frameID)
+	}
+
+	value, err := a.debugger.Evaluate(frameID, expression)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"result": interpreter.Stringify(value), "variablesReference": 0}, nil
+}
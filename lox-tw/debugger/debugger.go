@@ -0,0 +1,260 @@
+package debugger
+
+import (
+	"io"
+	"sync"
+
+	"lox-tw/ast"
+	"lox-tw/interpreter"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+	"lox-tw/token"
+)
+
+// Action tells a paused program how to go on.
+type Action uint8
+
+const (
+	CONTINUE Action = iota
+	STEP_IN
+	STEP_OVER
+	STEP_OUT
+	QUIT
+)
+
+type Frame struct {
+	Name        string
+	Line        uint
+	Environment *interpreter.Environment
+}
+
+// StopHandler is called on the program's goroutine every time it pauses. It
+// blocks until the user decides how to resume.
+type StopHandler func(reason string) Action
+
+type QuitError struct{}
+
+func (e *QuitError) Error() string {
+	return "Debugging session ended."
+}
+
+// Debugger pauses a program on breakpoints and steps through it. It hooks
+// into the interpreter as its Tracer.
+type Debugger struct {
+	mutex       sync.Mutex
+	breakpoints map[uint]bool
+	frames      []*Frame
+
+	// The last action and where it was taken, to know when a step is over.
+	action      Action
+	actionDepth int
+	actionLine  uint
+
+	pauseRequested bool
+	quitRequested  bool
+
+	onStop StopHandler
+	stdout io.Writer
+}
+
+func NewDebugger(onStop StopHandler, stdout io.Writer) *Debugger {
+	return &Debugger{
+		breakpoints: make(map[uint]bool),
+		onStop:      onStop,
+		stdout:      stdout,
+	}
+}
+
+// Run executes the program. When stopOnEntry is set, it pauses before the
+// first statement.
+func (d *Debugger) Run(stmts []ast.Stmt[any], stopOnEntry bool) error {
+	d.mutex.Lock()
+	d.frames = []*Frame{{Name: "<script>"}}
+	if stopOnEntry {
+		d.action = STEP_IN
+	}
+	d.mutex.Unlock()
+
+	codeInterpreter := interpreter.NewInterpreter()
+	codeInterpreter.SetOutput(d.stdout)
+	codeInterpreter.SetTracer(d)
+
+	for _, stmt := range stmts {
+		err := codeInterpreter.Execute(stmt)
+		if _, ok := err.(*interpreter.BreakError); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Debugger) SetBreakpoints(lines []uint) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.breakpoints = make(map[uint]bool)
+	for _, line := range lines {
+		d.breakpoints[line] = true
+	}
+}
+
+func (d *Debugger) AddBreakpoint(line uint) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.breakpoints[line] = true
+}
+
+func (d *Debugger) RemoveBreakpoint(line uint) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.breakpoints, line)
+}
+
+// Pause stops the running program before its next statement.
+func (d *Debugger) Pause() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.pauseRequested = true
+}
+
+// Stop ends the program before its next statement.
+func (d *Debugger) Stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.quitRequested = true
+}
+
+// Frames returns the call stack, innermost frame first.
+func (d *Debugger) Frames() []Frame {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	frames := make([]Frame, 0, len(d.frames))
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		frames = append(frames, *d.frames[i])
+	}
+	return frames
+}
+
+// Evaluate evaluates an expression in the environment of a frame, 0 being
+// the innermost one.
+func (d *Debugger) Evaluate(frame int, source string) (any, error) {
+	frames := d.Frames()
+	env := frames[frame].Environment
+
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := parser.ParseTokensToExpression(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the expression as if it was written where the frame is paused.
+	var scopes [][]string
+	for scope := env; !scope.IsGlobal(); scope = scope.Enclosing() {
+		scopes = append([][]string{scope.Names()}, scopes...)
+	}
+	codeResolver := resolver.NewResolverInScopes(scopes)
+	if _, err := expr.Accept(codeResolver); err != nil {
+		return nil, err
+	}
+
+	evaluator := interpreter.NewInterpreterWithEnv(env)
+	evaluator.SetOutput(d.stdout)
+	return expr.Accept(evaluator)
+}
+
+func (d *Debugger) BeforeStmt(i interpreter.Interpreter, stmt ast.Stmt[any]) error {
+	line := ast.FirstToken(stmt).Line
+
+	d.mutex.Lock()
+	if d.quitRequested {
+		d.mutex.Unlock()
+		return &QuitError{}
+	}
+
+	frame := d.frames[len(d.frames)-1]
+	previousLine := frame.Line
+	frame.Line, frame.Environment = line, i.Environment()
+
+	depth := len(d.frames)
+	reason := d.stopReason(line, previousLine, depth)
+	d.mutex.Unlock()
+
+	if reason == "" {
+		return nil
+	}
+
+	action := d.onStop(reason)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.action, d.actionDepth, d.actionLine = action, depth, line
+	if action == QUIT {
+		return &QuitError{}
+	}
+	return nil
+}
+
+// stopReason returns why the program should pause before a statement, or an
+// empty string to keep it running. Steps and breakpoints work on lines, not
+// on statements, so `if (a) print a;` is a single step.
+func (d *Debugger) stopReason(line, previousLine uint, depth int) string {
+	if d.pauseRequested {
+		d.pauseRequested = false
+		return "pause"
+	}
+
+	switch d.action {
+	case STEP_IN:
+		if d.actionDepth == 0 {
+			return "entry"
+		}
+		if depth != d.actionDepth || line != d.actionLine {
+			return "step"
+		}
+	case STEP_OVER:
+		if depth < d.actionDepth || (depth == d.actionDepth && line != d.actionLine) {
+			return "step"
+		}
+	case STEP_OUT:
+		if depth < d.actionDepth {
+			return "step"
+		}
+	}
+
+	if d.breakpoints[line] && line != previousLine {
+		return "breakpoint"
+	}
+
+	return ""
+}
+
+func (d *Debugger) Branch(site token.Token, branch int) {}
+
+func (d *Debugger) EnterCall(name string, line uint) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.frames = append(d.frames, &Frame{Name: name})
+}
+
+func (d *Debugger) ExitCall() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.frames = d.frames[:len(d.frames)-1]
+}
@@ -0,0 +1,261 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const SOURCE = `fun add(a, b) {
+    var sum = a + b;
+    return sum;
+}
+var x = 1;
+print add(x, 2);
+print "done";
+`
+
+func load(t *testing.T, source string) *Program {
+	program, errors := Load(source)
+	if len(errors) > 0 {
+		t.Fatalf("Error loading program: %v", errors)
+	}
+	return program
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		name        string
+		breakpoints []uint
+		actions     []Action
+		expected    []string
+	}{
+		{
+			name:     "step in",
+			actions:  []Action{STEP_IN, STEP_IN, STEP_IN, STEP_IN, STEP_IN, STEP_IN},
+			expected: []string{"entry <script>:1", "step <script>:5", "step <script>:6", "step add:2", "step add:3", "step <script>:7"},
+		},
+		{
+			name:     "step over",
+			actions:  []Action{STEP_OVER, STEP_OVER, STEP_OVER, STEP_OVER},
+			expected: []string{"entry <script>:1", "step <script>:5", "step <script>:6", "step <script>:7"},
+		},
+		{
+			name:     "step out",
+			actions:  []Action{STEP_IN, STEP_IN, STEP_IN, STEP_OUT, CONTINUE},
+			expected: []string{"entry <script>:1", "step <script>:5", "step <script>:6", "step add:2", "step <script>:7"},
+		},
+		{
+			name:        "breakpoints",
+			breakpoints: []uint{3, 7},
+			actions:     []Action{CONTINUE, CONTINUE, CONTINUE},
+			expected:    []string{"entry <script>:1", "breakpoint add:3", "breakpoint <script>:7"},
+		},
+		{
+			name:     "quit",
+			actions:  []Action{QUIT},
+			expected: []string{"entry <script>:1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program := load(t, SOURCE)
+
+			var stops []string
+			var debugger *Debugger
+			debugger = NewDebugger(func(reason string) Action {
+				frame := debugger.Frames()[0]
+				stops = append(stops, fmt.Sprintf("%s %s:%d", reason, frame.Name, frame.Line))
+				if len(stops) > len(test.actions) {
+					t.Fatalf("Unexpected stop: %s", stops[len(stops)-1])
+				}
+				return test.actions[len(stops)-1]
+			}, io.Discard)
+			debugger.SetBreakpoints(test.breakpoints)
+
+			if err := debugger.Run(program.Stmts, true); err != nil {
+				if _, ok := err.(*QuitError); !ok {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			if strings.Join(stops, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected stops:\n%s\nGot:\n%s", strings.Join(test.expected, "\n"), strings.Join(stops, "\n"))
+			}
+		})
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	input := "b 3\nc\nbt\nvars\np sum * 10\nf 1\np x + 1\nn\nc\n"
+	var output strings.Builder
+	if err := NewTerminal(load(t, SOURCE), strings.NewReader(input), &output).Run(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `Stopped (entry) in <script> at line 1:
+->    1  fun add(a, b) {
+(lox) Breakpoint set at line 3.
+(lox) Stopped (breakpoint) in add at line 3:
+->    3      return sum;
+(lox) * #0 add at line 3
+  #1 <script> at line 6
+(lox) Scope:
+  a = 1
+  b = 2
+  sum = 3
+Globals:
+  add = <fn add>
+  clock = <native fn>
+  list = <native fn>
+  map = <native fn>
+  range = <native fn>
+  str = <native fn>
+  x = 1
+(lox) 30
+(lox) #1 <script> at line 6
+(lox) 2
+(lox) 3
+Stopped (step) in <script> at line 7:
+->    7  print "done";
+(lox) done
+Program finished.
+`
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output.String())
+	}
+}
+
+// client drives an Adapter through pipes, the same way an editor would.
+type client struct {
+	t   *testing.T
+	in  *io.PipeWriter
+	out *bufio.Reader
+	seq int
+}
+
+func newClient(t *testing.T) *client {
+	adapterIn, clientOut := io.Pipe()
+	clientIn, adapterOut := io.Pipe()
+
+	go func() {
+		NewAdapter(adapterIn, adapterOut).Serve()
+		adapterOut.Close()
+	}()
+
+	return &client{t: t, in: clientOut, out: bufio.NewReader(clientIn)}
+}
+
+func (c *client) receive() map[string]any {
+	headers, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatalf("Error reading headers: %v", err)
+	}
+
+	length, _ := strconv.Atoi(headers.Get("Content-Length"))
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		c.t.Fatalf("Error reading body: %v", err)
+	}
+
+	var msg map[string]any
+	json.Unmarshal(body, &msg)
+	return msg
+}
+
+// request sends a request and returns its response body. Events received in
+// the meantime are returned too.
+func (c *client) request(command string, arguments any) (map[string]any, []map[string]any) {
+	c.seq += 1
+	body, _ := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+
+	var events []map[string]any
+	for {
+		msg := c.receive()
+		if msg["type"] == "event" {
+			events = append(events, msg)
+			continue
+		}
+
+		if msg["success"] != true {
+			c.t.Fatalf("%s failed: %v", command, msg["message"])
+		}
+		result, _ := msg["body"].(map[string]any)
+		return result, events
+	}
+}
+
+// waitFor reads messages until the given event, returning the events read.
+func (c *client) waitFor(event string) []map[string]any {
+	var events []map[string]any
+	for {
+		msg := c.receive()
+		events = append(events, msg)
+		if msg["event"] == event {
+			return events
+		}
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lox")
+	os.WriteFile(path, []byte(SOURCE), 0644)
+
+	c := newClient(t)
+	c.request("initialize", map[string]any{"adapterID": "lox"})
+	c.waitFor("initialized")
+	c.request("launch", map[string]any{"program": path})
+	c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []map[string]any{{"line": 3}},
+	})
+	c.request("configurationDone", nil)
+
+	stopped := c.waitFor("stopped")
+	if reason := stopped[len(stopped)-1]["body"].(map[string]any)["reason"]; reason != "breakpoint" {
+		t.Errorf("Expected to stop on a breakpoint, got %v", reason)
+	}
+
+	trace, _ := c.request("stackTrace", map[string]any{"threadId": THREAD_ID})
+	frames := trace["stackFrames"].([]any)
+	top := frames[0].(map[string]any)
+	if len(frames) != 2 || top["name"] != "add" || top["line"] != 3.0 {
+		t.Errorf("Unexpected stack trace: %v", frames)
+	}
+
+	scopes, _ := c.request("scopes", map[string]any{"frameId": 0})
+	locals := scopes["scopes"].([]any)[0].(map[string]any)
+	variables, _ := c.request("variables", map[string]any{"variablesReference": locals["variablesReference"]})
+	if fmt.Sprint(variables["variables"]) != "[map[name:a value:1 variablesReference:0] map[name:b value:2 variablesReference:0] map[name:sum value:3 variablesReference:0]]" {
+		t.Errorf("Unexpected variables: %v", variables["variables"])
+	}
+
+	evaluated, _ := c.request("evaluate", map[string]any{"expression": "a + b * 10", "frameId": 0})
+	if evaluated["result"] != "21" {
+		t.Errorf("Expected 21, got %v", evaluated["result"])
+	}
+
+	_, events := c.request("continue", map[string]any{"threadId": THREAD_ID})
+	events = append(events, c.waitFor("terminated")...)
+
+	var output string
+	for _, event := range events {
+		if event["event"] == "output" {
+			output += event["body"].(map[string]any)["output"].(string)
+		}
+	}
+	if output != "3\ndone\n" {
+		t.Errorf("Expected the program output, got %q", output)
+	}
+
+	c.request("disconnect", nil)
+}
@@ -0,0 +1,47 @@
+package debugger
+
+import (
+	"strings"
+
+	"lox-tw/ast"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+)
+
+// Program is a script ready to be debugged.
+type Program struct {
+	Lines []string
+	Stmts []ast.Stmt[any]
+}
+
+// Load scans, parses and resolves a script, returning every error found.
+func Load(source string) (*Program, []error) {
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	stmts, errors := parser.ParseTokensToStmtsWithErrors(tokens)
+	if len(errors) > 0 {
+		return nil, errors
+	}
+
+	codeResolver := resolver.NewResolver()
+	if errors := codeResolver.Resolve(stmts); len(errors) > 0 {
+		return nil, errors
+	}
+
+	return &Program{
+		Lines: strings.Split(source, "\n"),
+		Stmts: stmts,
+	}, nil
+}
+
+// Line returns the source of a line, numbered from 1.
+func (p *Program) Line(line uint) string {
+	if line == 0 || int(line) > len(p.Lines) {
+		return ""
+	}
+	return p.Lines[line-1]
+}
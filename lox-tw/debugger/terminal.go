@@ -0,0 +1,182 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"lox-tw/interpreter"
+)
+
+const TERMINAL_HELP = `Commands:
+  break LINE (b)      set a breakpoint
+  delete LINE (d)     remove a breakpoint
+  continue (c)        run until the next breakpoint
+  step (s)            step to the next line, entering calls
+  next (n)            step to the next line, over calls
+  out (o)             run until the current function returns
+  stack (bt)          show the call stack
+  frame N (f)         select a frame of the call stack
+  vars (v)            show the variables of the selected frame
+  print EXPR (p)      evaluate an expression in the selected frame
+  list (l)            show the source around the current line
+  quit (q)            stop the program
+`
+
+// Terminal drives a debugger from a line-based prompt. The program starts
+// paused on its first statement so breakpoints can be set.
+type Terminal struct {
+	program  *Program
+	debugger *Debugger
+	input    *bufio.Scanner
+	output   io.Writer
+
+	frame int
+}
+
+func NewTerminal(program *Program, in io.Reader, out io.Writer) *Terminal {
+	t := &Terminal{
+		program: program,
+		input:   bufio.NewScanner(in),
+		output:  out,
+	}
+	t.debugger = NewDebugger(t.stop, out)
+	return t
+}
+
+// Run executes the program until it ends or the user quits. Runtime errors
+// are returned.
+func (t *Terminal) Run() error {
+	err := t.debugger.Run(t.program.Stmts, true)
+	if _, ok := err.(*QuitError); ok {
+		return nil
+	}
+	if err == nil {
+		fmt.Fprintln(t.output, "Program finished.")
+	}
+	return err
+}
+
+func (t *Terminal) stop(reason string) Action {
+	t.frame = 0
+	frame := t.debugger.Frames()[0]
+	fmt.Fprintf(t.output, "Stopped (%s) in %s at line %d:\n", reason, frame.Name, frame.Line)
+	t.list(frame.Line, 0)
+
+	for {
+		fmt.Fprint(t.output, "(lox) ")
+		if !t.input.Scan() {
+			fmt.Fprintln(t.output)
+			return QUIT
+		}
+
+		command, argument, _ := strings.Cut(strings.TrimSpace(t.input.Text()), " ")
+		argument = strings.TrimSpace(argument)
+
+		switch command {
+		case "":
+		case "continue", "c":
+			return CONTINUE
+		case "step", "s":
+			return STEP_IN
+		case "next", "n":
+			return STEP_OVER
+		case "out", "o":
+			return STEP_OUT
+		case "quit", "q":
+			return QUIT
+		case "break", "b":
+			if line, ok := t.line(argument); ok {
+				t.debugger.AddBreakpoint(line)
+				fmt.Fprintf(t.output, "Breakpoint set at line %d.\n", line)
+			}
+		case "delete", "d":
+			if line, ok := t.line(argument); ok {
+				t.debugger.RemoveBreakpoint(line)
+				fmt.Fprintf(t.output, "Breakpoint removed from line %d.\n", line)
+			}
+		case "stack", "bt":
+			t.stack()
+		case "frame", "f":
+			t.selectFrame(argument)
+		case "vars", "v":
+			t.vars()
+		case "print", "p":
+			value, err := t.debugger.Evaluate(t.frame, argument)
+			if err != nil {
+				fmt.Fprintf(t.output, "%v\n", err)
+			} else {
+				fmt.Fprintln(t.output, interpreter.Stringify(value))
+			}
+		case "list", "l":
+			t.list(t.debugger.Frames()[t.frame].Line, 5)
+		case "help", "h":
+			fmt.Fprint(t.output, TERMINAL_HELP)
+		default:
+			fmt.Fprintf(t.output, "Unknown command '%s', type 'help' for a list.\n", command)
+		}
+	}
+}
+
+func (t *Terminal) line(argument string) (uint, bool) {
+	line, err := strconv.ParseUint(argument, 10, 0)
+	if err != nil || line == 0 || int(line) > len(t.program.Lines) {
+		fmt.Fprintf(t.output, "Invalid line '%s'.\n", argument)
+		return 0, false
+	}
+	return uint(line), true
+}
+
+func (t *Terminal) stack() {
+	for i, frame := range t.debugger.Frames() {
+		marker := " "
+		if i == t.frame {
+			marker = "*"
+		}
+		fmt.Fprintf(t.output, "%s #%d %s at line %d\n", marker, i, frame.Name, frame.Line)
+	}
+}
+
+func (t *Terminal) selectFrame(argument string) {
+	frames := t.debugger.Frames()
+	frame, err := strconv.Atoi(argument)
+	if err != nil || frame < 0 || frame >= len(frames) {
+		fmt.Fprintf(t.output, "Invalid frame '%s'.\n", argument)
+		return
+	}
+
+	t.frame = frame
+	fmt.Fprintf(t.output, "#%d %s at line %d\n", frame, frames[frame].Name, frames[frame].Line)
+}
+
+// vars prints the environment chain of the selected frame, innermost scope
+// first.
+func (t *Terminal) vars() {
+	for env := t.debugger.Frames()[t.frame].Environment; env != nil; env = env.Enclosing() {
+		if env.IsGlobal() {
+			fmt.Fprintln(t.output, "Globals:")
+		} else {
+			fmt.Fprintln(t.output, "Scope:")
+		}
+
+		for _, name := range env.Names() {
+			value, _ := env.GetAtByLexeme(0, name)
+			fmt.Fprintf(t.output, "  %s = %s\n", name, interpreter.Stringify(value))
+		}
+	}
+}
+
+// list prints the lines around a line, marking it with an arrow.
+func (t *Terminal) list(line uint, context uint) {
+	first := max(int(line)-int(context), 1)
+	last := min(int(line+context), len(t.program.Lines))
+	for i := first; i <= last; i++ {
+		marker := "  "
+		if uint(i) == line {
+			marker = "->"
+		}
+		fmt.Fprintf(t.output, "%s %4d  %s\n", marker, i, t.program.Line(uint(i)))
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"lox-tw/formatter"
+)
+
+func runFormat(arguments []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "report files that are not formatted instead of printing them")
+	write := flags.Bool("write", false, "rewrite files in place")
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 || (*check && *write) {
+		fmt.Println("Usage: lox-tw fmt [--check | --write] files...")
+		return 64
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			return 66
+		}
+
+		formatted, err := formatter.Format(string(content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			status = 65
+			continue
+		}
+
+		switch {
+		case *check:
+			if formatted != string(content) {
+				fmt.Println(path)
+				if status == 0 {
+					status = 1
+				}
+			}
+		case *write:
+			if formatted == string(content) {
+				continue
+			}
+			if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+				return 74
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+
+	return status
+}
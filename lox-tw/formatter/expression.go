@@ -0,0 +1,97 @@
+package formatter
+
+import (
+	"strings"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+func (f *Formatter) VisitGroupingExpr(expr ast.GroupingExpr[any]) (any, error) {
+	return "(" + f.expr(expr.Expression) + ")", nil
+}
+
+func (f *Formatter) VisitTernaryExpr(expr ast.TernaryExpr[any]) (any, error) {
+	return f.expr(expr.Condition) + " ? " + f.expr(expr.TrueExpr) + " : " + f.expr(expr.FalseExpr), nil
+}
+
+func (f *Formatter) VisitBinaryExpr(expr ast.BinaryExpr[any]) (any, error) {
+	if expr.Operator.Type == token.COMMA {
+		return f.expr(expr.Left) + ", " + f.expr(expr.Right), nil
+	}
+
+	return f.expr(expr.Left) + " " + expr.Operator.Lexeme + " " + f.expr(expr.Right), nil
+}
+
+func (f *Formatter) VisitUnaryExpr(expr ast.UnaryExpr[any]) (any, error) {
+	return expr.Operator.Lexeme + f.expr(expr.Right), nil
+}
+
+func (f *Formatter) VisitCallExpr(expr ast.CallExpr[any]) (any, error) {
+	var arguments []string
+	for i, argument := range expr.Arguments {
+		if i < len(expr.Names) && expr.Names[i] != nil {
+			arguments = append(arguments, expr.Names[i].Lexeme+": "+f.expr(argument))
+			continue
+		}
+		if i < len(expr.Spreads) && expr.Spreads[i] != nil {
+			arguments = append(arguments, "..."+f.expr(argument))
+			continue
+		}
+		arguments = append(arguments, f.expr(argument))
+	}
+	return f.expr(expr.Callee) + "(" + strings.Join(arguments, ", ") + ")", nil
+}
+
+func (f *Formatter) VisitGetExpr(expr ast.GetExpr[any]) (any, error) {
+	return f.expr(expr.Object) + "." + expr.Name.Lexeme, nil
+}
+
+func (f *Formatter) VisitSetExpr(expr ast.SetExpr[any]) (any, error) {
+	return f.expr(expr.Object) + "." + expr.Name.Lexeme + " = " + f.expr(expr.Value), nil
+}
+
+func (f *Formatter) VisitThisExpr(expr ast.ThisExpr[any]) (any, error) {
+	return "this", nil
+}
+
+func (f *Formatter) VisitLogicalExpr(expr ast.LogicalExpr[any]) (any, error) {
+	return f.expr(expr.Left) + " " + expr.Operator.Lexeme + " " + f.expr(expr.Right), nil
+}
+
+func (f *Formatter) VisitLiteralExpr(expr ast.LiteralExpr[any]) (any, error) {
+	return expr.Token.Lexeme, nil
+}
+
+func (f *Formatter) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
+	return "super." + expr.Method.Lexeme, nil
+}
+
+func (f *Formatter) VisitInnerExpr(expr ast.InnerExpr[any]) (any, error) {
+	return "inner(" + f.exprs(expr.Arguments) + ")", nil
+}
+
+func (f *Formatter) VisitNothingExpr(expr ast.NothingExpr[any]) (any, error) {
+	return "", nil
+}
+
+func (f *Formatter) VisitVarExpr(expr ast.VarExpr[any]) (any, error) {
+	return expr.Name.Lexeme, nil
+}
+
+func (f *Formatter) VisitAssignExpr(expr ast.AssignExpr[any]) (any, error) {
+	return expr.Name.Lexeme + " = " + f.expr(expr.Value), nil
+}
+
+// Lambda bodies are printed as lines of their own and joined back into a
+// single, multi-line, expression.
+func (f *Formatter) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
+	lines, prefix, lastLineHasComment := f.lines, f.prefix, f.lastLineHasComment
+	f.lines, f.prefix = nil, ""
+
+	err := f.body("fun "+f.signature(expr.Parameters, expr.ParameterTypes, expr.Defaults, expr.Ellipsis, expr.ReturnType)+" ", expr.Body, expr.RightBrace)
+	lambda := strings.TrimLeft(strings.Join(f.lines, "\n"), " ")
+
+	f.lines, f.prefix, f.lastLineHasComment = lines, prefix, lastLineHasComment
+	return lambda, err
+}
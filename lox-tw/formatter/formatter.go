@@ -0,0 +1,266 @@
+package formatter
+
+import (
+	"sort"
+	"strings"
+
+	"lox-tw/ast"
+	"lox-tw/parser"
+	"lox-tw/scanner"
+	"lox-tw/token"
+)
+
+const INDENT = "    "
+
+type anchoredTrivia struct {
+	position uint
+	trivia   token.Trivia
+}
+
+// Formatter prints statements back to Lox source. Comments and blank lines
+// are taken from the token trivia and emitted before the first statement
+// that starts after them, so formatting twice gives the same output.
+type Formatter struct {
+	lines  []string
+	indent int
+
+	// Text to put in front of the next written line, used to print nested
+	// statements on the same line as their header, e.g. `if (a) print a;`.
+	prefix string
+
+	lastLineHasComment bool
+
+	trivia []anchoredTrivia
+	next   int
+}
+
+func Format(source string) (string, error) {
+	tokens, err := scanner.ScanTokensWithTrivia(source)
+	if err != nil {
+		return "", err
+	}
+
+	stmts, err := parser.ParseTokensToStmts(tokens)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatStmts(stmts, tokens), nil
+}
+
+// FormatStmts formats already parsed statements. The tokens are the ones the
+// statements were parsed from and only provide the trivia.
+func FormatStmts(stmts []ast.Stmt[any], tokens []token.Token) string {
+	f := newFormatter(tokens)
+	for _, stmt := range stmts {
+		stmt.Accept(f)
+	}
+
+	if len(tokens) > 0 {
+		f.flush(tokens[len(tokens)-1].Position)
+	}
+	f.trimBlankLines()
+
+	if len(f.lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(f.lines, "\n") + "\n"
+}
+
+func newFormatter(tokens []token.Token) *Formatter {
+	var trivia []anchoredTrivia
+	for _, tok := range tokens {
+		for _, t := range tok.LeadingTrivia() {
+			trivia = append(trivia, anchoredTrivia{position: tok.Position, trivia: t})
+		}
+	}
+
+	return &Formatter{trivia: trivia}
+}
+
+func (f *Formatter) write(text string) {
+	line := f.takePrefix() + text
+	f.lines = append(f.lines, line)
+	f.lastLineHasComment = false
+}
+
+func (f *Formatter) takePrefix() string {
+	if f.prefix != "" {
+		prefix := f.prefix
+		f.prefix = ""
+		return prefix
+	}
+
+	return strings.Repeat(INDENT, f.indent)
+}
+
+// flush emits every comment and blank line anchored at or before position.
+func (f *Formatter) flush(position uint) {
+	for f.next < len(f.trivia) && f.trivia[f.next].position <= position {
+		f.emitTrivia(f.trivia[f.next].trivia)
+		f.next += 1
+	}
+}
+
+func (f *Formatter) emitTrivia(trivia token.Trivia) {
+	last := len(f.lines) - 1
+
+	switch {
+	case trivia.Kind == token.BLANK_LINE:
+		if last >= 0 && f.lines[last] != "" && !strings.HasSuffix(f.lines[last], "{") {
+			f.lines = append(f.lines, "")
+		}
+	case trivia.Trailing && last >= 0 && f.lines[last] != "" && !f.lastLineHasComment:
+		f.lines[last] += " " + trivia.Text
+		f.lastLineHasComment = trivia.Kind == token.LINE_COMMENT
+	default:
+		f.write(trivia.Text)
+		f.lastLineHasComment = trivia.Kind == token.LINE_COMMENT
+	}
+}
+
+func (f *Formatter) trimBlankLines() {
+	for len(f.lines) > 0 && f.lines[len(f.lines)-1] == "" {
+		f.lines = f.lines[:len(f.lines)-1]
+	}
+}
+
+// nested prints a statement that belongs to a header such as `while (a) `.
+// Block statements open on the header line, other statements follow it. When
+// a comment follows the header on its line, the statement goes on the next
+// one, indented unless it is a block.
+func (f *Formatter) nested(header string, stmt ast.Stmt[any]) error {
+	prefix := f.takePrefix() + header
+	position := ast.FirstToken(stmt).Position
+	if f.next >= len(f.trivia) || f.trivia[f.next].position > position || !f.trivia[f.next].trivia.Trailing {
+		f.flush(position)
+		f.prefix = prefix
+		return stmt.Accept(f)
+	}
+
+	f.lines = append(f.lines, strings.TrimRight(prefix, " "))
+	f.lastLineHasComment = false
+	f.flush(position)
+
+	if block, ok := stmt.(ast.BlockStmt[any]); ok && block.LeftBrace.Type == token.LEFT_BRACE {
+		return stmt.Accept(f)
+	}
+	f.indent += 1
+	defer func() { f.indent -= 1 }()
+	return stmt.Accept(f)
+}
+
+// body prints `header {`, the statements indented, and the closing brace.
+func (f *Formatter) body(header string, statements []ast.Stmt[any], rightBrace token.Token) error {
+	return f.braces(header, rightBrace, func() error {
+		for _, statement := range statements {
+			if err := statement.Accept(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (f *Formatter) braces(header string, rightBrace token.Token, inner func() error) error {
+	f.write(header + "{")
+	opened := len(f.lines)
+
+	f.indent += 1
+	if err := inner(); err != nil {
+		return err
+	}
+	f.flush(rightBrace.Position)
+	f.trimBlankLines()
+	f.indent -= 1
+
+	if len(f.lines) == opened && !f.lastLineHasComment {
+		f.lines[opened-1] += "}"
+		return nil
+	}
+
+	f.write("}")
+	return nil
+}
+
+func (f *Formatter) expr(expr ast.Expr[any]) string {
+	if expr == nil {
+		return ""
+	}
+
+	value, _ := expr.Accept(f)
+	return value.(string)
+}
+
+func (f *Formatter) exprs(exprs []ast.Expr[any]) string {
+	var values []string
+	for _, expr := range exprs {
+		values = append(values, f.expr(expr))
+	}
+	return strings.Join(values, ", ")
+}
+
+// signature returns the parameters of a function in parentheses and its return
+// type, with their annotations and default values.
+func (f *Formatter) signature(params []token.Token, paramTypes []*token.Token, defaults []ast.Expr[any], ellipsis *token.Token, returnType *token.Token) string {
+	var names []string
+	for i, param := range params {
+		var paramType *token.Token
+		if i < len(paramTypes) {
+			paramType = paramTypes[i]
+		}
+		name := param.Lexeme + annotation(paramType)
+		if i < len(defaults) && defaults[i] != nil {
+			name += " = " + f.expr(defaults[i])
+		}
+		if ellipsis != nil && i == len(params)-1 {
+			name = "..." + name
+		}
+		names = append(names, name)
+	}
+	return "(" + strings.Join(names, ", ") + ")" + annotation(returnType)
+}
+
+func annotation(typeName *token.Token) string {
+	if typeName == nil {
+		return ""
+	}
+	return ": " + typeName.Lexeme
+}
+
+// Methods, class methods, getters and setters are stored apart, sort them
+// back into the order they were written in.
+func classMethods(stmt ast.ClassStmt[any]) []ast.FunctionStmt[any] {
+	methods := stmt.Functions()
+	sort.SliceStable(methods, func(a, b int) bool {
+		return methods[a].Name.Position < methods[b].Name.Position
+	})
+	return methods
+}
+
+// methodHeader returns what precedes the body of a method of a class.
+func (f *Formatter) methodHeader(stmt ast.ClassStmt[any], method ast.FunctionStmt[any]) string {
+	switch {
+	case declares(stmt.GlobalMethods, method):
+		return "class " + method.Name.Lexeme + f.functionSignature(method) + " "
+	case declares(stmt.Getters, method):
+		return method.Name.Lexeme + annotation(method.ReturnType) + " "
+	case declares(stmt.Setters, method):
+		return method.Name.Lexeme + "=" + f.functionSignature(method) + " "
+	}
+	return method.Name.Lexeme + f.functionSignature(method) + " "
+}
+
+func (f *Formatter) functionSignature(function ast.FunctionStmt[any]) string {
+	return f.signature(function.Parameters, function.ParameterTypes, function.Defaults, function.Ellipsis, function.ReturnType)
+}
+
+func declares(methods []ast.FunctionStmt[any], method ast.FunctionStmt[any]) bool {
+	for _, declared := range methods {
+		if declared.Name == method.Name {
+			return true
+		}
+	}
+	return false
+}
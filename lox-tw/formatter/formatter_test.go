@@ -0,0 +1,117 @@
+package formatter
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "Spacing",
+			source:   "var a=1+2*3;print a;",
+			expected: "var a = 1 + 2 * 3;\nprint a;\n",
+		},
+		{
+			name:     "Comments",
+			source:   "// leading\nvar a = 1; // trailing\n/* block */ print a;\n// end",
+			expected: "// leading\nvar a = 1; // trailing\n/* block */\nprint a;\n// end\n",
+		},
+		{
+			name:     "Blank lines",
+			source:   "var a;\n\n\n\nvar b;\n{\n\n  print a;\n\n}",
+			expected: "var a;\n\nvar b;\n{\n    print a;\n}\n",
+		},
+		{
+			name:     "If else",
+			source:   "if (a) { print 1; } else if (b) print 2; else { print 3; }",
+			expected: "if (a) {\n    print 1;\n} else if (b) print 2;\nelse {\n    print 3;\n}\n",
+		},
+		{
+			name:     "For loops",
+			source:   "for (var i = 0; i < 3; i = i + 1) print i; for (;;) { break; }",
+			expected: "for (var i = 0; i < 3; i = i + 1) print i;\nfor (;;) {\n    break;\n}\n",
+		},
+		{
+			name:     "Nested for loops",
+			source:   "for (;;) for (var i = 0; i < 1;) print i; for (;; i = i + 1) for (j = 0;; j = j + 1) {}",
+			expected: "for (;;) for (var i = 0; i < 1;) print i;\nfor (;; i = i + 1) for (j = 0;; j = j + 1) {}\n",
+		},
+		{
+			name:     "Comment after loop header",
+			source:   "print 1;\nwhile (true) // forever\n  print 2;\nfor (;;) // block\n{ break; }",
+			expected: "print 1;\nwhile (true) // forever\n    print 2;\nfor (;;) // block\n{\n    break;\n}\n",
+		},
+		{
+			name:     "Functions",
+			source:   "fun f(a,b){\n// body\nreturn fun(c){return a+b+c;};\n}\nfun g(){}",
+			expected: "fun f(a, b) {\n    // body\n    return fun (c) {\n        return a + b + c;\n    };\n}\nfun g() {}\n",
+		},
+		{
+			name:     "Classes",
+			source:   "class A < B { class make() { return A(); } init() { super.init(); this.x = 1; } }",
+			expected: "class A < B {\n    class make() {\n        return A();\n    }\n    init() {\n        super.init();\n        this.x = 1;\n    }\n}\n",
+		},
+		{
+			name:     "Getters and setters",
+			source:   "class A { size { return this.n; } size=(n) { this.n=n; } }",
+			expected: "class A {\n    size {\n        return this.n;\n    }\n    size=(n) {\n        this.n = n;\n    }\n}\n",
+		},
+		{
+			name:     "Traits",
+			source:   "trait T{m(){}} class A<B with T,U{}",
+			expected: "trait T {\n    m() {}\n}\nclass A < B with T, U {}\n",
+		},
+		{
+			name:     "Type annotations",
+			source:   "var a:Number=1;fun f(a:String,b):Bool{} class A{size:Number{} size=(v:Number){}} var g=fun(x:A){};",
+			expected: "var a: Number = 1;\nfun f(a: String, b): Bool {}\nclass A {\n    size: Number {}\n    size=(v: Number) {}\n}\nvar g = fun (x: A) {};\n",
+		},
+		{
+			name:     "Default and named arguments",
+			source:   "fun f(a,b:Number=1+2){} f(1,b:2); var g=fun(x=nil){};",
+			expected: "fun f(a, b: Number = 1 + 2) {}\nf(1, b: 2);\nvar g = fun (x = nil) {};\n",
+		},
+		{
+			name:     "Rest parameters and spread arguments",
+			source:   "fun f(a,...rest){} f(1,...xs,2); var g=fun(...all:Number){};",
+			expected: "fun f(a, ...rest) {}\nf(1, ...xs, 2);\nvar g = fun (...all: Number) {};\n",
+		},
+		{
+			name:     "Yield",
+			source:   "fun g(){yield 1+2;yield;}",
+			expected: "fun g() {\n    yield 1 + 2;\n    yield;\n}\n",
+		},
+		{
+			name:     "For-in",
+			source:   "for(var x in xs)print x; for (var c:String in \"ab\") {print c;}",
+			expected: "for (var x in xs) print x;\nfor (var c: String in \"ab\") {\n    print c;\n}\n",
+		},
+		{
+			name:     "Comment before closing brace",
+			source:   "while (true) {\n  break;\n  // done\n}",
+			expected: "while (true) {\n    break;\n    // done\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := Format(tt.source)
+			if err != nil {
+				t.Fatalf("Error formatting source: %v", err)
+			}
+			if formatted != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, formatted)
+			}
+
+			again, err := Format(formatted)
+			if err != nil {
+				t.Fatalf("Error formatting formatted source: %v", err)
+			}
+			if again != formatted {
+				t.Errorf("Formatting is not idempotent, got:\n%s", again)
+			}
+		})
+	}
+}
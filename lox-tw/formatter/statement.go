@@ -0,0 +1,228 @@
+package formatter
+
+import (
+	"strings"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+func (f *Formatter) VisitVarStmt(stmt ast.VarStmt[any]) error {
+	f.flush(stmt.Name.Position)
+	f.write(f.varDeclaration(stmt))
+	return nil
+}
+
+func (f *Formatter) varDeclaration(stmt ast.VarStmt[any]) string {
+	if _, ok := stmt.Initializer.(ast.NothingExpr[any]); ok || stmt.Initializer == nil {
+		return "var " + stmt.Name.Lexeme + annotation(stmt.VariableType) + ";"
+	}
+
+	return "var " + stmt.Name.Lexeme + annotation(stmt.VariableType) + " = " + f.expr(stmt.Initializer) + ";"
+}
+
+func (f *Formatter) VisitExpressionStmt(stmt ast.ExpressionStmt[any]) error {
+	f.flush(ast.FirstExprToken(stmt.Expression).Position)
+	f.write(f.expr(stmt.Expression) + ";")
+	return nil
+}
+
+func (f *Formatter) VisitIfStmt(stmt ast.IfStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	if err := f.nested("if ("+f.expr(stmt.Condition)+") ", stmt.ThenBranch); err != nil {
+		return err
+	}
+
+	if stmt.ElseBranch == nil {
+		return nil
+	}
+
+	f.flush(ast.FirstToken(stmt.ElseBranch).Position)
+	last := len(f.lines) - 1
+	if _, ok := stmt.ThenBranch.(ast.BlockStmt[any]); ok && !f.lastLineHasComment && f.lines[last] != "" {
+		f.prefix = f.lines[last] + " else "
+		f.lines = f.lines[:last]
+		return stmt.ElseBranch.Accept(f)
+	}
+
+	return f.nested("else ", stmt.ElseBranch)
+}
+
+func (f *Formatter) VisitWhileStmt(stmt ast.WhileStmt[any]) error {
+	if stmt.Keyword.Type == token.FOR {
+		// A for loop without initializer, desugared to the while loop alone.
+		return f.forLoop(nil, stmt)
+	}
+
+	f.flush(stmt.Keyword.Position)
+	return f.nested("while ("+f.expr(stmt.Condition)+") ", stmt.Body)
+}
+
+// forLoop prints back a for loop that the parser desugared into a while loop,
+// see parseForStatement.
+func (f *Formatter) forLoop(initializer ast.Stmt[any], loop ast.WhileStmt[any]) error {
+	f.flush(loop.Keyword.Position)
+
+	header := "for ("
+	switch initializer := initializer.(type) {
+	case ast.VarStmt[any]:
+		header += f.varDeclaration(initializer)
+	case ast.ExpressionStmt[any]:
+		header += f.expr(initializer.Expression) + ";"
+	default:
+		header += ";"
+	}
+
+	if literal, ok := loop.Condition.(ast.LiteralExpr[any]); !ok || literal.Token.Type != token.FOR {
+		header += " " + f.expr(loop.Condition)
+	}
+	header += ";"
+
+	body := loop.Body
+	if inner, increment, ok := incrementBlock(loop); ok {
+		body = inner
+		header += " " + f.expr(increment.Expression)
+	}
+
+	return f.nested(header+") ", body)
+}
+
+// initializerBlock returns the initializer and the loop of the block a for
+// loop with an initializer is desugared to. The block carries the 'for'
+// keyword of its loop in place of braces.
+func initializerBlock(block ast.BlockStmt[any]) (ast.Stmt[any], ast.WhileStmt[any], bool) {
+	if block.LeftBrace.Type != token.FOR || len(block.Statements) != 2 {
+		return nil, ast.WhileStmt[any]{}, false
+	}
+
+	loop, ok := block.Statements[1].(ast.WhileStmt[any])
+	if !ok || loop.Keyword.Position != block.LeftBrace.Position {
+		return nil, ast.WhileStmt[any]{}, false
+	}
+	return block.Statements[0], loop, true
+}
+
+// incrementBlock returns the body and the increment of the block the body of
+// a for loop with an increment is desugared to. A for loop nested as the body
+// of another is a block with the 'for' keyword too, but its own.
+func incrementBlock(loop ast.WhileStmt[any]) (ast.Stmt[any], ast.ExpressionStmt[any], bool) {
+	block, ok := loop.Body.(ast.BlockStmt[any])
+	if !ok || block.LeftBrace.Type != token.FOR || block.LeftBrace.Position != loop.Keyword.Position || len(block.Statements) != 2 {
+		return nil, ast.ExpressionStmt[any]{}, false
+	}
+
+	increment, ok := block.Statements[1].(ast.ExpressionStmt[any])
+	if !ok {
+		return nil, ast.ExpressionStmt[any]{}, false
+	}
+	return block.Statements[0], increment, true
+}
+
+func (f *Formatter) VisitForInStmt(stmt ast.ForInStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	header := "for (var " + stmt.Name.Lexeme + annotation(stmt.VariableType) + " in " + f.expr(stmt.Iterable) + ") "
+	return f.nested(header, stmt.Body)
+}
+
+func (f *Formatter) VisitPrintStmt(stmt ast.PrintStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	f.write("print " + f.expr(stmt.Expression) + ";")
+	return nil
+}
+
+func (f *Formatter) VisitClassStmt(stmt ast.ClassStmt[any]) error {
+	f.flush(stmt.Name.Position)
+
+	header := "class " + stmt.Name.Lexeme + " "
+	if stmt.Superclass != nil {
+		header += "< " + stmt.Superclass.Name.Lexeme + " "
+	}
+	if len(stmt.Traits) > 0 {
+		var traits []string
+		for _, trait := range stmt.Traits {
+			traits = append(traits, trait.Name.Lexeme)
+		}
+		header += "with " + strings.Join(traits, ", ") + " "
+	}
+
+	return f.braces(header, stmt.RightBrace, func() error {
+		for _, method := range classMethods(stmt) {
+			if err := f.method(method, f.methodHeader(stmt, method)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (f *Formatter) VisitTraitStmt(stmt ast.TraitStmt[any]) error {
+	f.flush(stmt.Name.Position)
+
+	return f.braces("trait "+stmt.Name.Lexeme+" ", stmt.RightBrace, func() error {
+		for _, method := range stmt.Methods {
+			if err := f.method(method, method.Name.Lexeme+f.functionSignature(method)+" "); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (f *Formatter) method(method ast.FunctionStmt[any], header string) error {
+	f.flush(method.Name.Position)
+	return f.body(header, method.Body, method.RightBrace)
+}
+
+func (f *Formatter) VisitBlockStmt(stmt ast.BlockStmt[any]) error {
+	if initializer, loop, ok := initializerBlock(stmt); ok {
+		return f.forLoop(initializer, loop)
+	}
+
+	f.flush(stmt.LeftBrace.Position)
+	return f.body("", stmt.Statements, stmt.RightBrace)
+}
+
+func (f *Formatter) VisitBreakStmt(stmt ast.BreakStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	f.write("break;")
+	return nil
+}
+
+func (f *Formatter) VisitFunctionStmt(stmt ast.FunctionStmt[any]) error {
+	f.flush(stmt.Name.Position)
+	header := "fun " + stmt.Name.Lexeme + f.functionSignature(stmt) + " "
+	return f.body(header, stmt.Body, stmt.RightBrace)
+}
+
+func (f *Formatter) VisitAssertStmt(stmt ast.AssertStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	if stmt.Message == nil {
+		f.write("assert " + f.expr(stmt.Condition) + ";")
+		return nil
+	}
+
+	f.write("assert " + f.expr(stmt.Condition) + ", " + f.expr(stmt.Message) + ";")
+	return nil
+}
+
+func (f *Formatter) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	if stmt.Value == nil {
+		f.write("return;")
+		return nil
+	}
+
+	f.write("return " + f.expr(stmt.Value) + ";")
+	return nil
+}
+
+func (f *Formatter) VisitYieldStmt(stmt ast.YieldStmt[any]) error {
+	f.flush(stmt.Keyword.Position)
+	if stmt.Value == nil {
+		f.write("yield;")
+		return nil
+	}
+
+	f.write("yield " + f.expr(stmt.Value) + ";")
+	return nil
+}
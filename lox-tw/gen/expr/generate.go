@@ -30,21 +30,22 @@ func main() {
 		ReturnType: "T, error",
 		Expressions: []Expression{
 			{"Grouping", []Field{{"Expression", "Expr[T]"}}},
-			{"Ternary", []Field{{"Condition", "Expr[T]"}, {"TrueExpr", "Expr[T]"}, {"FalseExpr", "Expr[T]"}}},
+			{"Ternary", []Field{{"Condition", "Expr[T]"}, {"Question", "token.Token"}, {"TrueExpr", "Expr[T]"}, {"FalseExpr", "Expr[T]"}}},
 			{"Binary", []Field{{"Left", "Expr[T]"}, {"Operator", "token.Token"}, {"Right", "Expr[T]"}}},
 			{"Unary", []Field{{"Operator", "token.Token"}, {"Right", "Expr[T]"}}},
-			{"Call", []Field{{"Callee", "Expr[T]"}, {"Parenthesis", "token.Token"}, {"Arguments", "[]Expr[T]"}}},
-			{"Get", []Field{{"Object", "Expr[T]"}, {"Name", "token.Token"}}},
+			{"Call", []Field{{"Callee", "Expr[T]"}, {"Parenthesis", "token.Token"}, {"Arguments", "[]Expr[T]"}, {"Names", "[]*token.Token"}, {"Spreads", "[]*token.Token"}}},
+			{"Get", []Field{{"Object", "Expr[T]"}, {"Name", "token.Token"}, {"Cache", "*PropertyCache"}}},
 			{"Set", []Field{{"Object", "Expr[T]"}, {"Name", "token.Token"}, {"Value", "Expr[T]"}}},
-			{"This", []Field{{"Keyword", "token.Token"}}},
+			{"This", []Field{{"Keyword", "token.Token"}, {"Binding", "*Binding"}}},
 			{"Logical", []Field{{"Left", "Expr[T]"}, {"Operator", "token.Token"}, {"Right", "Expr[T]"}}},
-			{"Literal", []Field{{"Value", "any"}}},
-			{"Super", []Field{{"Keyword", "token.Token"}, {"Method", "token.Token"}}},
+			{"Literal", []Field{{"Token", "token.Token"}, {"Value", "any"}}},
+			{"Super", []Field{{"Keyword", "token.Token"}, {"Method", "token.Token"}, {"Binding", "*Binding"}}},
+			{"Inner", []Field{{"Keyword", "token.Token"}, {"Parenthesis", "token.Token"}, {"Arguments", "[]Expr[T]"}, {"Binding", "*Binding"}}},
 			{"Nothing", nil},
 
-			{"Var", []Field{{"Name", "token.Token"}}},
-			{"Assign", []Field{{"Name", "token.Token"}, {"Value", "Expr[T]"}}},
-			{"Lambda", []Field{{"Parameters", "[]token.Token"}, {"Body", "[]Stmt[T]"}}},
+			{"Var", []Field{{"Name", "token.Token"}, {"Binding", "*Binding"}}},
+			{"Assign", []Field{{"Name", "token.Token"}, {"Value", "Expr[T]"}, {"Binding", "*Binding"}}},
+			{"Lambda", []Field{{"Keyword", "token.Token"}, {"Parameters", "[]token.Token"}, {"ParameterTypes", "[]*token.Token"}, {"ReturnType", "*token.Token"}, {"Defaults", "[]Expr[T]"}, {"Ellipsis", "*token.Token"}, {"Body", "[]Stmt[T]"}, {"RightBrace", "token.Token"}, {"Generator", "bool"}}},
 		},
 	}
 
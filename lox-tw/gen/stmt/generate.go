@@ -29,16 +29,20 @@ func main() {
 		BaseType:   "Stmt",
 		ReturnType: "error",
 		Expressions: []Statement{
-			{"Var", []Field{{"Name", "token.Token"}, {"Initializer", "Expr[T]"}}},
+			{"Var", []Field{{"Name", "token.Token"}, {"VariableType", "*token.Token"}, {"Initializer", "Expr[T]"}}},
 			{"Expression", []Field{{"Expression", "Expr[T]"}}},
-			{"If", []Field{{"Condition", "Expr[T]"}, {"ThenBranch", "Stmt[T]"}, {"ElseBranch", "Stmt[T]"}}},
-			{"While", []Field{{"Condition", "Expr[T]"}, {"Body", "Stmt[T]"}}},
-			{"Print", []Field{{"Expression", "Expr[T]"}}},
-			{"Class", []Field{{"Name", "token.Token"}, {"Superclass", "*VarExpr[T]"}, {"Methods", "[]FunctionStmt[T]"}, {"GlobalMethods", "[]FunctionStmt[T]"}}},
-			{"Block", []Field{{"Statements", "[]Stmt[T]"}}},
-			{"Break", []Field{}},
-			{"Function", []Field{{"Name", "token.Token"}, {"Parameters", "[]token.Token"}, {"Body", "[]Stmt[T]"}}},
+			{"If", []Field{{"Keyword", "token.Token"}, {"Condition", "Expr[T]"}, {"ThenBranch", "Stmt[T]"}, {"ElseBranch", "Stmt[T]"}}},
+			{"While", []Field{{"Keyword", "token.Token"}, {"Condition", "Expr[T]"}, {"Body", "Stmt[T]"}}},
+			{"ForIn", []Field{{"Keyword", "token.Token"}, {"Name", "token.Token"}, {"VariableType", "*token.Token"}, {"Iterable", "Expr[T]"}, {"Body", "Stmt[T]"}}},
+			{"Print", []Field{{"Keyword", "token.Token"}, {"Expression", "Expr[T]"}}},
+			{"Class", []Field{{"Name", "token.Token"}, {"Superclass", "*VarExpr[T]"}, {"Traits", "[]VarExpr[T]"}, {"Methods", "[]FunctionStmt[T]"}, {"GlobalMethods", "[]FunctionStmt[T]"}, {"Getters", "[]FunctionStmt[T]"}, {"Setters", "[]FunctionStmt[T]"}, {"RightBrace", "token.Token"}}},
+			{"Trait", []Field{{"Name", "token.Token"}, {"Methods", "[]FunctionStmt[T]"}, {"RightBrace", "token.Token"}}},
+			{"Block", []Field{{"LeftBrace", "token.Token"}, {"Statements", "[]Stmt[T]"}, {"RightBrace", "token.Token"}}},
+			{"Break", []Field{{"Keyword", "token.Token"}}},
+			{"Function", []Field{{"Name", "token.Token"}, {"Parameters", "[]token.Token"}, {"ParameterTypes", "[]*token.Token"}, {"ReturnType", "*token.Token"}, {"Defaults", "[]Expr[T]"}, {"Ellipsis", "*token.Token"}, {"Body", "[]Stmt[T]"}, {"RightBrace", "token.Token"}, {"Generator", "bool"}}},
 			{"Return", []Field{{"Keyword", "token.Token"}, {"Value", "Expr[T]"}}},
+			{"Yield", []Field{{"Keyword", "token.Token"}, {"Value", "Expr[T]"}}},
+			{"Assert", []Field{{"Keyword", "token.Token"}, {"Condition", "Expr[T]"}, {"Message", "Expr[T]"}}},
 		},
 	}
 
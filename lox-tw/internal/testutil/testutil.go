@@ -0,0 +1,78 @@
+// Package testutil runs Lox sources through the scanner, the parser, the
+// resolver and the tree-walker for the tests of other packages, so they all
+// go through the same pipeline as scripts do.
+package testutil
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"lox-tw/ast"
+	"lox-tw/interpreter"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+)
+
+// Parse scans, parses and resolves a program, failing the test on errors.
+func Parse(t testing.TB, source string) []ast.Stmt[any] {
+	t.Helper()
+
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		t.Fatalf("Error scanning tokens: %v", err)
+	}
+	stmts, err := parser.ParseTokensToStmts(tokens)
+	if err != nil {
+		t.Fatalf("Error parsing tokens: %v", err)
+	}
+	if errors := resolver.NewResolver().Resolve(stmts); len(errors) > 0 {
+		t.Fatalf("Error resolving statements: %v", errors)
+	}
+	return stmts
+}
+
+// Run returns what a program prints on the tree-walker, followed by its
+// runtime errors.
+func Run(t testing.TB, source string) string {
+	t.Helper()
+	return RunStmts(Parse(t, source))
+}
+
+// RunStmts is Run for statements already parsed and resolved. A statement
+// failing doesn't stop the next ones, as in scripts.
+func RunStmts(stmts []ast.Stmt[any]) string {
+	var output strings.Builder
+	execute(stmts, &output, nil, func(err error) {
+		output.WriteString(err.Error() + "\n")
+	})
+	return output.String()
+}
+
+// Execute runs statements on the tree-walker under a tracer, which may be
+// nil, discarding what they print and failing the test on runtime errors.
+func Execute(t testing.TB, stmts []ast.Stmt[any], tracer interpreter.Tracer) {
+	t.Helper()
+	execute(stmts, io.Discard, tracer, func(err error) {
+		t.Fatalf("Error running statements: %v", err)
+	})
+}
+
+func execute(stmts []ast.Stmt[any], output io.Writer, tracer interpreter.Tracer, fail func(err error)) {
+	codeInterpreter := interpreter.NewInterpreter()
+	codeInterpreter.SetOutput(output)
+	if tracer != nil {
+		codeInterpreter.SetTracer(tracer)
+	}
+
+	for _, stmt := range stmts {
+		err := codeInterpreter.Execute(stmt)
+		if _, ok := err.(*interpreter.BreakError); ok {
+			continue
+		}
+		if err != nil {
+			fail(err)
+		}
+	}
+}
@@ -1,6 +1,216 @@
 package interpreter
 
+import (
+	"fmt"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+	"lox-tw/utils"
+)
+
+// Callable is a value calls can be made to. Arity returns the least and the
+// most arguments it takes, the parameters with a default value being
+// optional. The most is -1 for callables taking any number of arguments past
+// the least, collected in a list by a rest parameter.
 type Callable interface {
 	Call(interpreter Interpreter, arguments []any) (any, error)
-	Arity() int
+	Arity() (min int, max int)
+}
+
+// missingArgument stands for the argument of a parameter with a default value
+// that the call skipped, as named arguments can skip any of them.
+type missingArgument struct{}
+
+func checkArity(callee Callable, at token.Token, count int) error {
+	min, max := callee.Arity()
+	if count < min || max >= 0 && count > max {
+		return &RuntimeError{Token: at, Message: utils.ArityMessage(min, max, count)}
+	}
+	return nil
+}
+
+// arity returns the least and the most arguments of parameters, the last of
+// which is a rest parameter when there is an ellipsis.
+func arity(defaults []ast.Expr[any], parameters []token.Token, ellipsis *token.Token) (int, int) {
+	max := len(parameters)
+	if ellipsis != nil {
+		max--
+	}
+
+	min := max
+	for min > 0 && min <= len(defaults) && defaults[min-1] != nil {
+		min--
+	}
+
+	if ellipsis != nil {
+		return min, -1
+	}
+	return min, max
+}
+
+// parameters returns the parameters of a callable declared in Lox, the only
+// ones taking named arguments, rest parameters left out.
+func parameters(callee Callable) ([]token.Token, bool) {
+	var declared []token.Token
+	var ellipsis *token.Token
+	switch callee := callee.(type) {
+	case *Function:
+		declared, ellipsis = callee.declaration.Parameters, callee.declaration.Ellipsis
+	case *Lambda:
+		declared, ellipsis = callee.declaration.Parameters, callee.declaration.Ellipsis
+	case *Class:
+		if initializer := callee.FindMethod("init"); initializer != nil {
+			declared, ellipsis = initializer.declaration.Parameters, initializer.declaration.Ellipsis
+		}
+	default:
+		return nil, false
+	}
+
+	if ellipsis != nil {
+		return declared[:len(declared)-1], true
+	}
+	return declared, true
+}
+
+// evaluateArguments evaluates the arguments of a call, spreading the lists
+// prefixed with an ellipsis, and returns their names along with them.
+func (i Interpreter) evaluateArguments(expr ast.CallExpr[any]) ([]any, []*token.Token, error) {
+	values := []any{}
+	names := []*token.Token{}
+	for index, argument := range expr.Arguments {
+		value, err := argument.Accept(i)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if index < len(expr.Spreads) && expr.Spreads[index] != nil {
+			list, ok := value.(*List)
+			if !ok {
+				return nil, nil, &RuntimeError{
+					Token:   *expr.Spreads[index],
+					Message: "Can only spread lists.",
+				}
+			}
+			values = append(values, list.Elements...)
+			names = append(names, make([]*token.Token, len(list.Elements))...)
+			continue
+		}
+
+		values = append(values, value)
+		if index < len(expr.Names) {
+			names = append(names, expr.Names[index])
+		} else {
+			names = append(names, nil)
+		}
+	}
+
+	return values, names, nil
+}
+
+// bindArguments puts the arguments of a call at the position of their
+// parameter, missing ones being left to the default values. Positional
+// arguments past the parameters follow them, for the rest parameter.
+func bindArguments(callee Callable, expr ast.CallExpr[any], values []any, names []*token.Token) ([]any, error) {
+	if !ast.HasNamedArguments(expr) {
+		return values, checkArity(callee, expr.Parenthesis, len(values))
+	}
+
+	parameters, ok := parameters(callee)
+	if !ok {
+		return nil, &RuntimeError{
+			Token:   expr.Parenthesis,
+			Message: "Native functions don't take named arguments.",
+		}
+	}
+
+	min, max := callee.Arity()
+	if max >= 0 && len(values) > max {
+		return nil, &RuntimeError{Token: expr.Parenthesis, Message: utils.ArityMessage(min, max, len(values))}
+	}
+
+	arguments := make([]any, len(parameters))
+	for i := range arguments {
+		arguments[i] = missingArgument{}
+	}
+	for i, value := range values {
+		name := names[i]
+		if name == nil && i >= len(parameters) {
+			arguments = append(arguments, value)
+			continue
+		}
+		if name == nil {
+			arguments[i] = value
+			continue
+		}
+
+		index := parameterIndex(parameters, name.Lexeme)
+		if index == -1 {
+			return nil, &RuntimeError{
+				Token:   *name,
+				Message: fmt.Sprintf("Unexpected argument '%s'.", name.Lexeme),
+			}
+		}
+		if _, ok := arguments[index].(missingArgument); !ok {
+			return nil, &RuntimeError{
+				Token:   *name,
+				Message: fmt.Sprintf("Argument '%s' is passed twice.", name.Lexeme),
+			}
+		}
+		arguments[index] = value
+	}
+
+	for i := range min {
+		if _, ok := arguments[i].(missingArgument); ok {
+			return nil, &RuntimeError{
+				Token:   expr.Parenthesis,
+				Message: fmt.Sprintf("Missing argument '%s'.", parameters[i].Lexeme),
+			}
+		}
+	}
+
+	return arguments, nil
+}
+
+func parameterIndex(parameters []token.Token, name string) int {
+	for i, parameter := range parameters {
+		if parameter.Lexeme == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// defineParameters defines the parameters of a function in the environment
+// of a call, evaluating the default values of the missing arguments there.
+// The rest parameter, after an ellipsis, gets a list of the arguments left.
+func defineParameters(interpreter *Interpreter, parameters []token.Token, defaults []ast.Expr[any], ellipsis *token.Token, arguments []any) error {
+	regular := parameters
+	if ellipsis != nil {
+		regular = parameters[:len(parameters)-1]
+	}
+
+	for i, param := range regular {
+		var value any = missingArgument{}
+		if i < len(arguments) {
+			value = arguments[i]
+		}
+
+		if _, ok := value.(missingArgument); ok {
+			var err error
+			if value, err = defaults[i].Accept(interpreter); err != nil {
+				return err
+			}
+		}
+		interpreter.environment.Define(param.Lexeme, value)
+	}
+
+	if ellipsis != nil {
+		var rest []any
+		if len(arguments) > len(regular) {
+			rest = arguments[len(regular):]
+		}
+		interpreter.environment.Define(parameters[len(parameters)-1].Lexeme, NewList(rest))
+	}
+
+	return nil
 }
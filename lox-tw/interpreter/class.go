@@ -1,41 +1,96 @@
 package interpreter
 
+import (
+	"maps"
+	"os"
+)
+
 type Class struct {
 	Name       string
 	Superclass *Class
-	Methods    map[string]*Function
+
+	// The methods declared by the class and the ones it inherits, and the
+	// same for getters and setters, run when reading and assigning the
+	// property of their name.
+	Methods map[string]*Function
+	Getters map[string]*Function
+	Setters map[string]*Function
 
 	// metaclasses
 	instance *Instance
+
+	// With inner calls, the methods of the superclasses take precedence, and
+	// this maps each of them to the method of a subclass refining it, which
+	// inner() calls. It is nil otherwise.
+	refinements map[*Function]*Function
 }
 
-func NewClass(metaclass *Class, name string, superclass *Class, methods map[string]*Function) *Class {
-	class := &Class{Name: name, Methods: methods, instance: nil, Superclass: superclass}
+// NewClass copies the methods of the superclass down into the ones declared
+// by the class, so finding a method never walks up the superclasses.
+func NewClass(metaclass *Class, name string, superclass *Class, methods, getters, setters map[string]*Function) *Class {
+	class := &Class{Name: name, instance: nil, Superclass: superclass}
+	inherited := superclass
+	if inherited == nil {
+		inherited = &Class{}
+	}
+
+	if os.Getenv("INNER_ENABLED") == "true" {
+		class.refinements = maps.Clone(inherited.refinements)
+		if class.refinements == nil {
+			class.refinements = make(map[*Function]*Function)
+		}
+		class.Methods = class.refine(inherited.Methods, methods)
+		class.Getters = class.refine(inherited.Getters, getters)
+		class.Setters = class.refine(inherited.Setters, setters)
+	} else {
+		class.Methods = inherit(inherited.Methods, methods)
+		class.Getters = inherit(inherited.Getters, getters)
+		class.Setters = inherit(inherited.Setters, setters)
+	}
 	class.instance = NewInstance(metaclass)
 
 	return class
 }
 
-func (c *Class) FindMethod(name string) *Function {
-	if method, ok := c.Methods[name]; ok {
-		return method
+// refine returns the inherited methods, taking precedence over the declared
+// ones, and records each declared method as the refinement of the last one
+// of its name down the superclasses.
+func (c *Class) refine(inherited map[string]*Function, declared map[string]*Function) map[string]*Function {
+	for name, method := range declared {
+		if refined, ok := inherited[name]; ok {
+			for c.refinements[refined] != nil {
+				refined = c.refinements[refined]
+			}
+			c.refinements[refined] = method
+		}
 	}
+	return inherit(declared, inherited)
+}
 
-	if c.Superclass != nil {
-		return c.Superclass.FindMethod(name)
+// inherit returns the inherited methods overridden by the declared ones.
+func inherit(inherited map[string]*Function, declared map[string]*Function) map[string]*Function {
+	methods := make(map[string]*Function, len(inherited)+len(declared))
+	for name, method := range inherited {
+		methods[name] = method
 	}
+	for name, method := range declared {
+		methods[name] = method
+	}
+	return methods
+}
 
-	return nil
+func (c *Class) FindMethod(name string) *Function {
+	return c.Methods[name]
 }
 
 func (c *Class) String() string {
 	return c.Name
 }
 
-func (c *Class) Arity() int {
+func (c *Class) Arity() (int, int) {
 	initializer := c.FindMethod("init")
 	if initializer == nil {
-		return 0
+		return 0, 0
 	}
 	return initializer.Arity()
 }
@@ -44,7 +99,7 @@ func (c *Class) Call(interpreter Interpreter, arguments []any) (any, error) {
 	instance := NewInstance(c)
 	initializer := c.FindMethod("init")
 	if initializer != nil {
-		_, err := initializer.Bind(instance).Call(interpreter, arguments)
+		_, err := initializer.callMethod(interpreter, instance, arguments)
 		if err != nil {
 			return nil, err
 		}
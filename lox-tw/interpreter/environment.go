@@ -1,11 +1,23 @@
 package interpreter
 
-import "lox-tw/token"
+import (
+	"sort"
 
+	"lox-tw/token"
+)
+
+// Environment holds the variables of a scope. Globals are looked up by
+// name, locals by the slot the resolver gave them, see ast.Binding.
 type Environment struct {
 	global    *Environment
 	enclosing *Environment
+
+	// Only the global environment has variables by name.
 	variables map[string]any
+
+	// The locals of the scope in slot order, and their names for tooling.
+	values []any
+	names  []string
 }
 
 func NewRootEnvironment() *Environment {
@@ -16,6 +28,10 @@ func NewRootEnvironment() *Environment {
 	}
 	environment.global = environment
 	environment.Define("clock", Clock{})
+	environment.Define("str", Str{})
+	environment.Define("list", ListNative{})
+	environment.Define("map", MapNative{})
+	environment.Define("range", RangeNative{})
 
 	return environment
 }
@@ -24,16 +40,24 @@ func NewChildEnvironment(parent *Environment) *Environment {
 	return &Environment{
 		global:    parent.global,
 		enclosing: parent,
-		variables: make(map[string]any),
 	}
 }
 
+// Define adds a variable to the environment. Locals must be defined in the
+// order the resolver numbered their slots.
 func (env *Environment) Define(name string, value any) {
-	env.variables[name] = value
+	if env.variables != nil {
+		env.variables[name] = value
+		return
+	}
+
+	env.values = append(env.values, value)
+	env.names = append(env.names, name)
 }
 
+// Get looks a variable up by name, from this environment outwards.
 func (env *Environment) Get(name token.Token) (any, error) {
-	if value, exists := env.variables[name.Lexeme]; exists {
+	if value, exists := env.lookup(name.Lexeme); exists {
 		return value, nil
 	}
 
@@ -47,12 +71,30 @@ func (env *Environment) Get(name token.Token) (any, error) {
 	return env.enclosing.Get(name)
 }
 
-func (env *Environment) GetAt(distance int, name token.Token) (any, error) {
-	return env.GetAtByLexeme(distance, name.Lexeme)
+func (env *Environment) lookup(name string) (any, bool) {
+	if env.variables != nil {
+		value, exists := env.variables[name]
+		return value, exists
+	}
+
+	// The last definition of a name wins, as it would in a map.
+	for i := len(env.names) - 1; i >= 0; i-- {
+		if env.names[i] == name {
+			return env.values[i], true
+		}
+	}
+	return nil, false
+}
+
+func (env *Environment) GetAt(distance int, index int) any {
+	return env.ancestor(distance).values[index]
 }
 
+// GetAtByLexeme looks a variable up by name in an ancestor, for tools
+// showing variables.
 func (env *Environment) GetAtByLexeme(distance int, name string) (any, error) {
-	return env.ancestor(distance).variables[name], nil
+	value, _ := env.ancestor(distance).lookup(name)
+	return value, nil
 }
 
 func (env *Environment) ancestor(distance int) *Environment {
@@ -69,9 +111,18 @@ func (env *Environment) GetGlobal(name token.Token) (any, error) {
 }
 
 func (env *Environment) Assign(name token.Token, value any) error {
-	if _, exists := env.variables[name.Lexeme]; exists {
-		env.variables[name.Lexeme] = value
-		return nil
+	if env.variables != nil {
+		if _, exists := env.variables[name.Lexeme]; exists {
+			env.variables[name.Lexeme] = value
+			return nil
+		}
+	} else {
+		for i := len(env.names) - 1; i >= 0; i-- {
+			if env.names[i] == name.Lexeme {
+				env.values[i] = value
+				return nil
+			}
+		}
 	}
 
 	if env.enclosing == nil {
@@ -84,11 +135,33 @@ func (env *Environment) Assign(name token.Token, value any) error {
 	return env.enclosing.Assign(name, value)
 }
 
-func (env *Environment) AssignAt(distance int, name token.Token, value any) error {
-	env.ancestor(distance).variables[name.Lexeme] = value
-	return nil
+func (env *Environment) AssignAt(distance int, index int, value any) {
+	env.ancestor(distance).values[index] = value
 }
 
 func (env *Environment) AssignGlobal(name token.Token, value any) error {
 	return env.global.Assign(name, value)
 }
+
+func (env *Environment) Enclosing() *Environment {
+	return env.enclosing
+}
+
+func (env *Environment) IsGlobal() bool {
+	return env.enclosing == nil
+}
+
+// Names returns the variables defined in this environment: sorted for
+// globals, in slot order for locals.
+func (env *Environment) Names() []string {
+	if env.variables == nil {
+		return append([]string{}, env.names...)
+	}
+
+	names := make([]string, 0, len(env.variables))
+	for name := range env.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -23,6 +23,10 @@ func (e *BreakError) Error() string {
 
 type ReturnError struct {
 	Value any
+
+	// Set instead of Value when returning a call in tail position, for the
+	// caller to make it.
+	tailCall *pendingCall
 }
 
 func (e *ReturnError) Error() string {
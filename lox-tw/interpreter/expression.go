@@ -15,10 +15,8 @@ func (i Interpreter) VisitAssignExpr(expr ast.AssignExpr[any]) (any, error) {
 		return nil, err
 	}
 
-	if depth, ok := i.exprToDepth[expr]; ok {
-		if err = i.environment.AssignAt(depth, expr.Name, value); err != nil {
-			return nil, err
-		}
+	if local := expr.Binding; local.Local {
+		i.environment.AssignAt(local.Depth, local.Index, value)
 		return value, nil
 	}
 
@@ -40,9 +38,11 @@ func (i Interpreter) VisitTernaryExpr(expr ast.TernaryExpr[any]) (any, error) {
 	}
 
 	if value, ok := conditionValue.(bool); ok && !value {
+		i.branch(expr.Question, 1)
 		return expr.FalseExpr.Accept(i)
 	}
 
+	i.branch(expr.Question, 0)
 	return expr.TrueExpr.Accept(i)
 }
 
@@ -57,6 +57,25 @@ func (i Interpreter) VisitBinaryExpr(expr ast.BinaryExpr[any]) (any, error) {
 		return rightValue, err
 	}
 
+	_, leftInstance := leftValue.(*Instance)
+	_, rightInstance := rightValue.(*Instance)
+	if name, ok := operatorMethods[expr.Operator.Type]; ok && (leftInstance || rightInstance) {
+		result, called, err := i.callOperator(expr.Operator, name, leftValue, rightValue)
+		if !called && err == nil && name == "__eq__" {
+			// Equality is symmetric, the right operand may overload it too.
+			result, called, err = i.callOperator(expr.Operator, name, rightValue, leftValue)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if called && expr.Operator.Type == token.BANG_EQUAL {
+			return !utils.IsTruthy(result), nil
+		}
+		if called {
+			return result, nil
+		}
+	}
+
 	switch expr.Operator.Type {
 	case token.COMMA:
 		return rightValue, nil
@@ -71,6 +90,19 @@ func (i Interpreter) VisitBinaryExpr(expr ast.BinaryExpr[any]) (any, error) {
 			return left + right, nil
 		}
 
+		// Strings are concatenated with instances converted by toString.
+		if ok && toString(rightValue) != nil || ok2 && toString(leftValue) != nil {
+			left, err := i.stringify(leftValue)
+			if err != nil {
+				return nil, err
+			}
+			right, err := i.stringify(rightValue)
+			if err != nil {
+				return nil, err
+			}
+			return left + right, nil
+		}
+
 		return nil, &RuntimeError{
 			Token:   expr.Operator,
 			Message: "Operands must be two numbers or two strings.",
@@ -86,6 +118,41 @@ func (i Interpreter) VisitBinaryExpr(expr ast.BinaryExpr[any]) (any, error) {
 	return nil, nil
 }
 
+// operatorMethods are the methods of instances overloading the binary
+// operators. '!=' is the negation of '=='.
+var operatorMethods = map[token.TokenType]string{
+	token.PLUS:          "__add__",
+	token.MINUS:         "__sub__",
+	token.STAR:          "__mul__",
+	token.SLASH:         "__div__",
+	token.GREATER:       "__gt__",
+	token.GREATER_EQUAL: "__ge__",
+	token.LESS:          "__lt__",
+	token.LESS_EQUAL:    "__le__",
+	token.EQUAL_EQUAL:   "__eq__",
+	token.BANG_EQUAL:    "__eq__",
+}
+
+// callOperator calls the method overloading an operator when the operand is
+// an instance of a class declaring it, and reports whether it did.
+func (i Interpreter) callOperator(operator token.Token, name string, operand any, arguments ...any) (any, bool, error) {
+	instance, ok := operand.(*Instance)
+	if !ok {
+		return nil, false, nil
+	}
+	method := instance.class.FindMethod(name)
+	if method == nil {
+		return nil, false, nil
+	}
+
+	if err := checkArity(method, operator, len(arguments)); err != nil {
+		return nil, true, err
+	}
+
+	result, err := method.callMethod(i, instance, arguments)
+	return result, true, err
+}
+
 func computeOpFloats(leftValue, rightValue any, operator token.Token) (any, error) {
 	left, ok := leftValue.(float64)
 	if !ok {
@@ -135,6 +202,10 @@ func (i Interpreter) VisitUnaryExpr(expr ast.UnaryExpr[any]) (any, error) {
 
 	switch expr.Operator.Type {
 	case token.MINUS:
+		if result, called, err := i.callOperator(expr.Operator, "__neg__", rightValue); called || err != nil {
+			return result, err
+		}
+
 		parsedValue, ok := rightValue.(float64)
 		if !ok {
 			return rightValue, &RuntimeError{
@@ -158,13 +229,16 @@ func (i Interpreter) VisitLogicalExpr(expr ast.LogicalExpr[any]) (any, error) {
 
 	leftBool := utils.IsTruthy(leftValue)
 	if expr.Operator.Type == token.OR && leftBool {
+		i.branch(expr.Operator, 0)
 		return leftValue, nil
 	}
 
 	if expr.Operator.Type == token.AND && !leftBool {
+		i.branch(expr.Operator, 0)
 		return leftValue, nil
 	}
 
+	i.branch(expr.Operator, 1)
 	return expr.Right.Accept(i)
 }
 
@@ -177,35 +251,68 @@ func (i Interpreter) VisitNothingExpr(expr ast.NothingExpr[any]) (any, error) {
 }
 
 func (i Interpreter) VisitCallExpr(expr ast.CallExpr[any]) (any, error) {
-	callee, err := expr.Callee.Accept(i)
+	call, err := i.evaluateCall(expr)
 	if err != nil {
-		return callee, err
+		return nil, err
+	}
+
+	return i.callFunction(call)
+}
+
+// evaluateCall evaluates the callee and the arguments of a call, checking
+// they can be called together.
+func (i Interpreter) evaluateCall(expr ast.CallExpr[any]) (pendingCall, error) {
+	var callee any
+	var receiver *Instance
+	var err error
+	if get, ok := expr.Callee.(ast.GetExpr[any]); ok {
+		callee, receiver, err = i.getMethod(get)
+	} else {
+		callee, err = expr.Callee.Accept(i)
+	}
+	if err != nil {
+		return pendingCall{}, err
 	}
 	function, ok := callee.(Callable)
 	if !ok {
-		return nil, &RuntimeError{
+		return pendingCall{}, &RuntimeError{
 			Token:   expr.Parenthesis,
 			Message: "Can only call functions and classes.",
 		}
 	}
 
-	arguments := []any{}
-	for _, arg := range expr.Arguments {
-		argValue, err := arg.Accept(i)
-		if err != nil {
-			return argValue, err
-		}
-		arguments = append(arguments, argValue)
+	arguments, names, err := i.evaluateArguments(expr)
+	if err != nil {
+		return pendingCall{}, err
 	}
 
-	if len(arguments) != function.Arity() {
-		return nil, &RuntimeError{
-			Token:   expr.Parenthesis,
-			Message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)),
+	arguments, err = bindArguments(function, expr, arguments, names)
+	if err != nil {
+		return pendingCall{}, err
+	}
+
+	return pendingCall{function: function, receiver: receiver, arguments: arguments, parenthesis: expr.Parenthesis}, nil
+}
+
+// getMethod evaluates the callee of a call such as obj.m(). When m is a
+// method of an instance, the method is returned unbound along with the
+// instance, for the call not to allocate a bound method.
+func (i Interpreter) getMethod(expr ast.GetExpr[any]) (any, *Instance, error) {
+	object, err := expr.Object.Accept(i)
+	if err != nil {
+		return object, nil, err
+	}
+
+	if instance, ok := object.(*Instance); ok {
+		if _, ok := instance.field(expr.Name.Lexeme); !ok && instance.getter(expr.Name.Lexeme) == nil {
+			if method := instance.findMethod(expr.Name.Lexeme, expr.Cache); method != nil {
+				return method, instance, nil
+			}
 		}
 	}
 
-	return function.Call(i, arguments)
+	value, err := i.getProperty(object, expr)
+	return value, nil, err
 }
 
 func (i Interpreter) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
@@ -213,8 +320,8 @@ func (i Interpreter) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
 }
 
 func (i Interpreter) VisitVarExpr(expr ast.VarExpr[any]) (any, error) {
-	if depth, ok := i.exprToDepth[expr]; ok {
-		return i.environment.GetAt(depth, expr.Name)
+	if local := expr.Binding; local.Local {
+		return i.environment.GetAt(local.Depth, local.Index), nil
 	}
 
 	return i.environment.GetGlobal(expr.Name)
@@ -226,13 +333,23 @@ func (i Interpreter) VisitGetExpr(expr ast.GetExpr[any]) (any, error) {
 		return nil, err
 	}
 
+	return i.getProperty(object, expr)
+}
+
+func (i Interpreter) getProperty(object any, expr ast.GetExpr[any]) (any, error) {
 	if instance, ok := object.(*Instance); ok {
-		return instance.Get(expr.Name)
+		return i.readProperty(instance, expr)
+	}
+	if object, ok := object.(Object); ok {
+		return objectProperty(object, expr.Name)
+	}
+	if generator, ok := object.(*Generator); ok {
+		return generatorProperty(generator, expr.Name)
 	}
 
 	if os.Getenv("METACLASSES_ENABLED") == "true" {
 		if classInstance, ok := object.(*Class); ok && classInstance.instance != nil {
-			return classInstance.instance.Get(expr.Name)
+			return i.readProperty(classInstance.instance, expr)
 		}
 	}
 
@@ -242,6 +359,16 @@ func (i Interpreter) VisitGetExpr(expr ast.GetExpr[any]) (any, error) {
 	}
 }
 
+// readProperty reads a field of an instance, runs the getter of the property,
+// or returns the method of the property bound to the instance.
+func (i Interpreter) readProperty(instance *Instance, expr ast.GetExpr[any]) (any, error) {
+	if getter := instance.getter(expr.Name.Lexeme); getter != nil {
+		return getter.callMethod(i, instance, nil)
+	}
+
+	return instance.get(expr.Name, expr.Cache)
+}
+
 func (i Interpreter) VisitSetExpr(expr ast.SetExpr[any]) (any, error) {
 	object, err := expr.Object.Accept(i)
 	if err != nil {
@@ -261,33 +388,70 @@ func (i Interpreter) VisitSetExpr(expr ast.SetExpr[any]) (any, error) {
 		return nil, err
 	}
 
+	if setter := instance.class.Setters[expr.Name.Lexeme]; setter != nil {
+		if _, err := setter.callMethod(i, instance, []any{value}); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
 	instance.Set(expr.Name, value)
 
 	return value, nil
 }
 
 func (i Interpreter) VisitThisExpr(expr ast.ThisExpr[any]) (any, error) {
-	if depth, ok := i.exprToDepth[expr]; ok {
-		return i.environment.GetAt(depth, expr.Keyword)
+	if local := expr.Binding; local.Local {
+		return i.environment.GetAt(local.Depth, local.Index), nil
 	}
 
 	return i.environment.GetGlobal(expr.Keyword)
 }
 
-func (i Interpreter) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
-	depth, ok := i.exprToDepth[expr]
-	if !ok {
+func (i Interpreter) VisitInnerExpr(expr ast.InnerExpr[any]) (any, error) {
+	local := expr.Binding
+	if !local.Local {
 		return nil, &RuntimeError{
 			Token:   expr.Keyword,
-			Message: "Undefined 'super' reference.",
+			Message: "Undefined 'inner' reference.",
 		}
 	}
 
-	superclassValue, err := i.environment.GetAt(depth, expr.Keyword)
-	if err != nil {
+	// 'inner' is defined right after 'this', in the scope binding a method.
+	refinement, _ := i.environment.GetAt(local.Depth, local.Index).(*Function)
+	object, _ := i.environment.GetAt(local.Depth, 0).(*Instance)
+
+	var arguments []any
+	for _, argument := range expr.Arguments {
+		value, err := argument.Accept(i)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, value)
+	}
+
+	// Without a refinement in a subclass, the call does nothing.
+	if refinement == nil {
+		return nil, nil
+	}
+
+	if err := checkArity(refinement, expr.Parenthesis, len(arguments)); err != nil {
 		return nil, err
 	}
 
+	return refinement.callMethod(i, object, arguments)
+}
+
+func (i Interpreter) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
+	local := expr.Binding
+	if !local.Local {
+		return nil, &RuntimeError{
+			Token:   expr.Keyword,
+			Message: "Undefined 'super' reference.",
+		}
+	}
+
+	superclassValue := i.environment.GetAt(local.Depth, local.Index)
 	superclass, ok := superclassValue.(*Class)
 	if !ok {
 		return nil, &RuntimeError{
@@ -296,11 +460,8 @@ func (i Interpreter) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
 		}
 	}
 
-	objectValue, err := i.environment.GetAtByLexeme(depth-1, "this")
-	if err != nil {
-		return nil, err
-	}
-
+	// 'this' is the only variable of the scope inside the one of 'super'.
+	objectValue := i.environment.GetAt(local.Depth-1, 0)
 	object, ok := objectValue.(*Instance)
 	if !ok {
 		return nil, &RuntimeError{
@@ -0,0 +1,211 @@
+package interpreter
+
+import (
+	"errors"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+// errGeneratorRunning is returned when the body of a generator asks for its
+// own next value, which it would wait for forever.
+var errGeneratorRunning = errors.New("Generator is already running.")
+
+// errGeneratorClosed is returned by the yield a closed generator is parked
+// at, unwinding its body.
+var errGeneratorClosed = errors.New("Generator is closed.")
+
+// Generator is what calling a function containing a yield statement returns:
+// the body of the call, run up to its next yield each time a value is asked
+// for. The body runs in a goroutine of its own, which hands control back and
+// forth with the one asking, so only one of them runs at a time. A for-in
+// loop leaving a generator early closes it, ending its goroutine; one left
+// unfinished otherwise keeps its goroutine parked until the program exits.
+type Generator struct {
+	name        string
+	function    string
+	line        uint
+	body        []ast.Stmt[any]
+	interpreter *Interpreter
+
+	started  bool
+	running  bool
+	finished bool
+	closing  bool
+	resume   chan struct{}
+	steps    chan generatorStep
+
+	// The value the body yielded when done was read, until next returns it.
+	ahead *generatorStep
+}
+
+// generatorStep is what the body hands back when it yields a value, ends or
+// fails.
+type generatorStep struct {
+	value any
+	done  bool
+	err   error
+}
+
+// newGenerator returns the generator of a call, its parameters defined in the
+// environment of the interpreter. The function and line are told to the
+// tracer, the name is shown when printing the generator.
+func newGenerator(name string, function string, line uint, body []ast.Stmt[any], interpreter *Interpreter) *Generator {
+	generator := &Generator{
+		name:        name,
+		function:    function,
+		line:        line,
+		body:        body,
+		interpreter: interpreter,
+		resume:      make(chan struct{}),
+		steps:       make(chan generatorStep),
+	}
+	interpreter.generator = generator
+	return generator
+}
+
+func (g *Generator) String() string {
+	return "<generator " + g.name + ">"
+}
+
+// Next returns the next value the body yields, nil once it has finished.
+func (g *Generator) Next() (any, error) {
+	if g.ahead != nil {
+		step := g.ahead
+		g.ahead = nil
+		return step.value, nil
+	}
+
+	step := g.advance()
+	return step.value, step.err
+}
+
+// Done reports whether the body has finished. Unless it knows already, the
+// generator runs the body up to its next yield to find out, keeping the value
+// for Next.
+func (g *Generator) Done() (bool, error) {
+	if g.ahead != nil {
+		return false, nil
+	}
+
+	step := g.advance()
+	if step.done || step.err != nil {
+		return true, step.err
+	}
+	g.ahead = &step
+	return false, nil
+}
+
+// advance runs the body up to its next yield or its end.
+func (g *Generator) advance() generatorStep {
+	if g.finished {
+		return generatorStep{done: true}
+	}
+	if g.running {
+		return generatorStep{err: errGeneratorRunning}
+	}
+
+	g.running = true
+	if g.started {
+		g.resume <- struct{}{}
+	} else {
+		g.started = true
+		go g.run()
+	}
+
+	step := <-g.steps
+	g.running = false
+	g.finished = step.done
+	return step
+}
+
+// run executes the body in the goroutine of the generator. A return ends the
+// generator, its value is dropped, but a call it ends with is still made.
+func (g *Generator) run() {
+	err := executeBody(g.function, g.line, g.body, g.interpreter)
+	if err == errGeneratorClosed {
+		err = nil
+	}
+	if returned, ok := err.(*ReturnError); ok {
+		err = nil
+		if returned.tailCall != nil {
+			_, err = g.interpreter.callFunction(*returned.tailCall)
+		}
+	}
+
+	g.steps <- generatorStep{done: true, err: err}
+}
+
+// Close ends a generator parked at a yield, unwinding its body from there,
+// and waits for its goroutine to finish. A generator that never started is
+// only marked finished, one running is left alone.
+func (g *Generator) Close() {
+	if g.finished || g.running {
+		return
+	}
+	g.finished = true
+	g.ahead = nil
+	if !g.started {
+		return
+	}
+
+	g.closing = true
+	g.resume <- struct{}{}
+	<-g.steps
+}
+
+// yield hands a value to the caller of the generator and waits to be resumed,
+// returning errGeneratorClosed if it was closed instead. The tracer sees the
+// body leave meanwhile, and enter again when it runs on.
+func (g *Generator) yield(value any) error {
+	tracer := g.interpreter.tracer
+	if tracer != nil {
+		tracer.ExitCall()
+	}
+	g.steps <- generatorStep{value: value}
+	<-g.resume
+	if tracer != nil {
+		tracer.EnterCall(g.function, g.line)
+	}
+	if g.closing {
+		return errGeneratorClosed
+	}
+	return nil
+}
+
+// generatorNext is the next method of a generator, bound to it when its
+// property is read.
+type generatorNext struct {
+	generator *Generator
+}
+
+func (n generatorNext) Arity() (int, int) {
+	return 0, 0
+}
+
+func (n generatorNext) Call(interpreter Interpreter, arguments []any) (any, error) {
+	return n.generator.Next()
+}
+
+func (n generatorNext) String() string {
+	return "<native fn>"
+}
+
+// generatorProperty reads whether a generator is done or its next method.
+func generatorProperty(generator *Generator, name token.Token) (any, error) {
+	switch name.Lexeme {
+	case "next":
+		return generatorNext{generator: generator}, nil
+	case "done":
+		done, err := generator.Done()
+		if err == errGeneratorRunning {
+			err = &RuntimeError{Token: name, Message: err.Error()}
+		}
+		return done, err
+	}
+
+	return nil, &RuntimeError{
+		Token:   name,
+		Message: "Undefined property '" + name.Lexeme + "'.",
+	}
+}
@@ -1,6 +1,7 @@
 package interpreter
 
 import (
+	"lox-tw/ast"
 	"lox-tw/token"
 )
 
@@ -18,14 +19,16 @@ func (i *Instance) String() string {
 }
 
 func (i *Instance) Get(name token.Token) (any, error) {
-	value, ok := i.fields[name.Lexeme]
-	if ok && value != nil {
+	return i.get(name, nil)
+}
+
+func (i *Instance) get(name token.Token, cache *ast.PropertyCache) (any, error) {
+	if value, ok := i.field(name.Lexeme); ok {
 		return value, nil
 	}
 
-	if method := i.class.FindMethod(name.Lexeme); method != nil {
-		method = method.Bind(i)
-		return method, nil
+	if method := i.findMethod(name.Lexeme, cache); method != nil {
+		return method.Bind(i), nil
 	}
 
 	return nil, &RuntimeError{
@@ -33,6 +36,39 @@ func (i *Instance) Get(name token.Token) (any, error) {
 		Message: "Undefined property '" + name.Lexeme + "'."}
 }
 
+// field returns the value of a field. Fields set to nil hide nothing.
+func (i *Instance) field(name string) (any, bool) {
+	value, ok := i.fields[name]
+	return value, ok && value != nil
+}
+
+// getter returns the getter of a property, unless a field hides it.
+func (i *Instance) getter(name string) *Function {
+	if _, ok := i.field(name); ok {
+		return nil
+	}
+	return i.class.Getters[name]
+}
+
+// findMethod finds a method of the class of the instance, through the inline
+// cache of the expression naming it when there is one.
+func (i *Instance) findMethod(name string, cache *ast.PropertyCache) *Function {
+	if cache == nil {
+		return i.class.FindMethod(name)
+	}
+
+	if cache.Class == i.class {
+		return cache.Method.(*Function)
+	}
+
+	method := i.class.FindMethod(name)
+	if method != nil {
+		cache.Class = i.class
+		cache.Method = method
+	}
+	return method
+}
+
 func (i *Instance) Set(name token.Token, value any) {
 	i.fields[name.Lexeme] = value
 }
@@ -1,22 +1,83 @@
 package interpreter
 
-import "lox-tw/ast"
+import (
+	"io"
+	"os"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
 
 type Interpreter struct {
 	environment *Environment
-	exprToDepth map[ast.Expr[any]]int
+
+	stdout io.Writer
+	tracer Tracer
+
+	// The instances whose toString method is running.
+	converting map[*Instance]bool
+
+	// The generator whose body is running, which yield statements hand their
+	// values to.
+	generator *Generator
 }
 
-func NewInterpreter(exprToDepth map[ast.Expr[any]]int) *Interpreter {
+func NewInterpreter() *Interpreter {
 	return &Interpreter{
 		environment: NewRootEnvironment(),
-		exprToDepth: exprToDepth,
+		stdout:      os.Stdout,
+		converting:  make(map[*Instance]bool),
 	}
 }
 
-func NewInterpreterWithEnv(env *Environment, exprToDepth map[ast.Expr[any]]int) *Interpreter {
+func NewInterpreterWithEnv(env *Environment) *Interpreter {
 	return &Interpreter{
 		environment: env,
-		exprToDepth: exprToDepth,
+		stdout:      os.Stdout,
+		converting:  make(map[*Instance]bool),
+	}
+}
+
+// SetOutput changes where print statements write to.
+func (i *Interpreter) SetOutput(stdout io.Writer) {
+	i.stdout = stdout
+}
+
+func (i *Interpreter) SetTracer(tracer Tracer) {
+	i.tracer = tracer
+}
+
+func (i Interpreter) Environment() *Environment {
+	return i.environment
+}
+
+// Execute runs a statement, letting the tracer know about it first.
+func (i Interpreter) Execute(stmt ast.Stmt[any]) error {
+	if i.tracer != nil {
+		if err := i.tracer.BeforeStmt(i, stmt); err != nil {
+			return err
+		}
+	}
+
+	return stmt.Accept(i)
+}
+
+func (i Interpreter) branch(site token.Token, branch int) {
+	if i.tracer != nil {
+		i.tracer.Branch(site, branch)
+	}
+}
+
+// withEnvironment returns a copy of the interpreter running in env, used to
+// execute function bodies.
+func (i Interpreter) withEnvironment(env *Environment) *Interpreter {
+	i.environment = env
+	return &i
+}
+
+func (i Interpreter) output() io.Writer {
+	if i.stdout == nil {
+		return os.Stdout
 	}
+	return i.stdout
 }
@@ -0,0 +1,94 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"lox-tw/internal/testutil"
+)
+
+// The function of fib.lox, at the root of the repository, on a smaller input.
+const FIB = `fun fib(n) {
+    if (n < 2) return n;
+    return fib(n - 1) + fib(n - 2);
+}
+
+print fib(20);
+`
+
+// Reads variables of enclosing scopes, without arithmetic or calls.
+const LOCALS = `{
+    var a = true;
+    var b = nil;
+    var i = 0;
+    var result;
+    while (i < 5000) {
+        var c = a;
+        {
+            var d = b;
+            result = c and d or a;
+            i = (i, b, a, c, d, result, i + 1);
+        }
+    }
+    print result;
+}
+`
+
+// Calls methods of an instance, some of them declared by its superclasses.
+const METHODS = `class Shape {
+    init(size) { this.size = size; }
+    size() { return this.size; }
+    scale(n) { this.size = this.size * n; return this; }
+}
+
+class Square < Shape {
+    area() { return this.size * this.size; }
+}
+
+class Tile < Square {
+    cost(price) { return this.area() * price; }
+}
+
+var tile = Tile(1);
+var total = 0;
+for (var i = 0; i < 2000; i = i + 1) {
+    total = total + tile.cost(2) + tile.scale(1).area();
+}
+print total;
+`
+
+// Reads methods as values and calls them later, which binds them.
+const BOUND_METHODS = `class Counter {
+    init() { this.count = 0; }
+    increment() { this.count = this.count + 1; }
+}
+
+var counter = Counter();
+for (var i = 0; i < 2000; i = i + 1) {
+    var increment = counter.increment;
+    increment();
+}
+print counter.count;
+`
+
+func benchmark(b *testing.B, source string) {
+	stmts := testutil.Parse(b, source)
+	for b.Loop() {
+		testutil.Execute(b, stmts, nil)
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmark(b, FIB)
+}
+
+func BenchmarkLocals(b *testing.B) {
+	benchmark(b, LOCALS)
+}
+
+func BenchmarkMethods(b *testing.B) {
+	benchmark(b, METHODS)
+}
+
+func BenchmarkBoundMethods(b *testing.B) {
+	benchmark(b, BOUND_METHODS)
+}
@@ -1,9 +1,14 @@
-package interpreter
+package interpreter_test
 
 import (
+	"runtime"
 	"testing"
+	"time"
 
+	"lox-tw/internal/testutil"
+	"lox-tw/interpreter"
 	"lox-tw/parser"
+	"lox-tw/resolver"
 	"lox-tw/scanner"
 )
 
@@ -87,7 +92,7 @@ func TestExprEvaluation(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tokens, _ := scanner.ScanTokens(tt.expr)
 			expr, _ := parser.ParseTokensToExpression(tokens)
-			result, err := expr.Accept(Interpreter{})
+			result, err := expr.Accept(interpreter.Interpreter{})
 			if err != nil {
 				t.Errorf("Error evaluating expression: %v", err)
 			}
@@ -97,3 +102,774 @@ func TestExprEvaluation(t *testing.T) {
 		})
 	}
 }
+
+// run returns what a program prints, followed by its runtime errors.
+func run(t *testing.T, source string) string {
+	t.Helper()
+	return testutil.Run(t, source)
+}
+
+func TestIdenticalExpressionsOnOneLine(t *testing.T) {
+	// Both 'a' and both 'a = a + "!"' have the same text and line, but refer
+	// to different variables.
+	source := `var a = "global"; { fun f() { a = a + "!"; print a; var a = "local"; a = a + "!"; print a; } f(); print a; }`
+
+	expected := "global!\nlocal!\nglobal!\n"
+	if output := run(t, source); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestPropertyCaches(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Call site seeing several classes", `
+class A { name() { return "A"; } }
+class B < A {}
+class C < A { name() { return "C"; } }
+fun objects(i) { return i == 0 ? A() : i == 1 ? B() : C(); }
+for (var i = 0; i < 3; i = i + 1) print objects(i).name();`, "A\nA\nC\n"},
+		{"Fields hide cached methods", `
+class A { m() { return "method"; } }
+var a = A();
+fun call() { return a.m(); }
+print call();
+fun field() { return "field"; }
+a.m = field;
+print call();
+a.m = nil;
+print call();`, "method\nfield\nmethod\n"},
+		{"Cached methods are bound", `
+class A { init(n) { this.n = n; } get() { return this.n; } }
+fun objects(i) { return A(i); }
+for (var i = 0; i < 2; i = i + 1) { var get = objects(i).get; print get(); }`, "0\n1\n"},
+		{"Missing methods", `
+class A { m() {} }
+fun call(a) { return a.missing(); }
+call(A());
+call(A());`, "Undefined property 'missing'.\n[line 3]\nUndefined property 'missing'.\n[line 3]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestGettersAndSetters(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Getters run when read", `
+class Circle {
+    init(radius) { this.radius = radius; }
+    area { return 3 * this.radius * this.radius; }
+}
+var c = Circle(2);
+print c.area;
+c.radius = 3;
+print c.area;`, "12\n27\n"},
+		{"Setters run when assigned", `
+class Temperature {
+    celsius=(value) { this.kelvin = value + 273; }
+}
+var t = Temperature();
+print t.celsius = 10;
+print t.kelvin;`, "10\n283\n"},
+		{"Setters return the value assigned", `
+class A { x=(value) { value = value * 2; this.y = value; } }
+var a = A();
+print a.x = 1;
+print a.y;`, "1\n2\n"},
+		{"Inherited", `
+class A { name { return "A"; } name=(value) { this.set = value; } }
+class B < A {}
+var b = B();
+b.name = "b";
+print b.name + b.set;`, "Ab\n"},
+		{"Hidden by fields", `
+class A { name { return "getter"; } }
+var a = A();
+a.name = "field";
+print a.name;
+a.name = nil;
+print a.name;`, "field\ngetter\n"},
+		{"Errors", `
+class A { value { return -"a"; } }
+print A().value;
+print "after";`, "Operand must be a number.\n[line 2]\nafter\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestInner(t *testing.T) {
+	t.Setenv("INNER_ENABLED", "true")
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Superclass methods take precedence", `
+class A { name() { return "A"; } }
+class B < A { name() { return "B"; } }
+print B().name();`, "A\n"},
+		{"Calls down into refinements", `
+class Doughnut { cook() { print "fry"; inner(); print "box"; } }
+class BostonCream < Doughnut { cook() { print "custard"; inner(); } }
+class Sprinkled < BostonCream { cook() { print "sprinkles"; } }
+Sprinkled().cook();`, "fry\ncustard\nsprinkles\nbox\n"},
+		{"Without refinement", `
+class A { m() { return inner(1, 2); } }
+class B < A {}
+print A().m();
+print B().m();`, "nil\nnil\n"},
+		{"Arguments and results", `
+class Template { run(x) { return "<" + inner(x + "1") + ">"; } }
+class Impl < Template { run(x) { return "impl " + x; } }
+print Impl().run("a");`, "<impl a1>\n"},
+		{"Refinements skip classes not declaring the method", `
+class A { m() { print "A"; inner(); } }
+class B < A {}
+class C < B { m() { print "C"; inner(); } }
+C().m();`, "A\nC\n"},
+		{"From closures", `
+class A { m() { fun f() { return inner(); } return f; } }
+class B < A { m() { return "B"; } }
+print B().m()();`, "B\n"},
+		{"Arity", `
+class A { m() { inner(); } }
+class B < A { m(x) {} }
+B().m();`, "Expected 1 arguments but got 0.\n[line 2]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestInnerDisabled(t *testing.T) {
+	source := `
+fun inner() { return "function"; }
+class A { m() { return inner(); } }
+class B < A { m() { return "B"; } }
+print B().m();`
+	if output := run(t, source); output != "B\n" {
+		t.Errorf("Expected %q, got %q", "B\n", output)
+	}
+}
+
+func TestTraits(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Methods are copied", `
+trait Greeter { greet() { return "hello " + this.name; } }
+class Person with Greeter { init(name) { this.name = name; } }
+print Person("bob").greet();`, "hello bob\n"},
+		{"Calling methods of the class", `
+trait Comparable {
+    lessThan(other) { return this.compare(other) < 0; }
+    greaterThan(other) { return this.compare(other) > 0; }
+}
+class Num with Comparable {
+    init(n) { this.n = n; }
+    compare(other) { return this.n - other.n; }
+}
+print Num(1).lessThan(Num(2));
+print Num(1).greaterThan(Num(2));`, "true\nfalse\n"},
+		{"Precedence", `
+class Base { m() { return "base"; } n() { return "base"; } o() { return "base"; } }
+trait T { m() { return "trait"; } n() { return "trait"; } }
+class A < Base with T { m() { return "class"; } }
+var a = A();
+print a.m() + " " + a.n() + " " + a.o();`, "class trait base\n"},
+		{"Overridden conflicts", `
+trait A { name() { return "A"; } }
+trait B { name() { return "B"; } }
+class C with A, B { name() { return "C"; } }
+print C().name();`, "C\n"},
+		{"Only traits", `
+class A {}
+class B with A {}
+print "after";`, "Can only compose traits.\n[line 3]\nafter\n"},
+		{"Contextual keywords", `
+var trait = "trait"; var with = "with";
+print trait + with;`, "traitwith\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestTraitErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"trait A { m() {} } trait B { m() {} } class C with A, B {}", "[line 1] Error at 'B': Traits 'A' and 'B' both provide 'm', the class must override it."},
+		{"trait A { m() { return super.m(); } }", "[line 1] Error at 'super': Can't use 'super' in a trait."},
+	}
+
+	for _, test := range tests {
+		tokens, err := scanner.ScanTokens(test.source)
+		if err != nil {
+			t.Fatalf("Error scanning tokens: %v", err)
+		}
+		stmts, err := parser.ParseTokensToStmts(tokens)
+		if err != nil {
+			t.Fatalf("Error parsing tokens: %v", err)
+		}
+
+		errors := resolver.NewResolver().Resolve(stmts)
+		if len(errors) != 1 || errors[0].Error() != test.expected {
+			t.Errorf("Expected %q, got %v", test.expected, errors)
+		}
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Arithmetic", `
+class Vec {
+    init(x, y) { this.x = x; this.y = y; }
+    __add__(other) { return Vec(this.x + other.x, this.y + other.y); }
+    __sub__(other) { return Vec(this.x - other.x, this.y - other.y); }
+    __mul__(k) { return Vec(this.x * k, this.y * k); }
+    __div__(k) { return Vec(this.x / k, this.y / k); }
+    __neg__() { return Vec(-this.x, -this.y); }
+}
+var v = -(Vec(1, 2) + Vec(3, 4) - Vec(2, 2)) * 3 / 2;
+print v.x; print v.y;`, "-3\n-6\n"},
+		{"Comparison", `
+class Version {
+    init(n) { this.n = n; }
+    __lt__(other) { return this.n < other.n; }
+    __le__(other) { return this.n <= other.n; }
+    __gt__(other) { return this.n > other.n; }
+    __ge__(other) { return this.n >= other.n; }
+}
+var a = Version(1); var b = Version(2);
+print a < b; print a <= b; print a > b; print a >= b;`, "true\ntrue\nfalse\nfalse\n"},
+		{"Equality", `
+class Point {
+    init(x) { this.x = x; }
+    __eq__(other) { return other != nil and this.x == other.x; }
+}
+print Point(1) == Point(1); print Point(1) != Point(1);
+print Point(1) == Point(2); print Point(1) != Point(2);
+print Point(1) == nil; print nil == Point(1);`, "true\nfalse\nfalse\ntrue\nfalse\nfalse\n"},
+		{"Identity without __eq__", `
+class A {}
+var a = A();
+print a == a; print a == A(); print a != A();`, "true\nfalse\ntrue\n"},
+		{"Fallback errors", `
+class A {}
+A() + 1;
+-A();
+A() < 1;`, "Operands must be two numbers or two strings.\n[line 3]\nOperand must be a number.\n[line 4]\nOperands must be numbers.\n[line 5]\n"},
+		{"Arity", `
+class A { __add__() { return 1; } }
+A() + A();`, "Expected 0 arguments but got 1.\n[line 3]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestToString(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Print", `
+class Point {
+    init(x, y) { this.x = x; this.y = y; }
+    toString() { return "(" + str(this.x) + ", " + str(this.y) + ")"; }
+}
+print Point(1, 2);`, "(1, 2)\n"},
+		{"Concatenation", `
+class Name { toString() { return "Ada"; } }
+print "Hello, " + Name() + "!";
+print Name() + "" + Name();`, "Hello, Ada!\nAdaAda\n"},
+		{"str", `print str(1.5) + str(nil) + str(true) + str("s"); class A {} print str(A());`, "1.5niltrues\nA instance\n"},
+		{"Recursion", `
+class Loop { toString() { return "Loop(" + str(this) + ")"; } }
+print Loop();`, "Loop(Loop instance)\n"},
+		{"Nested", `
+class Node {
+    init(value, next) { this.value = value; this.next = next; }
+    toString() { return str(this.value) + " -> " + str(this.next); }
+}
+print Node(1, Node(2, "end"));`, "1 -> 2 -> end\n"},
+		{"Errors", `
+class Broken { toString() { return -"a"; } }
+print Broken();
+class A {}
+print "a" + A();`, "Operand must be a number.\n[line 2]\nOperands must be two numbers or two strings.\n[line 5]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestDefaultsAndNamedArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Defaults", `
+fun greet(name, greeting = "Hello") { print greeting + ", " + name; }
+greet("Ada"); greet("Ada", "Hi");`, "Hello, Ada\nHi, Ada\n"},
+		{"Evaluated per call", `
+var calls = 0;
+fun count() { calls = calls + 1; return calls; }
+fun f(a = count()) { print a; }
+f(); f(); f(10); print calls;`, "1\n2\n10\n2\n"},
+		{"Earlier parameters", `
+fun f(a, b = a * 2, c = a + b) { print c; }
+var a = 100;
+f(1); f(1, 1);`, "3\n2\n"},
+		{"Named arguments", `
+fun f(a, b = "b", c = "c") { print a + b + c; }
+f("a", c: "C"); f(c: "C", a: "A"); f(b: "B", a: "a");`, "abC\nAbC\naBc\n"},
+		{"Methods and initializers", `
+class Point {
+    init(x = 0, y = 0) { this.x = x; this.y = y; }
+    moved(dx = 0, dy = 0) { return Point(this.x + dx, this.y + dy); }
+}
+var p = Point(y: 2).moved(dy: 1);
+print str(p.x) + "," + str(p.y);`, "0,3\n"},
+		{"Lambdas", `var f = fun (a, b = 1) { print a + b; }; f(1); f(b: 2, a: 3);`, "2\n5\n"},
+		{"Errors", `
+fun f(a, b = 1) {}
+f(); f(1, 2, 3); f(1, c: 2); f(1, a: 2); f(b: 2); clock(x: 1);`, `Expected 1 to 2 arguments but got 0.
+[line 3]
+Expected 1 to 2 arguments but got 3.
+[line 3]
+Unexpected argument 'c'.
+[line 3]
+Argument 'a' is passed twice.
+[line 3]
+Missing argument 'a'.
+[line 3]
+Native functions don't take named arguments.
+[line 3]
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestVariadics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Rest parameters", `
+fun log(format, ...args) { print format + str(args); }
+log("none "); log("some ", 1, "two", nil);`, "none []\nsome [1, two, nil]\n"},
+		{"Spread arguments", `
+fun add(a, b, c) { return a + b + c; }
+var xs = list(1, 2);
+print add(...xs, 3); print add(0, ...xs); print str(...list("one"));`, "6\n3\none\n"},
+		{"Defaults and named arguments", `
+fun f(a, b = "b", ...rest) { print a + b + str(rest); }
+f("a"); f("a", "B", 1, 2); f(b: "B", a: "A"); f(...list("x", "y", "z"));`, "ab[]\naB[1, 2]\nAB[]\nxy[z]\n"},
+		{"Initializers and lambdas", `
+class Bag { init(name, ...items) { this.items = items; } }
+print Bag("bag", 1, 2).items;
+var count = fun (...all) { print all.length; };
+count(); count(...Bag("b", 1, 2, 3).items);`, "[1, 2]\n0\n3\n"},
+		{"Lists", `
+var xs = list(1, "two");
+xs.push(list(3)); xs.set(0, xs.get(0) + 1);
+print xs; print xs.length; print list();
+xs.push(xs); print xs;`, "[2, two, [3]]\n3\n[]\n[2, two, [3], [...]]\n"},
+		{"Errors", `
+fun f(a, ...rest) {}
+f(); f(...1); f(rest: 1);
+list(1).get(1); list(1).get(0.5); list().pop();`, `Expected at least 1 arguments but got 0.
+[line 3]
+Can only spread lists.
+[line 3]
+Unexpected argument 'rest'.
+[line 3]
+List index out of range.
+[line 4]
+List index must be an integer.
+[line 4]
+Undefined property 'pop'.
+[line 4]
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+// A break in an inner loop only ends that loop.
+func TestBreak(t *testing.T) {
+	source := `
+fun first(n) {
+    for (var i = 0; i < n; i = i + 1) {
+        for (var j = 0; j < n; j = j + 1) {
+            if (j > i) break;
+            print str(i) + str(j);
+        }
+    }
+    return "done";
+}
+print first(3);`
+	expected := "00\n10\n11\n20\n21\n22\ndone\n"
+	if output := run(t, source); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestLambdaReturns(t *testing.T) {
+	source := `
+var twice = fun (x) { return x * 2; };
+class A { init() { this.f = fun () { return "lambda"; }; } }
+print twice(2); print A().f();`
+	expected := "4\nlambda\n"
+	if output := run(t, source); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Next and done", `
+fun count(n) { for (var i = 0; i < n; i = i + 1) yield i; }
+var g = count(2);
+print g; print g.done; print g.next(); print g.next(); print g.done; print g.next();`, "<generator count>\nfalse\n0\n1\ntrue\nnil\n"},
+		{"Lazy bodies", `
+fun lines() { print "first"; yield 1; print "second"; yield 2; }
+var g = lines();
+print "created"; print g.next(); print "between"; print g.next(); print g.done;`, "created\nfirst\n1\nbetween\nsecond\n2\ntrue\n"},
+		{"Nested loops and break", `
+fun pairs(n) {
+    for (var i = 0; i < n; i = i + 1) {
+        for (var j = 0; j < n; j = j + 1) {
+            if (j > i) break;
+            yield str(i) + str(j);
+        }
+    }
+}
+var g = pairs(3);
+while (!g.done) print g.next();`, "00\n10\n11\n20\n21\n22\n"},
+		{"Return", `
+fun until(limit) {
+    var i = 0;
+    while (true) { if (i == limit) return "dropped"; yield i; i = i + 1; }
+}
+var g = until(2);
+print g.next(); print g.next(); print g.next(); print g.done;
+fun log(message) { print message; }
+fun last() { yield 1; return log("tail"); }
+var h = last(); h.next(); print h.done;`, "0\n1\nnil\ntrue\ntail\ntrue\n"},
+		{"Methods, lambdas and recursion", `
+class Tree {
+    init(value) { this.value = value; this.children = list(); }
+    walk() {
+        yield this.value;
+        for (var i = 0; i < this.children.length; i = i + 1) {
+            var walk = this.children.get(i).walk();
+            while (!walk.done) yield walk.next();
+        }
+    }
+}
+var tree = Tree(1);
+tree.children.push(Tree(2)); tree.children.push(Tree(4));
+tree.children.get(0).children.push(Tree(3));
+var walk = tree.walk();
+while (!walk.done) print walk.next();
+var twice = fun (x) { yield x; yield x; };
+var g = twice("a");
+print g.next() + g.next(); print g;`, "1\n2\n3\n4\naa\n<generator <lambda>>\n"},
+		{"Independent generators", `
+fun naturals() { var n = 0; while (true) { yield n; n = n + 1; } }
+var a = naturals(); var b = naturals();
+a.next(); a.next();
+print a.next(); print b.next();`, "2\n0\n"},
+		{"Running generators", `
+var g;
+fun reentrant() { yield 1; yield g.next(); }
+g = reentrant();
+print g.next(); print g.next(); print g.done;
+fun peeking() { print g.done; yield 1; }
+g = peeking(); g.next();
+fun looping() { for (var x in g) yield x; }
+g = looping(); g.next();`, `1
+Generator is already running.
+[line 3]
+true
+Generator is already running.
+[line 6]
+Generator is already running.
+[line 8]
+`},
+		{"Errors", `
+fun broken() { yield 1; yield nope; }
+var g = broken();
+print g.next(); g.next(); print g.done; print g.next();
+g.value;`, `1
+Undefined variable 'nope'.
+[line 2]
+true
+nil
+Undefined property 'value'.
+[line 5]
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestForIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Lists, strings and ranges", `
+for (var x in list(1, "two", nil)) print x;
+for (var c in "hé!") print c;
+for (var i in range(3)) print i;
+for (var i in range(1, 2)) print i;
+for (var i in range(10, 0, -4)) print i;
+for (var i in range(3, 1)) print "never";
+print range(5); print range(0, 1, 0.5);`, "1\ntwo\nnil\nh\né\n!\n0\n1\n2\n1\n10\n6\n2\nrange(0, 5)\nrange(0, 1, 0.5)\n"},
+		{"Maps", `
+var ages = map();
+ages.set("ann", 31); ages.set("bob", 27); ages.set("ann", 32);
+print ages; print ages.length; print ages.has("bob"); print ages.get("eve");
+for (var name in ages) print name + " " + str(ages.get(name));
+print ages.remove("ann"); print ages; print map();`, "{ann: 32, bob: 27}\n2\ntrue\nnil\nann 32\nbob 27\n32\n{bob: 27}\n{}\n"},
+		{"Changes while iterating", `
+var xs = list(1, 2);
+for (var x in xs) { if (x < 3) xs.push(x + 2); print x; }
+var m = map(); m.set(1, 1);
+for (var k in m) { m.set(k + 1, 1); print k; }
+print m.length;`, "1\n2\n3\n4\n1\n2\n"},
+		{"Generators", `
+fun squares(n) { for (var i in range(n)) yield i * i; }
+for (var x in squares(4)) print x;
+fun nothing() { return; yield 1; }
+for (var x in nothing()) print x;`, "0\n1\n4\n9\n"},
+		{"Iterator classes", `
+class Countdown {
+    init(from) { this.from = from; }
+    iterator() { return CountdownIterator(this.from); }
+}
+class CountdownIterator {
+    init(n) { this.n = n; }
+    done { return this.n == 0; }
+    next() { this.n = this.n - 1; return this.n + 1; }
+}
+for (var n in Countdown(3)) print n;
+class Evens { iterator() { for (var i in range(0, 6, 2)) yield i; } }
+for (var n in Evens()) print n;`, "3\n2\n1\n0\n2\n4\n"},
+		{"Break and nesting", `
+for (var i in range(3)) {
+    for (var j in range(3)) { if (j > i) break; print str(i) + str(j); }
+    if (i == 1) break;
+}
+print "after";`, "00\n10\n11\nafter\n"},
+		{"Closing generators", `
+fun nat() { var n = 0; while (true) { yield n; n = n + 1; } }
+fun pairs() { for (var n in nat()) yield n * 2; }
+var g = nat();
+for (var n in g) if (n == 2) break;
+print g.done; print g.next();
+var p = pairs();
+for (var n in p) if (n == 4) break;
+print p.done;
+fun first() { for (var n in nat()) return n; }
+print first();
+class Naturals { iterator() { return nat(); } }
+for (var n in Naturals()) { print n; break; }`, "true\nnil\ntrue\n0\n0\n"},
+		{"Fresh bindings", `
+var printers = list();
+for (var x in list("a", "b")) printers.push(fun () { print x; });
+printers.get(0)(); printers.get(1)();
+for (var x in range(2)) { x = x + 10; print x; }`, "a\nb\n10\n11\n"},
+		{"Errors", `
+for (var x in 1) print x;
+class Empty {}
+for (var x in Empty()) print x;
+class Bad { iterator() { return 1; } }
+for (var x in Bad()) print x;
+range(1, "2"); range(0, 1, 0); range();`, `Can only iterate over lists, maps, strings, ranges, generators and instances with an iterator method.
+[line 2]
+Can only iterate over lists, maps, strings, ranges, generators and instances with an iterator method.
+[line 4]
+Only instances have properties.
+[line 6]
+Range bounds must be numbers.
+[line 7]
+Range step can't be 0.
+[line 7]
+Expected 1 to 3 arguments but got 0.
+[line 7]
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+// Leaving a for-in loop early closes its generator, ending the goroutine its
+// body runs in.
+func TestForInClosesGenerators(t *testing.T) {
+	source := `
+fun nat() { var n = 0; while (true) { yield n; n = n + 1; } }
+fun first() { for (var n in nat()) return n; }
+for (var k in range(1000)) {
+    for (var n in nat()) if (n == 1) break;
+    first();
+}
+for (var n in nat()) nil();`
+	before := runtime.NumGoroutine()
+	expected := "Can only call functions and classes.\n[line 8]\n"
+	if output := run(t, source); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	// A closed generator has finished its body, but its goroutine may take a
+	// moment to exit.
+	for range 100 {
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("Expected at most %d goroutines, got %d", before, runtime.NumGoroutine())
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Self recursion", `
+fun countdown(n) {
+    if (n == 0) return "done";
+    return countdown(n - 1);
+}
+print countdown(1000000);`, "done\n"},
+		{"Mutual recursion", `
+fun isEven(n) { if (n == 0) return true; return isOdd(n - 1); }
+fun isOdd(n) { if (n == 0) return false; return isEven(n - 1); }
+print isEven(100000);
+print isOdd(100001);`, "true\ntrue\n"},
+		{"Methods", `
+class Counter {
+    init() { this.count = 0; }
+    countTo(n) {
+        if (this.count == n) return this;
+        this.count = this.count + 1;
+        return this.countTo(n);
+    }
+}
+print Counter().countTo(100000).count;`, "100000\n"},
+		{"Closures", `
+fun loop(n, acc) {
+    fun step() { return loop(n - 1, acc + n); }
+    if (n == 0) return acc;
+    return step();
+}
+print loop(100000, 0);`, "5000050000\n"},
+		{"Tail calls to classes and natives", `
+class A { init(n) { this.n = n; } }
+fun make(n) { return A(n); }
+fun now() { return clock(); }
+print make(3).n;
+print now() > 0;`, "3\ntrue\n"},
+		{"Errors in tail calls", `
+fun f(n) { return g(n); }
+fun g(n) { return -n; }
+f("a");
+fun h() { return f(); }
+h();`, "Operand must be a number.\n[line 3]\nExpected 1 arguments but got 0.\n[line 5]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := run(t, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
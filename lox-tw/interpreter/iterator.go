@@ -0,0 +1,233 @@
+package interpreter
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+	"lox-tw/utils"
+)
+
+// Range is the numbers from Start up to End, excluded, counting by Step,
+// created by the range native. The VM shares it with the tree-walker.
+type Range struct {
+	Start float64
+	End   float64
+	Step  float64
+}
+
+// NewRange returns the range of the arguments of range(end),
+// range(start, end) or range(start, end, step).
+func NewRange(arguments []any) (*Range, error) {
+	if len(arguments) < 1 || len(arguments) > 3 {
+		return nil, errors.New(utils.ArityMessage(1, 3, len(arguments)))
+	}
+
+	bounds := []float64{0, 0, 1}
+	if len(arguments) == 1 {
+		arguments = []any{0.0, arguments[0]}
+	}
+	for i, argument := range arguments {
+		bound, ok := argument.(float64)
+		if !ok {
+			return nil, errors.New("Range bounds must be numbers.")
+		}
+		bounds[i] = bound
+	}
+	if bounds[2] == 0 {
+		return nil, errors.New("Range step can't be 0.")
+	}
+
+	return &Range{Start: bounds[0], End: bounds[1], Step: bounds[2]}, nil
+}
+
+func (r *Range) String() string {
+	bounds := Stringify(r.Start) + ", " + Stringify(r.End)
+	if r.Step != 1 {
+		bounds += ", " + Stringify(r.Step)
+	}
+	return "range(" + bounds + ")"
+}
+
+func (r *Range) length() int {
+	length := math.Ceil((r.End - r.Start) / r.Step)
+	if !(length > 0) {
+		return 0
+	}
+	return int(length)
+}
+
+// RangeNative creates a range: range(end), range(start, end) or
+// range(start, end, step).
+type RangeNative struct{}
+
+func (r RangeNative) Arity() (int, int) {
+	return 1, 3
+}
+
+func (r RangeNative) Call(interpreter Interpreter, arguments []any) (any, error) {
+	return NewRange(arguments)
+}
+
+func (r RangeNative) String() string {
+	return "<native fn>"
+}
+
+// Iterator steps through the elements of a list, the keys of a map, the
+// characters of a string or the numbers of a range, with the same done and
+// next() as generators. Lists are read as they are when each element is
+// asked for, maps as they were when the iteration started.
+type Iterator struct {
+	index   int
+	length  func() int
+	element func(index int) any
+}
+
+// Iterate returns an iterator over a list, a map, a string or a range, and
+// whether the value is one of them.
+func Iterate(value any) (*Iterator, bool) {
+	switch value := value.(type) {
+	case *List:
+		return &Iterator{
+			length:  func() int { return len(value.Elements) },
+			element: func(index int) any { return value.Elements[index] },
+		}, true
+	case *Map:
+		keys := slices.Clone(value.keys)
+		return &Iterator{
+			length:  func() int { return len(keys) },
+			element: func(index int) any { return keys[index] },
+		}, true
+	case string:
+		characters := strings.Split(value, "")
+		return &Iterator{
+			length:  func() int { return len(characters) },
+			element: func(index int) any { return characters[index] },
+		}, true
+	case *Range:
+		length := value.length()
+		return &Iterator{
+			length:  func() int { return length },
+			element: func(index int) any { return value.Start + float64(index)*value.Step },
+		}, true
+	}
+	return nil, false
+}
+
+func (it *Iterator) Done() bool {
+	return it.index >= it.length()
+}
+
+// Next returns the next element, nil once there are none left.
+func (it *Iterator) Next() any {
+	if it.Done() {
+		return nil
+	}
+	it.index++
+	return it.element(it.index - 1)
+}
+
+func (it *Iterator) String() string {
+	return "<iterator>"
+}
+
+func (it *Iterator) Field(name string) (any, bool) {
+	if name == "done" {
+		return it.Done(), true
+	}
+	return nil, false
+}
+
+func (it *Iterator) MethodArity(name string) (int, bool) {
+	return 0, name == "next"
+}
+
+func (it *Iterator) CallMethod(name string, arguments []any) (any, error) {
+	return it.Next(), nil
+}
+
+// iterate returns a function stepping through the values of a for-in loop,
+// which reports false once there are none left, and one to call when the
+// loop ends, closing generators. Instances are iterated through what their
+// iterator method returns, which like generators has a done property and a
+// next method.
+func (i Interpreter) iterate(iterable any, keyword token.Token) (func() (any, bool, error), func(), error) {
+	if instance, ok := iterable.(*Instance); ok {
+		if method := instance.class.FindMethod("iterator"); method != nil {
+			if err := checkArity(method, keyword, 0); err != nil {
+				return nil, nil, err
+			}
+			result, err := method.callMethod(i, instance, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, ok := result.(*Generator); !ok {
+				return i.iterateProperties(result, keyword), func() {}, nil
+			}
+			iterable = result
+		}
+	}
+
+	if generator, ok := iterable.(*Generator); ok {
+		return func() (any, bool, error) {
+			done, err := generator.Done()
+			if err == errGeneratorRunning {
+				err = &RuntimeError{Token: keyword, Message: err.Error()}
+			}
+			if done || err != nil {
+				return nil, false, err
+			}
+			value, err := generator.Next()
+			return value, true, err
+		}, generator.Close, nil
+	}
+
+	if iterator, ok := Iterate(iterable); ok {
+		return func() (any, bool, error) {
+			if iterator.Done() {
+				return nil, false, nil
+			}
+			return iterator.Next(), true, nil
+		}, func() {}, nil
+	}
+
+	return nil, nil, &RuntimeError{
+		Token:   keyword,
+		Message: "Can only iterate over lists, maps, strings, ranges, generators and instances with an iterator method.",
+	}
+}
+
+// iterateProperties steps through an iterator by reading its done property
+// and calling its next method.
+func (i Interpreter) iterateProperties(iterator any, keyword token.Token) func() (any, bool, error) {
+	property := func(name string) ast.GetExpr[any] {
+		at := keyword
+		at.Lexeme = name
+		return ast.GetExpr[any]{Name: at, Cache: ast.NewPropertyCache()}
+	}
+	done, next := property("done"), property("next")
+
+	return func() (any, bool, error) {
+		value, err := i.getProperty(iterator, done)
+		if err != nil || utils.IsTruthy(value) {
+			return nil, false, err
+		}
+
+		value, err = i.getProperty(iterator, next)
+		if err != nil {
+			return nil, false, err
+		}
+		function, ok := value.(Callable)
+		if !ok {
+			return nil, false, &RuntimeError{Token: next.Name, Message: "Can only call functions and classes."}
+		}
+		if err := checkArity(function, next.Name, 0); err != nil {
+			return nil, false, err
+		}
+		value, err = i.callFunction(pendingCall{function: function, parenthesis: next.Name})
+		return value, true, err
+	}
+}
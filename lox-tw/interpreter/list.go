@@ -0,0 +1,102 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// List is a growable sequence of values, created by the list native and by
+// rest parameters. The VM shares it with the tree-walker.
+type List struct {
+	Elements []any
+}
+
+func NewList(elements []any) *List {
+	return &List{Elements: slices.Clone(elements)}
+}
+
+// A list contained in itself is shown as [...].
+func (l *List) String() string {
+	return l.format(make(map[any]bool))
+}
+
+func (l *List) format(shown map[any]bool) string {
+	if shown[l] {
+		return "[...]"
+	}
+	shown[l] = true
+	defer delete(shown, l)
+
+	elements := make([]string, len(l.Elements))
+	for i, element := range l.Elements {
+		elements[i] = format(element, shown)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (l *List) Field(name string) (any, bool) {
+	if name == "length" {
+		return float64(len(l.Elements)), true
+	}
+	return nil, false
+}
+
+func (l *List) MethodArity(name string) (int, bool) {
+	arity, ok := map[string]int{
+		"get":  1,
+		"set":  2,
+		"push": 1,
+	}[name]
+	return arity, ok
+}
+
+func (l *List) CallMethod(name string, arguments []any) (any, error) {
+	switch name {
+	case "get":
+		index, err := l.index(arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		return l.Elements[index], nil
+	case "set":
+		index, err := l.index(arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		l.Elements[index] = arguments[1]
+		return arguments[1], nil
+	case "push":
+		l.Elements = append(l.Elements, arguments[0])
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s'.", name)
+}
+
+func (l *List) index(value any) (int, error) {
+	index, ok := value.(float64)
+	if !ok || index != math.Trunc(index) {
+		return 0, errors.New("List index must be an integer.")
+	}
+	if index < 0 || int(index) >= len(l.Elements) {
+		return 0, errors.New("List index out of range.")
+	}
+	return int(index), nil
+}
+
+// ListNative creates a list of its arguments: list(1, 2, 3).
+type ListNative struct{}
+
+func (l ListNative) Arity() (int, int) {
+	return 0, -1
+}
+
+func (l ListNative) Call(interpreter Interpreter, arguments []any) (any, error) {
+	return NewList(arguments), nil
+}
+
+func (l ListNative) String() string {
+	return "<native fn>"
+}
@@ -0,0 +1,97 @@
+package interpreter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Map associates values with keys of any type, kept in the order they were
+// first set in, created by the map native. The VM shares it with the
+// tree-walker.
+type Map struct {
+	keys   []any
+	values map[any]any
+}
+
+func NewMap() *Map {
+	return &Map{values: make(map[any]any)}
+}
+
+// A map contained in itself is shown as {...}.
+func (m *Map) String() string {
+	return m.format(make(map[any]bool))
+}
+
+func (m *Map) format(shown map[any]bool) string {
+	if shown[m] {
+		return "{...}"
+	}
+	shown[m] = true
+	defer delete(shown, m)
+
+	entries := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		entries[i] = format(key, shown) + ": " + format(m.values[key], shown)
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+func (m *Map) Field(name string) (any, bool) {
+	if name == "length" {
+		return float64(len(m.keys)), true
+	}
+	return nil, false
+}
+
+func (m *Map) MethodArity(name string) (int, bool) {
+	arity, ok := map[string]int{
+		"get":    1,
+		"set":    2,
+		"has":    1,
+		"remove": 1,
+	}[name]
+	return arity, ok
+}
+
+// CallMethod runs a method of the map. Getting or removing a missing key
+// returns nil.
+func (m *Map) CallMethod(name string, arguments []any) (any, error) {
+	key := arguments[0]
+	switch name {
+	case "get":
+		return m.values[key], nil
+	case "set":
+		if _, ok := m.values[key]; !ok {
+			m.keys = append(m.keys, key)
+		}
+		m.values[key] = arguments[1]
+		return arguments[1], nil
+	case "has":
+		_, ok := m.values[key]
+		return ok, nil
+	case "remove":
+		value, ok := m.values[key]
+		if ok {
+			delete(m.values, key)
+			m.keys = slices.DeleteFunc(m.keys, func(k any) bool { return k == key })
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s'.", name)
+}
+
+// MapNative creates an empty map.
+type MapNative struct{}
+
+func (m MapNative) Arity() (int, int) {
+	return 0, 0
+}
+
+func (m MapNative) Call(interpreter Interpreter, arguments []any) (any, error) {
+	return NewMap(), nil
+}
+
+func (m MapNative) String() string {
+	return "<native fn>"
+}
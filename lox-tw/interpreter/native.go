@@ -4,12 +4,13 @@ import (
 	"time"
 
 	"lox-tw/ast"
+	"lox-tw/token"
 )
 
 type Clock struct{}
 
-func (c Clock) Arity() int {
-	return 0
+func (c Clock) Arity() (int, int) {
+	return 0, 0
 }
 
 func (c Clock) Call(interpreter Interpreter, arguments []any) (any, error) {
@@ -20,10 +21,30 @@ func (c Clock) String() string {
 	return "<native fn>"
 }
 
+// Str converts a value to a string like print does.
+type Str struct{}
+
+func (s Str) Arity() (int, int) {
+	return 1, 1
+}
+
+func (s Str) Call(interpreter Interpreter, arguments []any) (any, error) {
+	return interpreter.stringify(arguments[0])
+}
+
+func (s Str) String() string {
+	return "<native fn>"
+}
+
 type Function struct {
 	declaration   ast.FunctionStmt[any]
 	closure       *Environment
 	isInitializer bool
+
+	// The class declaring the function, when it is a method, and whether
+	// the method is a setter.
+	className string
+	isSetter  bool
 }
 
 func (f Function) String() string {
@@ -38,45 +59,82 @@ func NewFunction(function ast.FunctionStmt[any], closure *Environment, isInitial
 	}
 }
 
-func (f *Function) Arity() int {
-	return len(f.declaration.Parameters)
+// Name returns the name of the function, prefixed by its class for methods.
+// Setters end with '=', apart from the getter of the same property.
+func (f *Function) Name() string {
+	if f.className == "" {
+		return f.declaration.Name.Lexeme
+	}
+	if f.isSetter {
+		return f.className + "." + f.declaration.Name.Lexeme + "="
+	}
+	return f.className + "." + f.declaration.Name.Lexeme
+}
+
+func (f *Function) Arity() (int, int) {
+	return arity(f.declaration.Defaults, f.declaration.Parameters, f.declaration.Ellipsis)
 }
 
 func (f *Function) Call(interpreter Interpreter, arguments []any) (any, error) {
-	env := NewChildEnvironment(f.closure)
-	newInterpreter := NewInterpreterWithEnv(env, interpreter.exprToDepth)
+	return interpreter.callFunction(pendingCall{function: f, arguments: arguments})
+}
 
-	for i, param := range f.declaration.Parameters {
-		newInterpreter.environment.Define(param.Lexeme, arguments[i])
+// callMethod calls the function as a method of an instance, like calling it
+// bound to the instance would, without allocating the bound function.
+func (f *Function) callMethod(interpreter Interpreter, instance *Instance, arguments []any) (any, error) {
+	return interpreter.callFunction(pendingCall{function: f, receiver: instance, arguments: arguments})
+}
+
+// run executes the body of the function, returning either its value or the
+// call it ends with in tail position. Generators return their body instead,
+// to run when values are asked for.
+func (f *Function) run(interpreter Interpreter, closure *Environment, arguments []any) (any, *pendingCall, error) {
+	env := NewChildEnvironment(closure)
+	newInterpreter := interpreter.withEnvironment(env)
+
+	if err := defineParameters(newInterpreter, f.declaration.Parameters, f.declaration.Defaults, f.declaration.Ellipsis, arguments); err != nil {
+		return nil, nil, err
+	}
+	if f.declaration.Generator {
+		return newGenerator(f.declaration.Name.Lexeme, f.Name(), f.declaration.Name.Line, f.declaration.Body, newInterpreter), nil, nil
 	}
 
-	err := executeBlock(f.declaration.Body, newInterpreter)
+	err := executeBody(f.Name(), f.declaration.Name.Line, f.declaration.Body, newInterpreter)
 	switch err := err.(type) {
 	case *ReturnError:
 		if f.isInitializer {
-			return f.closure.GetAtByLexeme(0, "this")
+			return closure.GetAt(0, 0), nil, nil
 		}
 
-		return err.Value, nil
+		return err.Value, err.tailCall, nil
 	default:
 		if f.isInitializer {
-			return f.closure.GetAtByLexeme(0, "this")
+			return closure.GetAt(0, 0), nil, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
-
-	return nil, nil
 }
 
 func (f *Function) Bind(instance *Instance) *Function {
+	method := NewFunction(f.declaration, f.bindThis(instance), f.isInitializer)
+	method.className = f.className
+	method.isSetter = f.isSetter
+	return method
+}
+
+// bindThis returns the environment of a method bound to an instance.
+func (f *Function) bindThis(instance *Instance) *Environment {
 	env := NewChildEnvironment(f.closure)
 	env.Define("this", instance)
-	return NewFunction(f.declaration, env, f.isInitializer)
+	if instance.class.refinements != nil {
+		env.Define("inner", instance.class.refinements[f])
+	}
+	return env
 }
 
 func executeBlock(statements []ast.Stmt[any], interpreter *Interpreter) error {
 	for _, statement := range statements {
-		err := statement.Accept(interpreter)
+		err := interpreter.Execute(statement)
 		if err != nil {
 			return err
 		}
@@ -85,6 +143,61 @@ func executeBlock(statements []ast.Stmt[any], interpreter *Interpreter) error {
 	return nil
 }
 
+// executeBody runs the body of a function, telling the tracer about the call.
+func executeBody(name string, line uint, statements []ast.Stmt[any], interpreter *Interpreter) error {
+	if interpreter.tracer == nil {
+		return executeBlock(statements, interpreter)
+	}
+
+	interpreter.tracer.EnterCall(name, line)
+	defer interpreter.tracer.ExitCall()
+
+	return executeBlock(statements, interpreter)
+}
+
+// pendingCall is a call whose callee and arguments are evaluated. The
+// receiver is set when calling a method of an instance, the parenthesis when
+// the call is written in the source, for the errors of natives to have a
+// position.
+type pendingCall struct {
+	function    Callable
+	receiver    *Instance
+	arguments   []any
+	parenthesis token.Token
+}
+
+// callFunction makes a call, then the calls in tail position that each
+// function returns, one after the other, so tail calls do not grow the Go
+// stack.
+func (i Interpreter) callFunction(call pendingCall) (any, error) {
+	for {
+		var value any
+		var tailCall *pendingCall
+		var err error
+		switch function := call.function.(type) {
+		case *Function:
+			closure := function.closure
+			if call.receiver != nil {
+				closure = function.bindThis(call.receiver)
+			}
+			value, tailCall, err = function.run(i, closure, call.arguments)
+		case *Lambda:
+			value, tailCall, err = function.run(i, call.arguments)
+		default:
+			value, err := call.function.Call(i, call.arguments)
+			if _, ok := err.(*RuntimeError); err != nil && !ok {
+				err = &RuntimeError{Token: call.parenthesis, Message: err.Error()}
+			}
+			return value, err
+		}
+
+		if tailCall == nil {
+			return value, err
+		}
+		call = *tailCall
+	}
+}
+
 type Lambda struct {
 	declaration ast.LambdaExpr[any]
 	closure     *Environment
@@ -101,25 +214,30 @@ func NewLambda(lambda ast.LambdaExpr[any], closure *Environment) *Lambda {
 	}
 }
 
-func (l *Lambda) Arity() int {
-	return len(l.declaration.Parameters)
+func (l *Lambda) Arity() (int, int) {
+	return arity(l.declaration.Defaults, l.declaration.Parameters, l.declaration.Ellipsis)
 }
 
 func (l *Lambda) Call(interpreter Interpreter, arguments []any) (any, error) {
+	return interpreter.callFunction(pendingCall{function: l, arguments: arguments})
+}
+
+func (l *Lambda) run(interpreter Interpreter, arguments []any) (any, *pendingCall, error) {
 	env := NewChildEnvironment(l.closure)
-	newInterpreter := NewInterpreterWithEnv(env, interpreter.exprToDepth)
+	newInterpreter := interpreter.withEnvironment(env)
 
-	for i, param := range l.declaration.Parameters {
-		newInterpreter.environment.Define(param.Lexeme, arguments[i])
+	if err := defineParameters(newInterpreter, l.declaration.Parameters, l.declaration.Defaults, l.declaration.Ellipsis, arguments); err != nil {
+		return nil, nil, err
+	}
+	if l.declaration.Generator {
+		return newGenerator("<lambda>", "<lambda>", l.declaration.Keyword.Line, l.declaration.Body, newInterpreter), nil, nil
 	}
 
-	err := executeBlock(l.declaration.Body, newInterpreter)
+	err := executeBody("<lambda>", l.declaration.Keyword.Line, l.declaration.Body, newInterpreter)
 	switch err := err.(type) {
 	case *ReturnError:
-		return err.Value, nil
+		return err.Value, err.tailCall, nil
 	default:
-		return nil, err
+		return nil, nil, err
 	}
-
-	return nil, nil
 }
@@ -0,0 +1,67 @@
+package interpreter
+
+import "lox-tw/token"
+
+// Object is a value of a built-in type with properties, such as lists and
+// maps. The VM reads their properties the same way.
+type Object interface {
+	// Field returns the value of a property that isn't a method.
+	Field(name string) (any, bool)
+	// MethodArity returns the number of arguments a method takes, and
+	// whether the object has such a method.
+	MethodArity(name string) (int, bool)
+	// CallMethod runs a method with the number of arguments MethodArity
+	// returns.
+	CallMethod(name string, arguments []any) (any, error)
+}
+
+// objectMethod is a method of an object, bound to it when its property is
+// read.
+type objectMethod struct {
+	object Object
+	name   token.Token
+}
+
+func (m objectMethod) Arity() (int, int) {
+	arity, _ := m.object.MethodArity(m.name.Lexeme)
+	return arity, arity
+}
+
+func (m objectMethod) Call(interpreter Interpreter, arguments []any) (any, error) {
+	result, err := m.object.CallMethod(m.name.Lexeme, arguments)
+	if err != nil {
+		return nil, &RuntimeError{Token: m.name, Message: err.Error()}
+	}
+	return result, nil
+}
+
+func (m objectMethod) String() string {
+	return "<native fn>"
+}
+
+// objectProperty reads a field of an object or one of its methods.
+func objectProperty(object Object, name token.Token) (any, error) {
+	if value, ok := object.Field(name.Lexeme); ok {
+		return value, nil
+	}
+	if _, ok := object.MethodArity(name.Lexeme); ok {
+		return objectMethod{object: object, name: name}, nil
+	}
+
+	return nil, &RuntimeError{
+		Token:   name,
+		Message: "Undefined property '" + name.Lexeme + "'.",
+	}
+}
+
+// format shows a value inside a list or a map, where the lists and maps
+// already shown are shown as [...] and {...} instead of recursing forever.
+func format(value any, shown map[any]bool) string {
+	switch value := value.(type) {
+	case *List:
+		return value.format(shown)
+	case *Map:
+		return value.format(shown)
+	}
+	return Stringify(value)
+}
@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"fmt"
+	"maps"
 	"strconv"
 
 	"lox-tw/ast"
@@ -34,9 +35,13 @@ func (i Interpreter) VisitIfStmt(stmt ast.IfStmt[any]) error {
 	}
 
 	if utils.IsTruthy(condition) {
-		return stmt.ThenBranch.Accept(i)
-	} else if stmt.ElseBranch != nil {
-		return stmt.ElseBranch.Accept(i)
+		i.branch(stmt.Keyword, 0)
+		return i.Execute(stmt.ThenBranch)
+	}
+
+	i.branch(stmt.Keyword, 1)
+	if stmt.ElseBranch != nil {
+		return i.Execute(stmt.ElseBranch)
 	}
 
 	return nil
@@ -53,7 +58,10 @@ func (i Interpreter) VisitWhileStmt(stmt ast.WhileStmt[any]) error {
 			break
 		}
 
-		err = stmt.Body.Accept(i)
+		err = i.Execute(stmt.Body)
+		if _, ok := err.(*BreakError); ok {
+			break
+		}
 		if err != nil {
 			return err
 		}
@@ -62,22 +70,93 @@ func (i Interpreter) VisitWhileStmt(stmt ast.WhileStmt[any]) error {
 	return nil
 }
 
+// VisitForInStmt runs the body once for each value of the iterable, every
+// time in an environment of its own holding the loop variable, so closures
+// capture the value of their iteration. A generator left early, by a break,
+// a return or an error, is closed.
+func (i Interpreter) VisitForInStmt(stmt ast.ForInStmt[any]) error {
+	iterable, err := stmt.Iterable.Accept(i)
+	if err != nil {
+		return err
+	}
+
+	next, stop, err := i.iterate(iterable, stmt.Keyword)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	for {
+		value, ok, err := next()
+		if err != nil || !ok {
+			return err
+		}
+
+		env := NewChildEnvironment(i.environment)
+		env.Define(stmt.Name.Lexeme, value)
+		err = i.withEnvironment(env).Execute(stmt.Body)
+		if _, ok := err.(*BreakError); ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func (i Interpreter) VisitPrintStmt(stmt ast.PrintStmt[any]) error {
 	value, err := stmt.Expression.Accept(i)
 	if err != nil {
 		return err
 	}
 
+	text, err := i.stringify(value)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(i.output(), text)
+
+	return nil
+}
+
+// Stringify returns the text print shows for a value.
+func Stringify(value any) string {
 	switch v := value.(type) {
 	case nil:
-		fmt.Println("nil")
+		return "nil"
 	case float64:
-		finalValue := strconv.FormatFloat(v, 'f', -1, 64)
-		fmt.Println(finalValue)
+		return strconv.FormatFloat(v, 'f', -1, 64)
 	default:
-		fmt.Println(v)
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// stringify converts a value like Stringify, calling the toString method of
+// instances declaring one. An instance converted again by its own toString is
+// shown as usual, instead of recursing forever.
+func (i Interpreter) stringify(value any) (string, error) {
+	method := toString(value)
+	if method == nil || i.converting[value.(*Instance)] {
+		return Stringify(value), nil
 	}
 
+	instance := value.(*Instance)
+	i.converting[instance] = true
+	defer delete(i.converting, instance)
+
+	result, err := method.callMethod(i, instance, nil)
+	if err != nil {
+		return "", err
+	}
+	return i.stringify(result)
+}
+
+// toString returns the toString method of an instance, if its class declares
+// one.
+func toString(value any) *Function {
+	if instance, ok := value.(*Instance); ok {
+		return instance.class.FindMethod("toString")
+	}
 	return nil
 }
 
@@ -99,6 +178,23 @@ func (i Interpreter) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 		}
 	}
 
+	var traits []*Trait
+	for _, traitExpr := range stmt.Traits {
+		traitValue, err := traitExpr.Accept(i)
+		if err != nil {
+			return err
+		}
+
+		trait, ok := traitValue.(*Trait)
+		if !ok {
+			return &RuntimeError{
+				Token:   traitExpr.Name,
+				Message: "Can only compose traits.",
+			}
+		}
+		traits = append(traits, trait)
+	}
+
 	i.environment.Define(stmt.Name.Lexeme, nil)
 
 	if superclass != nil {
@@ -109,14 +205,31 @@ func (i Interpreter) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 	globalMethods := make(map[string]*Function)
 	for _, method := range stmt.GlobalMethods {
 		globalMethods[method.Name.Lexeme] = NewFunction(method, i.environment, false)
+		globalMethods[method.Name.Lexeme].className = stmt.Name.Lexeme
 	}
-	metaclass := NewClass(nil, stmt.Name.Lexeme+" metaclass", superclass, globalMethods)
+	metaclass := NewClass(nil, stmt.Name.Lexeme+" metaclass", superclass, globalMethods, nil, nil)
 
+	// Methods of traits are overridden by the ones the class declares.
 	methods := make(map[string]*Function)
+	for _, trait := range traits {
+		maps.Copy(methods, trait.Methods)
+	}
 	for _, method := range stmt.Methods {
 		methods[method.Name.Lexeme] = NewFunction(method, i.environment, method.Name.Lexeme == "init")
+		methods[method.Name.Lexeme].className = stmt.Name.Lexeme
+	}
+	getters := make(map[string]*Function)
+	for _, getter := range stmt.Getters {
+		getters[getter.Name.Lexeme] = NewFunction(getter, i.environment, false)
+		getters[getter.Name.Lexeme].className = stmt.Name.Lexeme
+	}
+	setters := make(map[string]*Function)
+	for _, setter := range stmt.Setters {
+		setters[setter.Name.Lexeme] = NewFunction(setter, i.environment, false)
+		setters[setter.Name.Lexeme].className = stmt.Name.Lexeme
+		setters[setter.Name.Lexeme].isSetter = true
 	}
-	class := NewClass(metaclass, stmt.Name.Lexeme, superclass, methods)
+	class := NewClass(metaclass, stmt.Name.Lexeme, superclass, methods, getters, setters)
 
 	if superclass != nil {
 		i.environment = i.environment.enclosing
@@ -127,12 +240,23 @@ func (i Interpreter) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 	return nil
 }
 
+func (i Interpreter) VisitTraitStmt(stmt ast.TraitStmt[any]) error {
+	methods := make(map[string]*Function)
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = NewFunction(method, i.environment, method.Name.Lexeme == "init")
+		methods[method.Name.Lexeme].className = stmt.Name.Lexeme
+	}
+
+	i.environment.Define(stmt.Name.Lexeme, &Trait{Name: stmt.Name.Lexeme, Methods: methods})
+	return nil
+}
+
 func (i Interpreter) VisitBlockStmt(stmt ast.BlockStmt[any]) error {
 	parentEnv := i.environment
 	i.environment = NewChildEnvironment(parentEnv)
 
 	for _, statement := range stmt.Statements {
-		err := statement.Accept(i)
+		err := i.Execute(statement)
 		if err != nil {
 			return err
 		}
@@ -145,13 +269,60 @@ func (i Interpreter) VisitBreakStmt(stmt ast.BreakStmt[any]) error {
 	return &BreakError{}
 }
 
+// The message is only evaluated when the assertion fails.
+func (i Interpreter) VisitAssertStmt(stmt ast.AssertStmt[any]) error {
+	condition, err := stmt.Condition.Accept(i)
+	if err != nil {
+		return err
+	}
+
+	if utils.IsTruthy(condition) {
+		return nil
+	}
+
+	message := "Assertion failed."
+	if stmt.Message != nil {
+		value, err := stmt.Message.Accept(i)
+		if err != nil {
+			return err
+		}
+		message = "Assertion failed: " + Stringify(value)
+	}
+
+	return &RuntimeError{Token: stmt.Keyword, Message: message}
+}
+
 func (i Interpreter) VisitFunctionStmt(stmt ast.FunctionStmt[any]) error {
 	function := NewFunction(stmt, i.environment, false)
 	i.environment.Define(stmt.Name.Lexeme, function)
 	return nil
 }
 
+func (i Interpreter) VisitYieldStmt(stmt ast.YieldStmt[any]) error {
+	var value any = nil
+	if stmt.Value != nil {
+		var err error
+		value, err = stmt.Value.Accept(i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.generator.yield(value)
+}
+
 func (i Interpreter) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
+	// A call in tail position is left for the caller to make once this
+	// function is gone, for deep tail recursion not to grow the Go stack.
+	// Traced programs make every call, for tracers to see each of them.
+	if call, ok := stmt.Value.(ast.CallExpr[any]); ok && i.tracer == nil {
+		tailCall, err := i.evaluateCall(call)
+		if err != nil {
+			return err
+		}
+		return &ReturnError{tailCall: &tailCall}
+	}
+
 	var value any = nil
 	if stmt.Value != nil {
 		var err error
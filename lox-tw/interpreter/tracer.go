@@ -0,0 +1,25 @@
+package interpreter
+
+import (
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+// Tracer observes a running program, e.g. to pause it in a debugger.
+type Tracer interface {
+	// BeforeStmt is called before every statement. Returning an error stops
+	// the program with that error.
+	BeforeStmt(interpreter Interpreter, stmt ast.Stmt[any]) error
+
+	// EnterCall and ExitCall surround the execution of the body of a Lox
+	// function, method or lambda, declared on the given line. The body of a
+	// generator is entered each time it runs up to its next yield.
+	EnterCall(name string, line uint)
+	ExitCall()
+
+	// Branch is called when a condition picks a branch. The site is the 'if'
+	// keyword, the '?' of a ternary or the operator of a logical expression.
+	// Branch 0 is the then branch, the true branch, or a logical expression
+	// decided by its left operand; branch 1 is the other one.
+	Branch(site token.Token, branch int)
+}
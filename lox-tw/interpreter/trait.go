@@ -0,0 +1,11 @@
+package interpreter
+
+// Trait is a bundle of methods, copied into the classes composed with it.
+type Trait struct {
+	Name    string
+	Methods map[string]*Function
+}
+
+func (t *Trait) String() string {
+	return t.Name
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"lox-tw/lsp"
+)
+
+func runLsp(arguments []string) int {
+	if len(arguments) != 0 {
+		fmt.Println("Usage: lox-tw lsp")
+		return 64
+	}
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
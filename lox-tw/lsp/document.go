@@ -0,0 +1,264 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"lox-tw/ast"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+	"lox-tw/token"
+)
+
+// document is an open file, analysed every time its text changes.
+type document struct {
+	uri        string
+	text       string
+	lineStarts []int
+
+	tokens      []token.Token
+	stmts       []ast.Stmt[any]
+	resolver    *resolver.Resolver
+	diagnostics []Diagnostic
+
+	symbols    []DocumentSymbol
+	signatures map[token.Token]string
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:        uri,
+		text:       text,
+		lineStarts: []int{0},
+		resolver:   resolver.NewResolver(),
+		signatures: make(map[token.Token]string),
+	}
+	for i, c := range text {
+		if c == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+
+	tokens, err := scanner.ScanTokens(text)
+	if err != nil {
+		d.addLineDiagnostic(err.(*scanner.ScannerError))
+		return d
+	}
+	d.tokens = tokens
+
+	stmts, errors := parser.ParseTokensToStmtsWithErrors(tokens)
+	d.stmts = stmts
+	for _, err := range errors {
+		err := err.(*parser.ParserError)
+		d.addDiagnostic(err.Token, err.Error())
+	}
+
+	for _, err := range d.resolver.Resolve(stmts) {
+		err := err.(*resolver.ResolverError)
+		d.addDiagnostic(err.Token, err.Error())
+	}
+
+	d.symbols = d.collectSymbols(stmts)
+
+	return d
+}
+
+func (d *document) addDiagnostic(t token.Token, message string) {
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Range:    d.tokenRange(t),
+		Severity: SEVERITY_ERROR,
+		Source:   "lox",
+		Message:  message,
+	})
+}
+
+func (d *document) addLineDiagnostic(err *scanner.ScannerError) {
+	line := int(err.Line) - 1
+	end := len(d.text)
+	if line+1 < len(d.lineStarts) {
+		end = d.lineStarts[line+1] - 1
+	}
+
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Range:    Range{Start: Position{Line: line}, End: d.position(end)},
+		Severity: SEVERITY_ERROR,
+		Source:   "lox",
+		Message:  err.Error(),
+	})
+}
+
+// position converts a byte offset to a protocol position, which counts
+// characters in UTF-16 code units.
+func (d *document) position(offset int) Position {
+	offset = min(max(offset, 0), len(d.text))
+	line := sort.Search(len(d.lineStarts), func(i int) bool {
+		return d.lineStarts[i] > offset
+	}) - 1
+
+	prefix := d.text[d.lineStarts[line]:offset]
+	return Position{Line: line, Character: len(utf16.Encode([]rune(prefix)))}
+}
+
+func (d *document) offset(position Position) int {
+	if position.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+
+	start := d.lineStarts[position.Line]
+	units := 0
+	for i, c := range d.text[start:] {
+		if units >= position.Character || c == '\n' {
+			return start + i
+		}
+		units += len(utf16.Encode([]rune{c}))
+	}
+	return len(d.text)
+}
+
+func (d *document) tokenRange(t token.Token) Range {
+	end := int(t.Position)
+	return Range{Start: d.position(end - len(t.Lexeme)), End: d.position(end)}
+}
+
+// tokenAt returns the identifier under the cursor. A cursor right after the
+// last character still counts as being on the identifier.
+func (d *document) tokenAt(position Position) (token.Token, bool) {
+	offset := d.offset(position)
+	for _, t := range d.tokens {
+		start := int(t.Position) - len(t.Lexeme)
+		if t.Type == token.IDENTIFIER && start <= offset && offset <= int(t.Position) {
+			return t, true
+		}
+	}
+
+	return token.Token{}, false
+}
+
+// declarationAt returns the declaration of the name under the cursor.
+func (d *document) declarationAt(position Position) (token.Token, bool) {
+	name, ok := d.tokenAt(position)
+	if !ok {
+		return token.Token{}, false
+	}
+
+	return d.resolver.DeclarationOf(name)
+}
+
+func (d *document) location(t token.Token) Location {
+	return Location{URI: d.uri, Range: d.tokenRange(t)}
+}
+
+func (d *document) hover(declaration token.Token) string {
+	kind := d.resolver.Declarations[declaration]
+	signature, ok := d.signatures[declaration]
+	if !ok {
+		signature = declaration.Lexeme
+	}
+
+	return "```lox\n" + signature + "\n```\n(" + kind.String() + ")"
+}
+
+// collectSymbols walks the statements to list the classes, methods,
+// functions and global variables, and remembers their signatures for hover.
+func (d *document) collectSymbols(stmts []ast.Stmt[any]) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case ast.VarStmt[any]:
+			d.signatures[stmt.Name] = "var " + stmt.Name.Lexeme
+			symbols = append(symbols, d.symbol(stmt.Name, SYMBOL_VARIABLE, stmt.Name, stmt.Name, nil))
+		case ast.FunctionStmt[any]:
+			d.signatures[stmt.Name] = "fun " + signature(stmt)
+			children := d.collectSymbols(nestedDeclarations(stmt.Body))
+			symbols = append(symbols, d.symbol(stmt.Name, SYMBOL_FUNCTION, stmt.Name, stmt.RightBrace, children))
+		case ast.ClassStmt[any]:
+			header := "class " + stmt.Name.Lexeme
+			if stmt.Superclass != nil {
+				header += " < " + stmt.Superclass.Name.Lexeme
+			}
+			for i, trait := range stmt.Traits {
+				if i == 0 {
+					header += " with " + trait.Name.Lexeme
+				} else {
+					header += ", " + trait.Name.Lexeme
+				}
+			}
+			d.signatures[stmt.Name] = header
+
+			var methods []DocumentSymbol
+			for _, method := range stmt.Functions() {
+				d.signatures[method.Name] = stmt.Name.Lexeme + "." + signature(method)
+				children := d.collectSymbols(nestedDeclarations(method.Body))
+				methods = append(methods, d.symbol(method.Name, SYMBOL_METHOD, method.Name, method.RightBrace, children))
+			}
+			sort.Slice(methods, func(a, b int) bool {
+				return d.offset(methods[a].Range.Start) < d.offset(methods[b].Range.Start)
+			})
+
+			symbols = append(symbols, d.symbol(stmt.Name, SYMBOL_CLASS, stmt.Name, stmt.RightBrace, methods))
+		case ast.TraitStmt[any]:
+			d.signatures[stmt.Name] = "trait " + stmt.Name.Lexeme
+
+			var methods []DocumentSymbol
+			for _, method := range stmt.Methods {
+				d.signatures[method.Name] = stmt.Name.Lexeme + "." + signature(method)
+				children := d.collectSymbols(nestedDeclarations(method.Body))
+				methods = append(methods, d.symbol(method.Name, SYMBOL_METHOD, method.Name, method.RightBrace, children))
+			}
+
+			symbols = append(symbols, d.symbol(stmt.Name, SYMBOL_INTERFACE, stmt.Name, stmt.RightBrace, methods))
+		}
+	}
+
+	return symbols
+}
+
+func (d *document) symbol(name token.Token, kind int, first, last token.Token, children []DocumentSymbol) DocumentSymbol {
+	return DocumentSymbol{
+		Name:           name.Lexeme,
+		Detail:         d.signatures[name],
+		Kind:           kind,
+		Range:          Range{Start: d.tokenRange(first).Start, End: d.tokenRange(last).End},
+		SelectionRange: d.tokenRange(name),
+		Children:       children,
+	}
+}
+
+// Only functions and classes declared inside a body are listed as children,
+// local variables would only add noise.
+func nestedDeclarations(body []ast.Stmt[any]) []ast.Stmt[any] {
+	var declarations []ast.Stmt[any]
+	for _, stmt := range body {
+		switch stmt.(type) {
+		case ast.FunctionStmt[any], ast.ClassStmt[any], ast.TraitStmt[any]:
+			declarations = append(declarations, stmt)
+		}
+	}
+	return declarations
+}
+
+func signature(function ast.FunctionStmt[any]) string {
+	var parameters []string
+	for i, param := range function.Parameters {
+		parameter := param.Lexeme
+		if i < len(function.ParameterTypes) && function.ParameterTypes[i] != nil {
+			parameter += ": " + function.ParameterTypes[i].Lexeme
+		}
+		// Default values are elided, they can be long.
+		if i < len(function.Defaults) && function.Defaults[i] != nil {
+			parameter += " = …"
+		}
+		if function.Ellipsis != nil && i == len(function.Parameters)-1 {
+			parameter = "..." + parameter
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	result := function.Name.Lexeme + "(" + strings.Join(parameters, ", ") + ")"
+	if function.ReturnType != nil {
+		result += ": " + function.ReturnType.Lexeme
+	}
+	return result
+}
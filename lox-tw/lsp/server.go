@@ -0,0 +1,311 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+
+	"lox-tw/resolver"
+	"lox-tw/token"
+)
+
+// Server answers Language Server Protocol requests for Lox files. Messages
+// are JSON-RPC 2.0 objects framed by a Content-Length header.
+type Server struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*document
+	shutdown  bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		reader:    bufio.NewReader(in),
+		writer:    out,
+		documents: make(map[string]*document),
+	}
+}
+
+// Serve handles messages until the client sends 'exit' or closes the input.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+
+		response := message{JSONRPC: "2.0", ID: msg.ID, Result: result, Error: rpcErr}
+		if rpcErr == nil && result == nil {
+			// A null result must still be sent.
+			response.Result = json.RawMessage("null")
+		}
+		if err := s.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) read() (message, error) {
+	headers, err := textproto.NewReader(s.reader).ReadMIMEHeader()
+	if err != nil {
+		return message{}, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return message{}, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return message{}, err
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return message{}, err
+	}
+	return msg, nil
+}
+
+func (s *Server) write(msg message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return s.write(message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *Server) handle(msg message) (any, *responseError) {
+	if s.shutdown && msg.ID != nil {
+		return nil, &responseError{Code: INVALID_REQUEST, Message: "The server is shutting down."}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":       1,
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"renameProvider":         true,
+			},
+			"serverInfo": map[string]any{"name": "lox-tw"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+		})
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			if len(params.ContentChanges) == 0 {
+				return nil, nil
+			}
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			return nil, s.update(params.TextDocument.URI, text)
+		})
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			delete(s.documents, params.TextDocument.URI)
+			return nil, s.publishDiagnostics(params.TextDocument.URI, []Diagnostic{})
+		})
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			return s.definition(params)
+		})
+	case "textDocument/references":
+		var params ReferenceParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			return s.references(params)
+		})
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			return s.hover(params)
+		})
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			doc, err := s.document(params.TextDocument.URI)
+			if err != nil {
+				return nil, err
+			}
+			return append([]DocumentSymbol{}, doc.symbols...), nil
+		})
+	case "textDocument/rename":
+		var params RenameParams
+		return handleParams(msg, &params, func() (any, *responseError) {
+			return s.rename(params)
+		})
+	}
+
+	if msg.ID == nil {
+		// Unknown notifications, such as 'initialized', are ignored.
+		return nil, nil
+	}
+
+	return nil, &responseError{Code: METHOD_NOT_FOUND, Message: "Method not found: " + msg.Method}
+}
+
+func handleParams[T any](msg message, params *T, handler func() (any, *responseError)) (any, *responseError) {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return nil, &responseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+
+	return handler()
+}
+
+func (s *Server) document(uri string) (*document, *responseError) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &responseError{Code: INVALID_PARAMS, Message: "Unknown document: " + uri}
+	}
+	return doc, nil
+}
+
+func (s *Server) update(uri, text string) *responseError {
+	doc := newDocument(uri, text)
+	s.documents[uri] = doc
+
+	return s.publishDiagnostics(uri, append([]Diagnostic{}, doc.diagnostics...))
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []Diagnostic) *responseError {
+	err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return &responseError{Code: REQUEST_FAILED, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) (any, *responseError) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	declaration, ok := doc.declarationAt(params.Position)
+	if !ok {
+		return nil, nil
+	}
+	return doc.location(declaration), nil
+}
+
+func (s *Server) references(params ReferenceParams) (any, *responseError) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []Location{}
+	declaration, ok := doc.declarationAt(params.Position)
+	if !ok {
+		return locations, nil
+	}
+
+	if params.Context.IncludeDeclaration {
+		locations = append(locations, doc.location(declaration))
+	}
+	for _, use := range doc.resolver.UsesOf(declaration) {
+		locations = append(locations, doc.location(use))
+	}
+	return locations, nil
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) (any, *responseError) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := doc.tokenAt(params.Position)
+	if !ok {
+		return nil, nil
+	}
+	declaration, ok := doc.resolver.DeclarationOf(name)
+	if !ok {
+		return nil, nil
+	}
+
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: doc.hover(declaration)},
+		Range:    doc.tokenRange(name),
+	}, nil
+}
+
+func (s *Server) rename(params RenameParams) (any, *responseError) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isIdentifier(params.NewName) {
+		return nil, &responseError{Code: REQUEST_FAILED, Message: "'" + params.NewName + "' is not a valid name."}
+	}
+
+	declaration, ok := doc.declarationAt(params.Position)
+	if !ok {
+		return nil, &responseError{Code: REQUEST_FAILED, Message: "There is no symbol to rename here."}
+	}
+	// Methods are used through properties, which are only bound at runtime,
+	// renaming their declaration alone would break their calls.
+	if doc.resolver.Declarations[declaration] == resolver.METHOD_DECLARATION {
+		return nil, &responseError{Code: REQUEST_FAILED, Message: "Methods can't be renamed, their uses are only known at runtime."}
+	}
+
+	edits := []TextEdit{{Range: doc.tokenRange(declaration), NewText: params.NewName}}
+	for _, use := range doc.resolver.UsesOf(declaration) {
+		edits = append(edits, TextEdit{Range: doc.tokenRange(use), NewText: params.NewName})
+	}
+
+	return WorkspaceEdit{Changes: map[string][]TextEdit{doc.uri: edits}}, nil
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || token.TryKeywordTokenType(name) != token.NOTHING {
+		return false
+	}
+
+	for i, c := range name {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+		if !isLetter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
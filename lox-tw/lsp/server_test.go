@@ -0,0 +1,184 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+)
+
+const SOURCE = `class Shape {
+    area() { return 0; }
+}
+fun double(n) {
+    var result = n * 2;
+    return result;
+}
+print double(21);
+`
+
+// client drives a Server through pipes, the same way an editor would.
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	nextID int
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	go func() {
+		NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+
+	return &client{t: t, in: clientOut, out: bufio.NewReader(clientIn)}
+}
+
+func (c *client) send(msg map[string]any) {
+	msg["jsonrpc"] = "2.0"
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (c *client) receive() map[string]any {
+	headers, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatalf("Error reading headers: %v", err)
+	}
+
+	length, _ := strconv.Atoi(headers.Get("Content-Length"))
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		c.t.Fatalf("Error reading body: %v", err)
+	}
+
+	var msg map[string]any
+	json.Unmarshal(body, &msg)
+	return msg
+}
+
+func (c *client) request(method string, params any) any {
+	c.nextID += 1
+	c.send(map[string]any{"id": c.nextID, "method": method, "params": params})
+
+	response := c.receive()
+	if response["error"] != nil {
+		c.t.Fatalf("%s failed: %v", method, response["error"])
+	}
+	return response["result"]
+}
+
+func (c *client) open(text string) []any {
+	c.send(map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": "file:///test.lox", "version": 1, "text": text},
+	}})
+
+	notification := c.receive()
+	if notification["method"] != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("Expected diagnostics, got %v", notification)
+	}
+	return notification["params"].(map[string]any)["diagnostics"].([]any)
+}
+
+func at(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": "file:///test.lox"},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func start(location any) string {
+	position := location.(map[string]any)["range"].(map[string]any)["start"].(map[string]any)
+	return fmt.Sprintf("%v:%v", position["line"], position["character"])
+}
+
+func TestSession(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", map[string]any{})
+	c.send(map[string]any{"method": "initialized", "params": map[string]any{}})
+
+	if diagnostics := c.open(SOURCE); len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	// `result` in `return result;` is declared on line 4.
+	if definition := c.request("textDocument/definition", at(5, 12)); start(definition) != "4:8" {
+		t.Errorf("Expected definition at 4:8, got %s", start(definition))
+	}
+
+	// `double` is used after its declaration at the top-level.
+	if definition := c.request("textDocument/definition", at(7, 7)); start(definition) != "3:4" {
+		t.Errorf("Expected definition at 3:4, got %s", start(definition))
+	}
+
+	params := at(3, 11)
+	params["context"] = map[string]any{"includeDeclaration": true}
+	references := c.request("textDocument/references", params).([]any)
+	if len(references) != 2 || start(references[0]) != "3:11" || start(references[1]) != "4:17" {
+		t.Errorf("Unexpected references to 'n': %v", references)
+	}
+
+	hover := c.request("textDocument/hover", at(7, 8)).(map[string]any)
+	if value := hover["contents"].(map[string]any)["value"]; value != "```lox\nfun double(n)\n```\n(function)" {
+		t.Errorf("Unexpected hover: %v", value)
+	}
+
+	symbols := c.request("textDocument/documentSymbol", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///test.lox"},
+	}).([]any)
+	if len(symbols) != 2 {
+		t.Fatalf("Expected 2 symbols, got %v", symbols)
+	}
+	shape := symbols[0].(map[string]any)
+	if shape["name"] != "Shape" || shape["children"].([]any)[0].(map[string]any)["name"] != "area" {
+		t.Errorf("Unexpected class symbol: %v", shape)
+	}
+
+	rename := at(4, 9)
+	rename["newName"] = "doubled"
+	edit := c.request("textDocument/rename", rename).(map[string]any)
+	edits := edit["changes"].(map[string]any)["file:///test.lox"].([]any)
+	if len(edits) != 2 || start(edits[0]) != "4:8" || start(edits[1]) != "5:11" {
+		t.Errorf("Unexpected rename edits: %v", edits)
+	}
+
+	// `area` is a method, called through properties the resolver can't follow.
+	rename = at(1, 5)
+	rename["newName"] = "size"
+	c.nextID += 1
+	c.send(map[string]any{"id": c.nextID, "method": "textDocument/rename", "params": rename})
+	if response := c.receive(); response["error"] == nil {
+		t.Errorf("Expected renaming a method to fail, got %v", response["result"])
+	}
+
+	c.request("shutdown", nil)
+	c.send(map[string]any{"method": "exit"})
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", map[string]any{})
+
+	diagnostics := c.open("var a = ;\nfun f() { var b = b; }\nreturn 1;\n")
+	expected := []string{
+		"[line 1] Error at ';': Expect expression.",
+		"[line 2] Error at 'b': Can't read local variable in its own initializer.",
+		"[line 3] Error at 'return': Can't return from top-level code.",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, message := range expected {
+		if diagnostic := diagnostics[i].(map[string]any); diagnostic["message"] != message {
+			t.Errorf("Expected '%s', got '%s'", message, diagnostic["message"])
+		}
+	}
+
+	c.send(map[string]any{"method": "exit"})
+}
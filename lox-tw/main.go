@@ -1,27 +1,93 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"bufio"
 
 	"lox-tw/ast"
+	"lox-tw/coverage"
 	"lox-tw/interpreter"
+	"lox-tw/optimizer"
 	"lox-tw/parser"
+	"lox-tw/profiler"
 	"lox-tw/resolver"
 	"lox-tw/scanner"
+	"lox-tw/vm"
 )
 
 var RUNTIME_ERROR = false
 var RESOLVER_ERROR = false
 
+// TRACER, when set, observes the interpreter running the script.
+var TRACER interpreter.Tracer = nil
+
+// ENGINE runs scripts: "tree" walks the AST, "vm" compiles it to bytecode.
+var ENGINE = "tree"
+
+// OPTIMIZE folds constants and removes dead branches before running scripts.
+// Traced scripts are never optimized, to report on the code as written.
+var OPTIMIZE = true
+
 func main() {
 	arguments := os.Args[1:]
 
-	if len(arguments) > 1 {
-		fmt.Println("Usage: lox-tw [script]")
+	if len(arguments) > 0 {
+		switch arguments[0] {
+		case "fmt":
+			os.Exit(runFormat(arguments[1:]))
+		case "ast":
+			os.Exit(runAst(arguments[1:]))
+		case "lsp":
+			os.Exit(runLsp(arguments[1:]))
+		case "debug":
+			os.Exit(runDebug(arguments[1:]))
+		case "test":
+			os.Exit(runTest(arguments[1:]))
+		case "bench":
+			os.Exit(runBench(arguments[1:]))
+		case "check":
+			os.Exit(runCheck(arguments[1:]))
+		}
+	}
+
+	flags := flag.NewFlagSet("lox-tw", flag.ExitOnError)
+	profile := flags.String("profile", "", "profile the script, writing folded stacks for flame graphs to `file`")
+	coverage := flags.String("coverage", "", "write the coverage of the script to `file` in LCOV format, and as HTML next to it")
+	flags.StringVar(&ENGINE, "engine", "tree", "run scripts with the tree-walker (tree) or the bytecode VM (vm)")
+	noOpt := flags.Bool("no-opt", false, "run scripts without folding constants and removing dead branches")
+	flags.Parse(arguments)
+	arguments = flags.Args()
+	OPTIMIZE = !*noOpt
+
+	traced := *profile != "" || *coverage != ""
+	invalidEngine := ENGINE != "tree" && ENGINE != "vm" || (ENGINE == "vm" && traced)
+	if len(arguments) > 1 || (traced && len(arguments) == 0) || (*profile != "" && *coverage != "") || invalidEngine {
+		fmt.Println("Usage: lox-tw [--engine tree|vm] [--no-opt] [script]")
+		fmt.Println("       lox-tw [--profile file | --coverage file] script")
+		fmt.Println("       lox-tw fmt [--check | --write] files...")
+		fmt.Println("       lox-tw ast [--json] file")
+		fmt.Println("       lox-tw lsp")
+		fmt.Println("       lox-tw debug [--dap | file]")
+		fmt.Println("       lox-tw test [--interpreter executable] [-j jobs] paths...")
+		fmt.Println("       lox-tw bench [--json] [--baseline file] [files...]")
+		fmt.Println("       lox-tw check [--types] files...")
+		os.Exit(64)
+	}
+
+	if chapter := os.Getenv("CHAPTER"); ENGINE == "vm" && !vmRunsChapter(chapter) {
+		fmt.Fprintf(os.Stderr, "CHAPTER=%s is only supported by the tree-walker.\n", chapter)
 		os.Exit(64)
+	}
+
+	if *profile != "" {
+		os.Exit(runProfile(arguments[0], *profile))
+	} else if *coverage != "" {
+		os.Exit(runCoverage(arguments[0], *coverage))
 	} else if len(arguments) == 1 {
 		runFile(arguments[0])
 	} else {
@@ -36,21 +102,117 @@ func runFile(path string) {
 		os.Exit(66)
 	}
 
-	err = run(string(content))
+	os.Exit(runSource(string(content)))
+}
+
+// runSource runs a script and returns the exit code.
+func runSource(source string) int {
+	err := run(source)
 	switch err.(type) {
 	case *scanner.ScannerError:
-		os.Exit(65)
+		return 65
 	case *parser.ParserError:
-		os.Exit(65)
+		return 65
 	}
 
 	if RESOLVER_ERROR {
-		os.Exit(65)
+		return 65
 	}
 
 	if RUNTIME_ERROR {
-		os.Exit(70)
+		return 70
+	}
+
+	return 0
+}
+
+// runProfile runs a script under the profiler, prints the report to stderr
+// and writes the folded stacks to output.
+func runProfile(path string, output string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		return 66
+	}
+
+	codeProfiler := profiler.NewProfiler()
+	TRACER = codeProfiler
+	status := runSource(string(content))
+	codeProfiler.Finish()
+
+	codeProfiler.WriteReport(os.Stderr, string(content))
+
+	file, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		return 74
+	}
+	defer file.Close()
+
+	if err := codeProfiler.WriteFolded(file); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		return 74
+	}
+
+	return status
+}
+
+// runCoverage runs a script while recording its coverage, prints a summary
+// to stderr and writes the LCOV and HTML reports.
+func runCoverage(path string, output string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		return 66
+	}
+	source := string(content)
+
+	// Parsed once here to know every line and branch upfront, run parses the
+	// script again into identical nodes.
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 65
+	}
+	stmts, err := parser.ParseTokensToStmts(tokens)
+	if err != nil {
+		return 65
 	}
+
+	codeCoverage := coverage.NewCoverage(stmts)
+	TRACER = codeCoverage
+	status := runSource(source)
+
+	fmt.Fprintln(os.Stderr, codeCoverage.Summary())
+
+	htmlOutput := strings.TrimSuffix(output, filepath.Ext(output)) + ".html"
+	err = writeFile(output, func(file *os.File) error {
+		return codeCoverage.WriteLCOV(file, path)
+	})
+	if err == nil {
+		err = writeFile(htmlOutput, func(file *os.File) error {
+			return codeCoverage.WriteHTML(file, path, source)
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		return 74
+	}
+
+	return status
+}
+
+func writeFile(path string, write func(file *os.File) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
 func runPrompt() {
@@ -76,8 +238,16 @@ func runPrompt() {
 
 func run(source string) error {
 	os.Setenv("METACLASSES_ENABLED", "false")
+	os.Setenv("INNER_ENABLED", "false")
 
 	chapter := os.Getenv("CHAPTER")
+	if ENGINE == "vm" {
+		if chapter == "12extra" {
+			os.Setenv("METACLASSES_ENABLED", "true")
+		}
+		return vm_run(source)
+	}
+
 	switch chapter {
 	case "4":
 		chapter_4_run(source)
@@ -99,6 +269,8 @@ func run(source string) error {
 		return chapter_12_extra(source)
 	case "13":
 		return chapter_13_run(source)
+	case "13extra":
+		return chapter_13_extra(source)
 	default:
 		return chapter_13_run(source)
 	}
@@ -160,7 +332,7 @@ func chapter_8_run(source string) error {
 		}
 	}
 
-	codeInterpreter := interpreter.NewInterpreter(codeResolver.ExprToDepth)
+	codeInterpreter := interpreter.NewInterpreter()
 	for _, stmt := range stmts {
 		err := stmt.Accept(codeInterpreter)
 		if err != nil {
@@ -193,7 +365,7 @@ func chapter_9_run(source string) error {
 		}
 	}
 
-	codeInterpreter := interpreter.NewInterpreter(codeResolver.ExprToDepth)
+	codeInterpreter := interpreter.NewInterpreter()
 	for _, stmt := range stmts {
 		err := stmt.Accept(codeInterpreter)
 		if _, ok := err.(*interpreter.BreakError); ok {
@@ -237,9 +409,16 @@ func chapter_11_run(source string) error {
 		return nil
 	}
 
-	codeInterpreter := interpreter.NewInterpreter(codeResolver.ExprToDepth)
+	if OPTIMIZE && TRACER == nil {
+		stmts = optimizer.Optimize(stmts)
+	}
+
+	codeInterpreter := interpreter.NewInterpreter()
+	if TRACER != nil {
+		codeInterpreter.SetTracer(TRACER)
+	}
 	for _, stmt := range stmts {
-		err := stmt.Accept(codeInterpreter)
+		err := codeInterpreter.Execute(stmt)
 		if _, ok := err.(*interpreter.BreakError); ok {
 			continue
 		}
@@ -252,6 +431,69 @@ func chapter_11_run(source string) error {
 	return nil
 }
 
+// vmRunsChapter reports whether the bytecode VM runs the language of a
+// chapter. It runs the complete language, from chapter 11 on, with the
+// metaclasses of chapter 12 but not the superclass-first methods and inner
+// calls of chapter 13.
+func vmRunsChapter(chapter string) bool {
+	switch chapter {
+	case "", "11", "12", "12extra", "13":
+		return true
+	}
+	return false
+}
+
+// vm_run runs a script like chapter_11_run, on the bytecode VM.
+func vm_run(source string) error {
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return err
+	}
+
+	stmts, err := parser.ParseTokensToStmts(tokens)
+	if err != nil {
+		return err
+	}
+
+	codeResolver := resolver.NewResolver()
+	for _, stmt := range stmts {
+		err := stmt.Accept(codeResolver)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			RESOLVER_ERROR = true
+		}
+	}
+
+	if RESOLVER_ERROR {
+		return nil
+	}
+
+	if OPTIMIZE {
+		stmts = optimizer.Optimize(stmts)
+	}
+
+	scripts, errors := vm.Compile(stmts)
+	for _, err := range errors {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		RESOLVER_ERROR = true
+	}
+
+	if RESOLVER_ERROR {
+		return nil
+	}
+
+	machine := vm.NewVM()
+	for _, script := range scripts {
+		if err := machine.Run(script); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			RUNTIME_ERROR = true
+		}
+	}
+
+	return nil
+}
+
 func chapter_12_run(source string) error {
 	return chapter_11_run(source)
 }
@@ -264,3 +506,8 @@ func chapter_12_extra(source string) error {
 func chapter_13_run(source string) error {
 	return chapter_12_run(source)
 }
+
+func chapter_13_extra(source string) error {
+	os.Setenv("INNER_ENABLED", "true")
+	return chapter_13_run(source)
+}
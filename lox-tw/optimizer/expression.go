@@ -0,0 +1,174 @@
+package optimizer
+
+import (
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+func optimizeExpr(expr ast.Expr[any]) ast.Expr[any] {
+	switch e := expr.(type) {
+	case ast.GroupingExpr[any]:
+		e.Expression = optimizeExpr(e.Expression)
+		if _, ok := constant(e.Expression); ok {
+			return e.Expression
+		}
+		return e
+	case ast.TernaryExpr[any]:
+		return optimizeTernary(e)
+	case ast.BinaryExpr[any]:
+		return optimizeBinary(e)
+	case ast.UnaryExpr[any]:
+		return optimizeUnary(e)
+	case ast.LogicalExpr[any]:
+		return optimizeLogical(e)
+	case ast.CallExpr[any]:
+		e.Callee = optimizeExpr(e.Callee)
+		e.Arguments = optimizeExprs(e.Arguments)
+		return e
+	case ast.InnerExpr[any]:
+		e.Arguments = optimizeExprs(e.Arguments)
+		return e
+	case ast.GetExpr[any]:
+		e.Object = optimizeExpr(e.Object)
+		return e
+	case ast.SetExpr[any]:
+		e.Object = optimizeExpr(e.Object)
+		e.Value = optimizeExpr(e.Value)
+		return e
+	case ast.AssignExpr[any]:
+		e.Value = optimizeExpr(e.Value)
+		return e
+	case ast.LambdaExpr[any]:
+		e.Defaults = optimizeExprs(e.Defaults)
+		e.Body = Optimize(e.Body)
+		return e
+	}
+
+	return expr
+}
+
+func optimizeExprs(exprs []ast.Expr[any]) []ast.Expr[any] {
+	optimized := make([]ast.Expr[any], len(exprs))
+	for i, expr := range exprs {
+		optimized[i] = optimizeExpr(expr)
+	}
+
+	return optimized
+}
+
+// literal replaces a folded expression, at the position of its first token.
+func literal(expr ast.Expr[any], value any) ast.Expr[any] {
+	return ast.LiteralExpr[any]{Token: ast.FirstExprToken(expr), Value: value}
+}
+
+func optimizeTernary(expr ast.TernaryExpr[any]) ast.Expr[any] {
+	expr.Condition = optimizeExpr(expr.Condition)
+	expr.TrueExpr = optimizeExpr(expr.TrueExpr)
+	expr.FalseExpr = optimizeExpr(expr.FalseExpr)
+
+	// Like the interpreter, only false itself picks the false branch.
+	if value, ok := constant(expr.Condition); ok {
+		if value == false {
+			return expr.FalseExpr
+		}
+		return expr.TrueExpr
+	}
+
+	return expr
+}
+
+func optimizeLogical(expr ast.LogicalExpr[any]) ast.Expr[any] {
+	expr.Left = optimizeExpr(expr.Left)
+	expr.Right = optimizeExpr(expr.Right)
+
+	truthy, ok := isTruthy(expr.Left)
+	if !ok {
+		return expr
+	}
+
+	if (expr.Operator.Type == token.OR) == truthy {
+		return expr.Left
+	}
+	return expr.Right
+}
+
+func optimizeUnary(expr ast.UnaryExpr[any]) ast.Expr[any] {
+	expr.Right = optimizeExpr(expr.Right)
+
+	value, ok := constant(expr.Right)
+	if !ok {
+		return expr
+	}
+
+	switch expr.Operator.Type {
+	case token.MINUS:
+		if number, ok := value.(float64); ok {
+			return literal(expr, -number)
+		}
+	case token.BANG:
+		truthy, _ := isTruthy(expr.Right)
+		return literal(expr, !truthy)
+	}
+
+	return expr
+}
+
+func optimizeBinary(expr ast.BinaryExpr[any]) ast.Expr[any] {
+	expr.Left = optimizeExpr(expr.Left)
+	expr.Right = optimizeExpr(expr.Right)
+
+	leftValue, ok := constant(expr.Left)
+	if !ok {
+		return expr
+	}
+
+	// A literal on the left of a comma has no effect.
+	if expr.Operator.Type == token.COMMA {
+		return expr.Right
+	}
+
+	rightValue, ok := constant(expr.Right)
+	if !ok {
+		return expr
+	}
+
+	switch expr.Operator.Type {
+	case token.EQUAL_EQUAL:
+		return literal(expr, leftValue == rightValue)
+	case token.BANG_EQUAL:
+		return literal(expr, leftValue != rightValue)
+	case token.PLUS:
+		left, ok := leftValue.(string)
+		right, ok2 := rightValue.(string)
+		if ok && ok2 {
+			return literal(expr, left+right)
+		}
+	}
+
+	left, ok := leftValue.(float64)
+	right, ok2 := rightValue.(float64)
+	if !ok || !ok2 {
+		return expr
+	}
+
+	switch expr.Operator.Type {
+	case token.PLUS:
+		return literal(expr, left+right)
+	case token.MINUS:
+		return literal(expr, left-right)
+	case token.STAR:
+		return literal(expr, left*right)
+	case token.SLASH:
+		return literal(expr, left/right)
+	case token.GREATER:
+		return literal(expr, left > right)
+	case token.GREATER_EQUAL:
+		return literal(expr, left >= right)
+	case token.LESS:
+		return literal(expr, left < right)
+	case token.LESS_EQUAL:
+		return literal(expr, left <= right)
+	}
+
+	return expr
+}
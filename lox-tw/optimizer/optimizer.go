@@ -0,0 +1,43 @@
+package optimizer
+
+import (
+	"lox-tw/ast"
+	"lox-tw/utils"
+)
+
+// Optimize rewrites resolved statements into simpler ones that behave the
+// same: operations on literals are folded into their result and branches
+// that can never run are removed. Operations that would fail, such as
+// -"a", are kept for the runtime error to be raised when they run.
+//
+// Blocks are never removed or added around existing statements, so the
+// bindings computed by the resolver stay valid.
+func Optimize(stmts []ast.Stmt[any]) []ast.Stmt[any] {
+	optimized := make([]ast.Stmt[any], 0, len(stmts))
+	for _, stmt := range stmts {
+		if stmt = optimizeStmt(stmt); stmt != nil {
+			optimized = append(optimized, stmt)
+		}
+	}
+
+	return optimized
+}
+
+// constant returns the value of an expression known before running it.
+func constant(expr ast.Expr[any]) (any, bool) {
+	literal, ok := expr.(ast.LiteralExpr[any])
+	if !ok {
+		return nil, false
+	}
+
+	return literal.Value, true
+}
+
+func isTruthy(expr ast.Expr[any]) (truthy bool, known bool) {
+	value, ok := constant(expr)
+	if !ok {
+		return false, false
+	}
+
+	return utils.IsTruthy(value), true
+}
@@ -0,0 +1,108 @@
+package optimizer
+
+import (
+	"strings"
+	"testing"
+
+	"lox-tw/ast"
+	"lox-tw/internal/testutil"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		// Folding
+		{"print 2 * 3 + 1;", "(print 7.0)"},
+		{"print (1 + 2) / 4 - -1;", "(print 1.75)"},
+		{`print "a" + "b" + "c";`, "(print abc)"},
+		{"print 1 < 2 == true;", "(print true)"},
+		{`print nil != false; print "a" == "a";`, "(print true) (print true)"},
+		{`print !nil; print !"";`, "(print true) (print false)"},
+		{"print (1, 2);", "(print 2.0)"},
+		{"var a = 1; print a + 2 * 3;", "(define a 1.0) (print (+ (var a) 6.0))"},
+
+		// Literal conditions
+		{`print nil or "x"; print 1 and 2; print false and a;`, "(print x) (print 2.0) (print false)"},
+		{"print nil ? 1 : 2; print false ? 1 : 2;", "(print 1.0) (print 2.0)"},
+		{"if (1 > 2) print 1; else print 2;", "(print 2.0)"},
+		{"if (nil) print 1; print 2;", "(print 2.0)"},
+		{"while (false) print 1;", ""},
+		{"var a; if (a) if (false) print 1;", "(define a) (if (var a) (block))"},
+		{"while (true) { if (true) break; }", "(while true (block (break)))"},
+
+		// Operations that fail at runtime are kept
+		{`print -"a";`, "(print (- a))"},
+		{`print 1 + "a"; print "a" < "b";`, "(print (+ 1.0 a)) (print (< a b))"},
+		{`print true or -"a"; print -"a" or true;`, "(print true) (print (or (- a) true))"},
+
+		// Inside functions and classes
+		{"fun f() { return 1 + 1; }", "(fun f () (return 2.0))"},
+		{"class A { m() { return 2 * 2; } }", "(class A (fun m () (return 4.0)))"},
+	}
+
+	for _, test := range tests {
+		var printed []string
+		for _, stmt := range Optimize(testutil.Parse(t, test.source)) {
+			printed = append(printed, ast.AnyPrinter{}.Print(stmt))
+		}
+
+		if result := strings.Join(printed, " "); result != test.expected {
+			t.Errorf("Optimize(%q) = %q, expected %q", test.source, result, test.expected)
+		}
+	}
+}
+
+func TestBehaviorUnchanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"Arithmetic", `print 1 + 2 * 3 - 4 / 8; print -(3); print 0/0 == 0/0; print 1/0; print -1/0 < 0;`},
+		{"Strings", `print "a" + "b"; print "a" == "a"; print "" == nil;`},
+		{"Truthiness", `print !nil; print !0; print nil or "x"; print false and 1; print 1 and 2;`},
+		{"Ternary", `print nil ? "yes" : "no"; print false ? "yes" : "no"; print 0 ? "yes" : "no";`},
+		{"Dead branches", `
+if (false) print "then"; else print "else";
+if (nil) { print "never"; }
+while (false) print "never";
+for (var i = 0; false; i = i + 1) print i;
+print "done";`},
+		{"Scopes around dead branches", `
+var a = "global";
+{
+    var b = "block";
+    if (true) { var a = "inner"; print a + b; }
+    if (false) { var c = "dead"; }
+    fun f() { return a + b; }
+    print f();
+}`},
+		{"Runtime errors", `
+print "before";
+print -"a";
+print 1 + "a";
+print "a" < "b";
+print 1 + nil;
+print true and -"a";
+print nil ? -"a" : 1;
+print false ? 1 : -"a";
+if (true) print -nil;
+print "after";`},
+		{"Errors after side effects", `
+var i = 0;
+fun f() { i = i + 1; return i; }
+print (f(), 1 + 2);
+print f() + "a";
+print i;`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := testutil.RunStmts(testutil.Parse(t, test.source))
+			if actual := testutil.RunStmts(Optimize(testutil.Parse(t, test.source))); actual != expected {
+				t.Errorf("Expected the output without optimizations:\n%s\nGot:\n%s", expected, actual)
+			}
+		})
+	}
+}
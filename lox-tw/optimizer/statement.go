@@ -0,0 +1,102 @@
+package optimizer
+
+import (
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+// optimizeStmt returns nil for statements that would do nothing.
+func optimizeStmt(stmt ast.Stmt[any]) ast.Stmt[any] {
+	switch s := stmt.(type) {
+	case ast.VarStmt[any]:
+		s.Initializer = optimizeExpr(s.Initializer)
+		return s
+	case ast.ExpressionStmt[any]:
+		s.Expression = optimizeExpr(s.Expression)
+		return s
+	case ast.IfStmt[any]:
+		return optimizeIf(s)
+	case ast.WhileStmt[any]:
+		s.Condition = optimizeExpr(s.Condition)
+		if truthy, ok := isTruthy(s.Condition); ok && !truthy {
+			return nil
+		}
+		s.Body = optimizeBody(s.Body, s.Keyword)
+		return s
+	case ast.ForInStmt[any]:
+		s.Iterable = optimizeExpr(s.Iterable)
+		s.Body = optimizeBody(s.Body, s.Keyword)
+		return s
+	case ast.PrintStmt[any]:
+		s.Expression = optimizeExpr(s.Expression)
+		return s
+	case ast.ClassStmt[any]:
+		s.Methods = optimizeFunctions(s.Methods)
+		s.GlobalMethods = optimizeFunctions(s.GlobalMethods)
+		s.Getters = optimizeFunctions(s.Getters)
+		s.Setters = optimizeFunctions(s.Setters)
+		return s
+	case ast.TraitStmt[any]:
+		s.Methods = optimizeFunctions(s.Methods)
+		return s
+	case ast.BlockStmt[any]:
+		s.Statements = Optimize(s.Statements)
+		return s
+	case ast.FunctionStmt[any]:
+		s.Defaults = optimizeExprs(s.Defaults)
+		s.Body = Optimize(s.Body)
+		return s
+	case ast.ReturnStmt[any]:
+		s.Value = optimizeExpr(s.Value)
+		return s
+	case ast.AssertStmt[any]:
+		s.Condition = optimizeExpr(s.Condition)
+		if s.Message != nil {
+			s.Message = optimizeExpr(s.Message)
+		}
+		return s
+	}
+
+	return stmt
+}
+
+func optimizeIf(stmt ast.IfStmt[any]) ast.Stmt[any] {
+	stmt.Condition = optimizeExpr(stmt.Condition)
+
+	truthy, ok := isTruthy(stmt.Condition)
+	if !ok {
+		stmt.ThenBranch = optimizeBody(stmt.ThenBranch, stmt.Keyword)
+		if stmt.ElseBranch != nil {
+			stmt.ElseBranch = optimizeBody(stmt.ElseBranch, stmt.Keyword)
+		}
+		return stmt
+	}
+
+	if truthy {
+		return optimizeStmt(stmt.ThenBranch)
+	} else if stmt.ElseBranch != nil {
+		return optimizeStmt(stmt.ElseBranch)
+	}
+	return nil
+}
+
+// optimizeBody optimizes a statement that must remain, such as the body of a
+// loop, with an empty block in place of a statement doing nothing.
+func optimizeBody(stmt ast.Stmt[any], keyword token.Token) ast.Stmt[any] {
+	if optimized := optimizeStmt(stmt); optimized != nil {
+		return optimized
+	}
+
+	return ast.BlockStmt[any]{LeftBrace: keyword, RightBrace: keyword}
+}
+
+func optimizeFunctions(functions []ast.FunctionStmt[any]) []ast.FunctionStmt[any] {
+	optimized := make([]ast.FunctionStmt[any], len(functions))
+	for i, function := range functions {
+		function.Defaults = optimizeExprs(function.Defaults)
+		function.Body = Optimize(function.Body)
+		optimized[i] = function
+	}
+
+	return optimized
+}
@@ -40,7 +40,7 @@ func parseAssign(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 		}
 	}
 
-	return ast.AssignExpr[any]{Name: v.Name, Value: assign}, endAssign, nil
+	return ast.AssignExpr[any]{Name: v.Name, Value: assign, Binding: ast.NewBinding()}, endAssign, nil
 }
 
 func parseTernary(tokens []token.Token, start int) (ast.Expr[any], int, error) {
@@ -66,7 +66,7 @@ func parseTernary(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 		return falseExpr, endSecondExpression, err
 	}
 
-	return ast.TernaryExpr[any]{Condition: orExpr, TrueExpr: trueExpr, FalseExpr: falseExpr}, endSecondExpression, nil
+	return ast.TernaryExpr[any]{Condition: orExpr, Question: tokens[endOr], TrueExpr: trueExpr, FalseExpr: falseExpr}, endSecondExpression, nil
 }
 
 func parseOr(tokens []token.Token, start int) (ast.Expr[any], int, error) {
@@ -126,7 +126,7 @@ func parseCall(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 					Message: "Expect property name after '.'.",
 				}
 			}
-			callee = ast.GetExpr[any]{Object: callee, Name: tokens[pos+1]}
+			callee = ast.GetExpr[any]{Object: callee, Name: tokens[pos+1], Cache: ast.NewPropertyCache()}
 			end = pos + 2
 		} else {
 			break
@@ -140,13 +140,13 @@ func parseCall(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 func parsePrimary(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 	switch tokens[start].Type {
 	case token.NUMBER, token.STRING, token.NIL:
-		return ast.LiteralExpr[any]{Value: tokens[start].Literal}, start + 1, nil
+		return ast.LiteralExpr[any]{Token: tokens[start], Value: tokens[start].Literal}, start + 1, nil
 	case token.TRUE:
-		return ast.LiteralExpr[any]{Value: true}, start + 1, nil
+		return ast.LiteralExpr[any]{Token: tokens[start], Value: true}, start + 1, nil
 	case token.FALSE:
-		return ast.LiteralExpr[any]{Value: false}, start + 1, nil
+		return ast.LiteralExpr[any]{Token: tokens[start], Value: false}, start + 1, nil
 	case token.THIS:
-		return ast.ThisExpr[any]{Keyword: tokens[start]}, start + 1, nil
+		return ast.ThisExpr[any]{Keyword: tokens[start], Binding: ast.NewBinding()}, start + 1, nil
 	case token.LEFT_PAREN:
 		expr, end, err := parseExpression(tokens, start+1)
 		if err != nil {
@@ -162,7 +162,10 @@ func parsePrimary(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 
 		return ast.GroupingExpr[any]{Expression: expr}, end + 1, nil
 	case token.IDENTIFIER:
-		return ast.VarExpr[any]{Name: tokens[start]}, start + 1, nil
+		if tokens[start].Lexeme == "inner" && os.Getenv("INNER_ENABLED") == "true" {
+			return parseInner(tokens, start)
+		}
+		return ast.VarExpr[any]{Name: tokens[start], Binding: ast.NewBinding()}, start + 1, nil
 	case token.SUPER:
 		if tokens[start+1].Type != token.DOT {
 			return nil, start + 1, &ParserError{
@@ -176,25 +179,74 @@ func parsePrimary(tokens []token.Token, start int) (ast.Expr[any], int, error) {
 				Message: "Expect superclass method name.",
 			}
 		}
-		return ast.SuperExpr[any]{Keyword: tokens[start], Method: tokens[start+2]}, start + 3, nil
+		return ast.SuperExpr[any]{Keyword: tokens[start], Method: tokens[start+2], Binding: ast.NewBinding()}, start + 3, nil
 	default:
 		if tokens[start].Type == token.FUN && tokens[start+1].Type != token.IDENTIFIER {
-			parameters, body, end, err := parseFunctionHelper("lambda", tokens, start+1)
+			function, end, err := parseFunctionHelper("lambda", tokens, start+1)
 			if err != nil {
 				return nil, end, err
 			}
 
-			return ast.LambdaExpr[any]{Parameters: parameters, Body: body}, end, nil
+			return ast.LambdaExpr[any]{
+				Keyword:        tokens[start],
+				Parameters:     function.Parameters,
+				ParameterTypes: function.ParameterTypes,
+				ReturnType:     function.ReturnType,
+				Defaults:       function.Defaults,
+				Ellipsis:       function.Ellipsis,
+				Body:           function.Body,
+				RightBrace:     function.RightBrace,
+				Generator:      function.Generator,
+			}, end, nil
 		}
-		return ast.LiteralExpr[any]{Value: tokens[start].Literal}, start, &ParserError{
+		return ast.LiteralExpr[any]{Token: tokens[start], Value: tokens[start].Literal}, start, &ParserError{
 			Token:   tokens[start],
 			Message: "Expect expression.",
 		}
 	}
 }
 
+// parseInner parses a call to the refinement of a method in a subclass,
+// inner(arguments), when inner calls are enabled.
+func parseInner(tokens []token.Token, start int) (ast.Expr[any], int, error) {
+	if tokens[start+1].Type != token.LEFT_PAREN {
+		return nil, start + 1, &ParserError{
+			Token:   tokens[start+1],
+			Message: "Expect '(' after 'inner'.",
+		}
+	}
+
+	expr, end, err := finishCall(nil, tokens, start+2)
+	if err != nil {
+		return nil, end, err
+	}
+
+	call := expr.(ast.CallExpr[any])
+	for i := range call.Arguments {
+		if name := call.Names[i]; name != nil {
+			return nil, end, &ParserError{
+				Token:   *name,
+				Message: "Inner calls can't have named arguments.",
+			}
+		}
+		if spread := call.Spreads[i]; spread != nil {
+			return nil, end, &ParserError{
+				Token:   *spread,
+				Message: "Inner calls can't have spread arguments.",
+			}
+		}
+	}
+
+	return ast.InnerExpr[any]{Keyword: tokens[start], Parenthesis: call.Parenthesis, Arguments: call.Arguments, Binding: ast.NewBinding()}, end, nil
+}
+
+// finishCall parses the arguments of a call, positional ones first, which
+// may spread lists: ...list, then named ones: name: value.
 func finishCall(callee ast.Expr[any], tokens []token.Token, start int) (ast.Expr[any], int, error) {
 	arguments := []ast.Expr[any]{}
+	names := []*token.Token{}
+	spreads := []*token.Token{}
+	named := make(map[string]bool)
 	pos := start
 	if tokens[pos].Type != token.RIGHT_PAREN {
 		for {
@@ -205,12 +257,38 @@ func finishCall(callee ast.Expr[any], tokens []token.Token, start int) (ast.Expr
 				}
 			}
 
+			var name *token.Token
+			if tokens[pos].Type == token.IDENTIFIER && tokens[pos+1].Type == token.COLON {
+				name = &tokens[pos]
+				if named[name.Lexeme] {
+					return nil, pos, &ParserError{
+						Token:   *name,
+						Message: "Argument '" + name.Lexeme + "' is passed twice.",
+					}
+				}
+				named[name.Lexeme] = true
+				pos += 2
+			} else if len(named) > 0 {
+				return nil, pos, &ParserError{
+					Token:   tokens[pos],
+					Message: "Positional arguments can't follow named arguments.",
+				}
+			}
+
+			var spread *token.Token
+			if name == nil && tokens[pos].Type == token.ELLIPSIS {
+				spread = &tokens[pos]
+				pos += 1
+			}
+
 			arg, end, err := parseAssign(tokens, pos)
 			if err != nil {
 				return arg, end, err
 			}
 
 			arguments = append(arguments, arg)
+			names = append(names, name)
+			spreads = append(spreads, spread)
 			pos = end
 
 			if tokens[pos].Type != token.COMMA {
@@ -227,7 +305,7 @@ func finishCall(callee ast.Expr[any], tokens []token.Token, start int) (ast.Expr
 		}
 	}
 
-	return ast.CallExpr[any]{Callee: callee, Parenthesis: tokens[pos], Arguments: arguments}, pos + 1, nil
+	return ast.CallExpr[any]{Callee: callee, Parenthesis: tokens[pos], Arguments: arguments, Names: names, Spreads: spreads}, pos + 1, nil
 }
 
 func parseLeftAssociativeRule(
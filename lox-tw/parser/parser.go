@@ -14,10 +14,24 @@ func ParseTokensToExpression(tokens []token.Token) (ast.Expr[any], error) {
 }
 
 func ParseTokensToStmts(tokens []token.Token) ([]ast.Stmt[any], error) {
+	statements, errors := ParseTokensToStmtsWithErrors(tokens)
+
+	var finalError error
+	for _, err := range errors {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		finalError = err
+	}
+
+	return statements, finalError
+}
+
+// ParseTokensToStmtsWithErrors parses as many statements as possible and
+// returns every error found on the way.
+func ParseTokensToStmtsWithErrors(tokens []token.Token) ([]ast.Stmt[any], []error) {
 	var statements []ast.Stmt[any]
+	var errors []error
 	pos := 0
 
-	var finalError error
 	for pos < len(tokens) && tokens[pos].Type != token.EOF {
 		stmt, end, err := parseDeclaration(tokens, pos)
 		if end == pos {
@@ -26,13 +40,12 @@ func ParseTokensToStmts(tokens []token.Token) ([]ast.Stmt[any], error) {
 		pos = end
 
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			finalError = err
+			errors = append(errors, err)
 			continue
 		}
 
 		statements = append(statements, stmt)
 	}
 
-	return statements, finalError
+	return statements, errors
 }
@@ -43,3 +43,99 @@ func TestParser(t *testing.T) {
 		}
 	}
 }
+
+func TestStmtPrinter(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var a = 1;", "(define a 1.0)"},
+		{"var a;", "(define a)"},
+		{"a = 1 + 2;", "(; (a = (+ 1.0 2.0)))"},
+		{"print a;", "(print (var a))"},
+		{"if (a) print 1; else print 2;", "(if-else (var a) (print 1.0) (print 2.0))"},
+		{"while (a) { break; }", "(while (var a) (block (break)))"},
+		{"for (var i = 0; i < 1; i = i + 1) print i;", "(block (define i 0.0) (while (< (var i) 1.0) (block (print (var i)) (; (i = (+ (var i) 1.0))))))"},
+		{"fun f(a, b) { return a; }", "(fun f (a b) (return (var a)))"},
+		{"var f = fun (a) { return; };", "(define f (lambda (a) (return)))"},
+		{"class A < B { init() { super.init(); } class make() {} }", "(class A < B (fun init () (; (call (super init) ()))) (class (fun make ())))"},
+		{"class A { size { return 1; } size=(value) {} }", "(class A (get (fun size () (return 1.0))) (set (fun size (value))))"},
+		{"trait T { m() {} } ", "(trait T (fun m ()))"},
+		{"class A < B with T, U {}", "(class A < B (with T U))"},
+		{"var a: Number = 1;", "(define a:Number 1.0)"},
+		{"fun f(a: String, b): Bool { return true; }", "(fun f (a:String b):Bool (return true))"},
+		{"class A { size: Number { return 1; } size=(value: Number) {} }", "(class A (get (fun size ():Number (return 1.0))) (set (fun size (value:Number))))"},
+		{"fun f(a, b: Number = 1 + 2) {}", "(fun f (a b:Number=(+ 1.0 2.0)))"},
+		{"f(1, b: 2);", "(; (call (var f) (1.0 b:2.0)))"},
+		{"fun f(a, ...rest: Number) {}", "(fun f (a ...rest:Number))"},
+		{"f(1, ...xs);", "(; (call (var f) (1.0 ...(var xs))))"},
+		{"fun f() { yield 1; yield; }", "(fun f () (yield 1.0) (yield))"},
+		{"for (var x in xs) print x;", "(for-in x (var xs) (print (var x)))"},
+		{"for (var i: Number in range(3)) {}", "(for-in i:Number (call (var range) (3.0)) (block))"},
+		{"var in = 1; for (var i = in; i < 2; i = i + 1) {}", "(define in 1.0)"},
+		{"assert a;", "(assert (var a))"},
+		{"assert a == 1, b;", "(assert (== (var a) 1.0) (var b))"},
+	}
+
+	for _, test := range tests {
+		tokens, _ := scanner.ScanTokens(test.input)
+		stmts, _ := ParseTokensToStmts(tokens)
+		result := ast.AnyPrinter{}.Print(stmts[0])
+		if result != test.expected {
+			t.Errorf("Expected '%s', got '%s'", test.expected, result)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	source := `
+class A < B {
+    init(a) { this.a = a; }
+    class make() { return A(1); }
+    get() { return super.get() + this.a; }
+}
+fun f(n) {
+    if (n < 2) return n; else return f(n - 1, "two") or nil;
+}
+for (var i = 0; i < 3; i = i + 1) print i ? -i : !true;
+var g = fun (a) { while (true) break; };
+var h: Number = 1;
+fun typed(a: A, b): String {}
+fun defaults(a, b = a + 1) {}
+defaults(b: 2, a: 1);
+fun variadic(a, ...rest) {}
+variadic(...list(1, 2), 3);
+fun generator() { yield 1; }
+for (var x: Number in range(3)) { if (x > 1) break; }
+`
+	tokens, _ := scanner.ScanTokens(source)
+	stmts, err := ParseTokensToStmts(tokens)
+	if err != nil {
+		t.Fatalf("Error parsing source: %v", err)
+	}
+
+	data, err := ast.ToJSON(stmts)
+	if err != nil {
+		t.Fatalf("Error serializing statements: %v", err)
+	}
+
+	decoded, err := ast.FromJSON(data)
+	if err != nil {
+		t.Fatalf("Error deserializing statements: %v", err)
+	}
+
+	if len(decoded) != len(stmts) {
+		t.Fatalf("Expected %d statements, got %d", len(stmts), len(decoded))
+	}
+	for i := range stmts {
+		expected, result := ast.AnyPrinter{}.Print(stmts[i]), ast.AnyPrinter{}.Print(decoded[i])
+		if result != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, result)
+		}
+	}
+
+	again, _ := ast.ToJSON(decoded)
+	if string(again) != string(data) {
+		t.Errorf("Serializing the deserialized statements gave a different document")
+	}
+}
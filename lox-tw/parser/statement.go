@@ -19,6 +19,8 @@ func parseDeclarationInner(tokens []token.Token, start int, depth int) (ast.Stmt
 		stmt, end, err = parseClassDeclaration(tokens, start+1)
 	} else if tokens[start].Type == token.FUN && tokens[start+1].Type == token.IDENTIFIER {
 		stmt, end, err = parseFunctionDeclaration("function", tokens, start+1)
+	} else if isContextualKeyword(tokens[start], "trait") && tokens[start+1].Type == token.IDENTIFIER {
+		stmt, end, err = parseTraitDeclaration(tokens, start+1)
 	} else {
 		stmt, end, err = parseStatement(tokens, start, depth)
 	}
@@ -53,10 +55,29 @@ func parseClassDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int,
 			}
 		}
 
-		superclass = &ast.VarExpr[any]{Name: tokens[pos]}
+		superclass = &ast.VarExpr[any]{Name: tokens[pos], Binding: ast.NewBinding()}
 		pos += 1
 	}
 
+	var traits []ast.VarExpr[any]
+	if isContextualKeyword(tokens[pos], "with") {
+		for {
+			pos += 1
+			if tokens[pos].Type != token.IDENTIFIER {
+				return nil, pos, &ParserError{
+					Token:   tokens[pos],
+					Message: "Expect trait name.",
+				}
+			}
+
+			traits = append(traits, ast.VarExpr[any]{Name: tokens[pos], Binding: ast.NewBinding()})
+			pos += 1
+			if tokens[pos].Type != token.COMMA {
+				break
+			}
+		}
+	}
+
 	if tokens[pos].Type != token.LEFT_BRACE {
 		return nil, pos, &ParserError{
 			Token:   tokens[pos],
@@ -67,6 +88,8 @@ func parseClassDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int,
 
 	var methods []ast.FunctionStmt[any]
 	var globalMethods []ast.FunctionStmt[any]
+	var getters []ast.FunctionStmt[any]
+	var setters []ast.FunctionStmt[any]
 	for tokens[pos].Type != token.RIGHT_BRACE && tokens[pos].Type != token.EOF {
 		if tokens[pos].Type == token.CLASS {
 			method, end, err := parseFunctionDeclaration("method", tokens, pos+1)
@@ -76,6 +99,22 @@ func parseClassDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int,
 
 			globalMethods = append(globalMethods, method.(ast.FunctionStmt[any]))
 			pos = end
+		} else if tokens[pos].Type == token.IDENTIFIER && (tokens[pos+1].Type == token.LEFT_BRACE || tokens[pos+1].Type == token.COLON) {
+			getter, end, err := parseGetter(tokens, pos)
+			if err != nil {
+				return nil, end, err
+			}
+
+			getters = append(getters, getter)
+			pos = end
+		} else if tokens[pos].Type == token.IDENTIFIER && tokens[pos+1].Type == token.EQUAL {
+			setter, end, err := parseSetter(tokens, pos)
+			if err != nil {
+				return nil, end, err
+			}
+
+			setters = append(setters, setter)
+			pos = end
 		} else {
 			method, end, err := parseFunctionDeclaration("method", tokens, pos)
 			if err != nil {
@@ -93,9 +132,94 @@ func parseClassDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int,
 			Message: "Expected '}' after class body.",
 		}
 	}
+	rightBrace := tokens[pos]
+	pos += 1
+
+	return ast.ClassStmt[any]{Name: name, Superclass: superclass, Traits: traits, Methods: methods, GlobalMethods: globalMethods, Getters: getters, Setters: setters, RightBrace: rightBrace}, pos, nil
+}
+
+// isContextualKeyword reports whether an identifier is a keyword where it is
+// used, such as 'trait', 'with' and 'in', which remain valid names elsewhere.
+func isContextualKeyword(name token.Token, keyword string) bool {
+	return name.Type == token.IDENTIFIER && name.Lexeme == keyword
+}
+
+// parseTraitDeclaration parses a trait, a bundle of methods classes are
+// composed with: trait Name { methods }.
+func parseTraitDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
+	name := tokens[start]
+	pos := start + 1
+
+	if tokens[pos].Type != token.LEFT_BRACE {
+		return nil, pos, &ParserError{
+			Token:   tokens[pos],
+			Message: "Expect '{' before trait body.",
+		}
+	}
 	pos += 1
 
-	return ast.ClassStmt[any]{Name: name, Superclass: superclass, Methods: methods, GlobalMethods: globalMethods}, pos, nil
+	var methods []ast.FunctionStmt[any]
+	for tokens[pos].Type != token.RIGHT_BRACE && tokens[pos].Type != token.EOF {
+		method, end, err := parseFunctionDeclaration("method", tokens, pos)
+		if err != nil {
+			return nil, end, err
+		}
+
+		methods = append(methods, method.(ast.FunctionStmt[any]))
+		pos = end
+	}
+
+	if tokens[pos].Type != token.RIGHT_BRACE {
+		return nil, pos, &ParserError{
+			Token:   tokens[pos],
+			Message: "Expected '}' after trait body.",
+		}
+	}
+
+	return ast.TraitStmt[any]{Name: name, Methods: methods, RightBrace: tokens[pos]}, pos + 1, nil
+}
+
+// parseGetter parses a getter, a method without parameter list run when its
+// property is read: name { body }, or name: Type { body }.
+func parseGetter(tokens []token.Token, start int) (ast.FunctionStmt[any], int, error) {
+	returnType, pos, err := parseTypeAnnotation(tokens, start+1)
+	if err != nil {
+		return ast.FunctionStmt[any]{}, pos, err
+	}
+
+	if tokens[pos].Type != token.LEFT_BRACE {
+		return ast.FunctionStmt[any]{}, pos, &ParserError{
+			Token:   tokens[pos],
+			Message: "Expect '{' before getter body.",
+		}
+	}
+
+	body, end, err := parseBlockStatement(tokens, pos+1, 0)
+	if err != nil {
+		return ast.FunctionStmt[any]{}, end, err
+	}
+
+	block := body.(ast.BlockStmt[any])
+	return ast.FunctionStmt[any]{Name: tokens[start], ReturnType: returnType, Body: block.Statements, RightBrace: block.RightBrace, Generator: yields(block.Statements)}, end, nil
+}
+
+// parseSetter parses a setter, a method run when its property is assigned,
+// with the value assigned as only parameter: name=(value) { body }.
+func parseSetter(tokens []token.Token, start int) (ast.FunctionStmt[any], int, error) {
+	setter, end, err := parseFunctionHelper("setter", tokens, start+2)
+	if err != nil {
+		return ast.FunctionStmt[any]{}, end, err
+	}
+
+	if len(setter.Parameters) != 1 {
+		return ast.FunctionStmt[any]{}, end, &ParserError{
+			Token:   tokens[start],
+			Message: "A setter must have exactly one parameter.",
+		}
+	}
+
+	setter.Name = tokens[start]
+	return setter, end, nil
 }
 
 func parseFunctionDeclaration(kind string, tokens []token.Token, start int) (ast.Stmt[any], int, error) {
@@ -108,18 +232,21 @@ func parseFunctionDeclaration(kind string, tokens []token.Token, start int) (ast
 	name := tokens[start]
 	end := start + 1
 
-	parameters, body, end, err := parseFunctionHelper("function", tokens, end)
+	function, end, err := parseFunctionHelper("function", tokens, end)
 	if err != nil {
 		return nil, end, err
 	}
 
-	return ast.FunctionStmt[any]{Name: name, Parameters: parameters, Body: body}, end, nil
+	function.Name = name
+	return function, end, nil
 }
 
-func parseFunctionHelper(kind string, tokens []token.Token, start int) ([]token.Token, []ast.Stmt[any], int, error) {
+// parseFunctionHelper parses the parameters, the optional return type and the
+// body of a function, leaving its name to the caller.
+func parseFunctionHelper(kind string, tokens []token.Token, start int) (ast.FunctionStmt[any], int, error) {
 	pos := start
 	if tokens[pos].Type != token.LEFT_PAREN {
-		return nil, nil, pos, &ParserError{
+		return ast.FunctionStmt[any]{}, pos, &ParserError{
 			Token:   tokens[pos],
 			Message: "Expect '(' after " + kind + " name.",
 		}
@@ -127,24 +254,64 @@ func parseFunctionHelper(kind string, tokens []token.Token, start int) ([]token.
 	pos += 1
 
 	parameters := []token.Token{}
+	parameterTypes := []*token.Token{}
+	defaults := []ast.Expr[any]{}
+	var ellipsis *token.Token
 	if tokens[pos].Type != token.RIGHT_PAREN {
 		for {
 			if len(parameters) >= 255 {
-				return nil, nil, pos, &ParserError{
+				return ast.FunctionStmt[any]{}, pos, &ParserError{
 					Token:   tokens[pos],
 					Message: "Can't have more than 255 parameters.",
 				}
 			}
 
+			if tokens[pos].Type == token.ELLIPSIS {
+				ellipsis = &tokens[pos]
+				pos += 1
+			}
+
 			if tokens[pos].Type != token.IDENTIFIER {
-				return nil, nil, pos, &ParserError{
+				return ast.FunctionStmt[any]{}, pos, &ParserError{
 					Token:   tokens[pos],
 					Message: "Expect parameter name.",
 				}
 			}
 
 			parameters = append(parameters, tokens[pos])
-			pos += 1
+			parameterType, end, err := parseTypeAnnotation(tokens, pos+1)
+			if err != nil {
+				return ast.FunctionStmt[any]{}, end, err
+			}
+			parameterTypes = append(parameterTypes, parameterType)
+			pos = end
+
+			if ellipsis != nil && tokens[pos].Type == token.COMMA {
+				return ast.FunctionStmt[any]{}, pos, &ParserError{
+					Token:   parameters[len(parameters)-1],
+					Message: "A rest parameter must be the last one.",
+				}
+			}
+			if ellipsis != nil && tokens[pos].Type == token.EQUAL {
+				return ast.FunctionStmt[any]{}, pos, &ParserError{
+					Token:   parameters[len(parameters)-1],
+					Message: "A rest parameter can't have a default value.",
+				}
+			}
+
+			var defaultValue ast.Expr[any]
+			if tokens[pos].Type == token.EQUAL {
+				defaultValue, pos, err = parseAssign(tokens, pos+1)
+				if err != nil {
+					return ast.FunctionStmt[any]{}, pos, err
+				}
+			} else if ellipsis == nil && len(defaults) > 0 && defaults[len(defaults)-1] != nil {
+				return ast.FunctionStmt[any]{}, pos, &ParserError{
+					Token:   parameters[len(parameters)-1],
+					Message: "A parameter without default value can't follow one with a default value.",
+				}
+			}
+			defaults = append(defaults, defaultValue)
 
 			if tokens[pos].Type != token.COMMA {
 				break
@@ -154,15 +321,19 @@ func parseFunctionHelper(kind string, tokens []token.Token, start int) ([]token.
 	}
 
 	if tokens[pos].Type != token.RIGHT_PAREN {
-		return nil, nil, pos, &ParserError{
+		return ast.FunctionStmt[any]{}, pos, &ParserError{
 			Token:   tokens[pos],
 			Message: "Expect ')' after parameters.",
 		}
 	}
-	pos += 1
+
+	returnType, pos, err := parseTypeAnnotation(tokens, pos+1)
+	if err != nil {
+		return ast.FunctionStmt[any]{}, pos, err
+	}
 
 	if tokens[pos].Type != token.LEFT_BRACE {
-		return nil, nil, pos, &ParserError{
+		return ast.FunctionStmt[any]{}, pos, &ParserError{
 			Token:   tokens[pos],
 			Message: "Expect '{' before " + kind + " body.",
 		}
@@ -171,10 +342,37 @@ func parseFunctionHelper(kind string, tokens []token.Token, start int) ([]token.
 
 	body, pos, err := parseBlockStatement(tokens, pos, 0)
 	if err != nil {
-		return nil, nil, pos, err
+		return ast.FunctionStmt[any]{}, pos, err
+	}
+
+	block := body.(ast.BlockStmt[any])
+	return ast.FunctionStmt[any]{
+		Parameters:     parameters,
+		ParameterTypes: parameterTypes,
+		ReturnType:     returnType,
+		Defaults:       defaults,
+		Ellipsis:       ellipsis,
+		Body:           block.Statements,
+		RightBrace:     block.RightBrace,
+		Generator:      yields(block.Statements),
+	}, pos, nil
+}
+
+// parseTypeAnnotation parses the optional type of a variable, a parameter or
+// the value returned by a function: ': Type'. It returns nil without one.
+func parseTypeAnnotation(tokens []token.Token, start int) (*token.Token, int, error) {
+	if tokens[start].Type != token.COLON {
+		return nil, start, nil
+	}
+
+	if tokens[start+1].Type != token.IDENTIFIER {
+		return nil, start + 1, &ParserError{
+			Token:   tokens[start+1],
+			Message: "Expect type name.",
+		}
 	}
 
-	return parameters, body.(ast.BlockStmt[any]).Statements, pos, nil
+	return &tokens[start+1], start + 2, nil
 }
 
 func parseVarDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
@@ -185,11 +383,14 @@ func parseVarDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int, e
 		}
 	}
 
-	end := start + 1
+	varType, end, err := parseTypeAnnotation(tokens, start+1)
+	if err != nil {
+		return nil, end, err
+	}
+
 	var initializer ast.Expr[any] = ast.NothingExpr[any]{}
-	var err error
 	if tokens[end].Type == token.EQUAL {
-		initializer, end, err = parseExpression(tokens, start+2)
+		initializer, end, err = parseExpression(tokens, end+1)
 		if err != nil {
 			return nil, end, err
 		}
@@ -202,7 +403,7 @@ func parseVarDeclaration(tokens []token.Token, start int) (ast.Stmt[any], int, e
 		}
 	}
 
-	return ast.VarStmt[any]{Name: tokens[start], Initializer: initializer}, end + 1, nil
+	return ast.VarStmt[any]{Name: tokens[start], VariableType: varType, Initializer: initializer}, end + 1, nil
 }
 
 func parseStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any], int, error) {
@@ -220,6 +421,10 @@ func parseStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any],
 		return parseBreakStatement(tokens, start+1, depth)
 	} else if tokens[start].Type == token.RETURN {
 		return parseReturnStatement(tokens, start+1)
+	} else if tokens[start].Type == token.YIELD {
+		return parseYieldStatement(tokens, start+1)
+	} else if tokens[start].Type == token.ASSERT {
+		return parseAssertStatement(tokens, start+1)
 	}
 
 	return parseExpressionStatement(tokens, start)
@@ -246,7 +451,7 @@ func parseBlockStatement(tokens []token.Token, start int, depth int) (ast.Stmt[a
 		}
 	}
 
-	return ast.BlockStmt[any]{Statements: statements}, pos + 1, nil
+	return ast.BlockStmt[any]{LeftBrace: tokens[start-1], Statements: statements, RightBrace: tokens[pos]}, pos + 1, nil
 }
 
 func parseExpressionStatement(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
@@ -298,7 +503,7 @@ func parseIfStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any]
 		}
 	}
 
-	return ast.IfStmt[any]{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, end, nil
+	return ast.IfStmt[any]{Keyword: tokens[start-1], Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, end, nil
 }
 
 func parseWhileStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any], int, error) {
@@ -326,7 +531,7 @@ func parseWhileStatement(tokens []token.Token, start int, depth int) (ast.Stmt[a
 		return nil, end, err
 	}
 
-	return ast.WhileStmt[any]{Condition: condition, Body: body}, end, nil
+	return ast.WhileStmt[any]{Keyword: tokens[start-1], Condition: condition, Body: body}, end, nil
 }
 
 func parseForStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any], int, error) {
@@ -337,6 +542,16 @@ func parseForStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any
 		}
 	}
 
+	if tokens[start+1].Type == token.VAR && tokens[start+2].Type == token.IDENTIFIER {
+		variableType, end, err := parseTypeAnnotation(tokens, start+3)
+		if err != nil {
+			return nil, end, err
+		}
+		if isContextualKeyword(tokens[end], "in") {
+			return parseForInStatement(tokens, start, end+1, variableType, depth)
+		}
+	}
+
 	var initializer ast.Stmt[any]
 	var end int
 	var err error
@@ -394,29 +609,54 @@ func parseForStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any
 		return nil, end, err
 	}
 
-	// Desugar the for loop into a while loop
+	// Desugar the for loop into a while loop. The synthesized nodes carry the
+	// 'for' keyword so tools can tell them apart from user written code.
+	keyword := tokens[start-1]
 	if increment != nil {
-		body = ast.BlockStmt[any]{Statements: []ast.Stmt[any]{
+		body = ast.BlockStmt[any]{LeftBrace: keyword, Statements: []ast.Stmt[any]{
 			body,
 			ast.ExpressionStmt[any]{Expression: increment},
-		}}
+		}, RightBrace: keyword}
 	}
 
 	if condition == nil {
-		condition = ast.LiteralExpr[any]{Value: true}
+		condition = ast.LiteralExpr[any]{Token: keyword, Value: true}
 	}
-	body = ast.WhileStmt[any]{Condition: condition, Body: body}
+	body = ast.WhileStmt[any]{Keyword: keyword, Condition: condition, Body: body}
 
 	if initializer != nil {
-		body = ast.BlockStmt[any]{Statements: []ast.Stmt[any]{
+		body = ast.BlockStmt[any]{LeftBrace: keyword, Statements: []ast.Stmt[any]{
 			initializer,
 			body,
-		}}
+		}, RightBrace: keyword}
 	}
 
 	return body, end, nil
 }
 
+// parseForInStatement parses the rest of a loop over the values of an
+// iterable, from the expression following 'in': for (var name in iterable).
+func parseForInStatement(tokens []token.Token, start int, pos int, variableType *token.Token, depth int) (ast.Stmt[any], int, error) {
+	iterable, end, err := parseExpression(tokens, pos)
+	if err != nil {
+		return nil, end, err
+	}
+
+	if tokens[end].Type != token.RIGHT_PAREN {
+		return nil, end, &ParserError{
+			Token:   tokens[end],
+			Message: "Expected ')' after for-in iterable.",
+		}
+	}
+
+	body, end, err := parseStatement(tokens, end+1, depth)
+	if err != nil {
+		return nil, end, err
+	}
+
+	return ast.ForInStmt[any]{Keyword: tokens[start-1], Name: tokens[start+2], VariableType: variableType, Iterable: iterable, Body: body}, end, nil
+}
+
 func parseBreakStatement(tokens []token.Token, start int, depth int) (ast.Stmt[any], int, error) {
 	if tokens[start].Type != token.SEMICOLON {
 		return nil, start, &ParserError{
@@ -432,7 +672,7 @@ func parseBreakStatement(tokens []token.Token, start int, depth int) (ast.Stmt[a
 		}
 	}
 
-	return ast.BreakStmt[any]{}, start + 1, nil
+	return ast.BreakStmt[any]{Keyword: tokens[start-1]}, start + 1, nil
 }
 
 func parseReturnStatement(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
@@ -456,6 +696,44 @@ func parseReturnStatement(tokens []token.Token, start int) (ast.Stmt[any], int,
 	return ast.ReturnStmt[any]{Keyword: tokens[start-1], Value: value}, end + 1, nil
 }
 
+func parseYieldStatement(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
+	var value ast.Expr[any] = nil
+	var end int = start
+	var err error
+	if tokens[start].Type != token.SEMICOLON {
+		value, end, err = parseExpression(tokens, start)
+		if err != nil {
+			return nil, end, err
+		}
+	}
+
+	if tokens[end].Type != token.SEMICOLON {
+		return nil, end, &ParserError{
+			Token:   tokens[end],
+			Message: "Expect ';' after yielded value.",
+		}
+	}
+
+	return ast.YieldStmt[any]{Keyword: tokens[start-1], Value: value}, end + 1, nil
+}
+
+// yields reports whether a function body contains a yield statement, which
+// makes it a generator. The bodies of the functions and classes it declares
+// are left out, they are generators or not on their own.
+func yields(body []ast.Stmt[any]) bool {
+	found := false
+	ast.Inspect(body, func(node any) bool {
+		switch node.(type) {
+		case ast.YieldStmt[any]:
+			found = true
+		case ast.FunctionStmt[any], ast.LambdaExpr[any], ast.ClassStmt[any], ast.TraitStmt[any]:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
 func parsePrintStatement(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
 	value, end, err := parseExpression(tokens, start)
 	if err != nil {
@@ -469,7 +747,33 @@ func parsePrintStatement(tokens []token.Token, start int) (ast.Stmt[any], int, e
 		}
 	}
 
-	return ast.PrintStmt[any]{Expression: value}, end + 1, nil
+	return ast.PrintStmt[any]{Keyword: tokens[start-1], Expression: value}, end + 1, nil
+}
+
+// The condition and the message are parsed below the comma operator, which
+// separates them.
+func parseAssertStatement(tokens []token.Token, start int) (ast.Stmt[any], int, error) {
+	condition, end, err := parseAssign(tokens, start)
+	if err != nil {
+		return nil, end, err
+	}
+
+	var message ast.Expr[any] = nil
+	if tokens[end].Type == token.COMMA {
+		message, end, err = parseAssign(tokens, end+1)
+		if err != nil {
+			return nil, end, err
+		}
+	}
+
+	if tokens[end].Type != token.SEMICOLON {
+		return nil, end, &ParserError{
+			Token:   tokens[end],
+			Message: "Expect ';' after assertion.",
+		}
+	}
+
+	return ast.AssertStmt[any]{Keyword: tokens[start-1], Condition: condition, Message: message}, end + 1, nil
 }
 
 func synchronize(tokens []token.Token, start int) int {
@@ -479,7 +783,7 @@ func synchronize(tokens []token.Token, start int) int {
 		}
 
 		switch tokens[i].Type {
-		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN:
+		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN, token.YIELD, token.ASSERT:
 			return i
 		}
 	}
@@ -0,0 +1,158 @@
+package profiler
+
+import (
+	"fmt"
+	"time"
+
+	"lox-tw/ast"
+	"lox-tw/interpreter"
+	"lox-tw/token"
+)
+
+// FunctionStats are the measures of a Lox function, method or lambda.
+// Cumulative time includes the time spent in the functions it calls, self
+// time does not.
+type FunctionStats struct {
+	Name       string
+	Line       uint
+	Calls      int
+	Self       time.Duration
+	Cumulative time.Duration
+
+	// Recursive calls are only counted once in the cumulative time.
+	active int
+}
+
+func (f *FunctionStats) String() string {
+	if f.Line == 0 {
+		return f.Name
+	}
+	return fmt.Sprintf("%s:%d", f.Name, f.Line)
+}
+
+// LineStats are the measures of a source line. Hits counts the statements
+// run on the line.
+type LineStats struct {
+	Line uint
+	Hits int
+	Self time.Duration
+}
+
+type activation struct {
+	function *FunctionStats
+	start    time.Time
+	line     uint
+	stack    string
+}
+
+// Profiler measures where a program spends its time. It hooks into the
+// interpreter as its Tracer, and charges the time between two events to the
+// line and function running at the time.
+type Profiler struct {
+	now  func() time.Time
+	last time.Time
+
+	script      *FunctionStats
+	activations []*activation
+
+	Functions map[string]*FunctionStats
+	Lines     map[uint]*LineStats
+
+	// Self time per call stack, with frames joined by ';'.
+	Stacks map[string]time.Duration
+}
+
+func NewProfiler() *Profiler {
+	return newProfiler(time.Now)
+}
+
+func newProfiler(now func() time.Time) *Profiler {
+	p := &Profiler{
+		now:       now,
+		Functions: make(map[string]*FunctionStats),
+		Lines:     make(map[uint]*LineStats),
+		Stacks:    make(map[string]time.Duration),
+	}
+
+	p.script = &FunctionStats{Name: "<script>", Calls: 1, active: 1}
+	p.Functions[p.script.String()] = p.script
+	p.last = p.now()
+	p.activations = []*activation{{function: p.script, start: p.last, stack: p.script.String()}}
+	return p
+}
+
+// Finish stops the clock, once the program is over.
+func (p *Profiler) Finish() {
+	now := p.tick()
+	p.script.Cumulative = now.Sub(p.activations[0].start)
+}
+
+// Total returns the time spent running the program.
+func (p *Profiler) Total() time.Duration {
+	return p.script.Cumulative
+}
+
+// tick charges the time elapsed since the last event to what was running.
+func (p *Profiler) tick() time.Time {
+	now := p.now()
+	elapsed := now.Sub(p.last)
+	p.last = now
+
+	top := p.activations[len(p.activations)-1]
+	top.function.Self += elapsed
+	p.Stacks[top.stack] += elapsed
+	if line, ok := p.Lines[top.line]; ok {
+		line.Self += elapsed
+	}
+
+	return now
+}
+
+func (p *Profiler) BeforeStmt(i interpreter.Interpreter, stmt ast.Stmt[any]) error {
+	p.tick()
+
+	line := ast.FirstToken(stmt).Line
+	stats, ok := p.Lines[line]
+	if !ok {
+		stats = &LineStats{Line: line}
+		p.Lines[line] = stats
+	}
+	stats.Hits++
+
+	p.activations[len(p.activations)-1].line = line
+	return nil
+}
+
+func (p *Profiler) Branch(site token.Token, branch int) {}
+
+func (p *Profiler) EnterCall(name string, line uint) {
+	now := p.tick()
+
+	function := &FunctionStats{Name: name, Line: line}
+	if stats, ok := p.Functions[function.String()]; ok {
+		function = stats
+	} else {
+		p.Functions[function.String()] = function
+	}
+	function.Calls++
+	function.active++
+
+	caller := p.activations[len(p.activations)-1]
+	p.activations = append(p.activations, &activation{
+		function: function,
+		start:    now,
+		stack:    caller.stack + ";" + function.String(),
+	})
+}
+
+func (p *Profiler) ExitCall() {
+	now := p.tick()
+
+	callee := p.activations[len(p.activations)-1]
+	p.activations = p.activations[:len(p.activations)-1]
+
+	callee.function.active--
+	if callee.function.active == 0 {
+		callee.function.Cumulative += now.Sub(callee.start)
+	}
+}
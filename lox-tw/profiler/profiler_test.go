@@ -0,0 +1,111 @@
+package profiler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"lox-tw/internal/testutil"
+)
+
+const SOURCE = `fun fib(n) {
+    if (n < 2) return n;
+    return fib(n - 1) + fib(n - 2);
+}
+class A {
+    f() { return fib(2); }
+}
+A().f();
+`
+
+// profile runs the source with a clock moving one millisecond every time it
+// is read, so every event takes the same time.
+func profile(t *testing.T, source string) *Profiler {
+	stmts := testutil.Parse(t, source)
+
+	clock := time.Unix(0, 0)
+	p := newProfiler(func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	})
+
+	testutil.Execute(t, stmts, p)
+	p.Finish()
+
+	return p
+}
+
+func TestProfiler(t *testing.T) {
+	p := profile(t, SOURCE)
+
+	tests := []struct {
+		function   string
+		calls      int
+		self       time.Duration
+		cumulative time.Duration
+	}{
+		{function: "<script>", calls: 1, self: 5 * time.Millisecond, cumulative: 19 * time.Millisecond},
+		{function: "A.f:6", calls: 1, self: 3 * time.Millisecond, cumulative: 14 * time.Millisecond},
+		{function: "fib:1", calls: 3, self: 11 * time.Millisecond, cumulative: 11 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		stats, ok := p.Functions[test.function]
+		if !ok {
+			t.Errorf("Expected %s to be profiled", test.function)
+			continue
+		}
+		if stats.Calls != test.calls || stats.Self != test.self || stats.Cumulative != test.cumulative {
+			t.Errorf("Expected %s to have %d calls, %s self and %s cumulative, got %d, %s and %s",
+				test.function, test.calls, test.self, test.cumulative, stats.Calls, stats.Self, stats.Cumulative)
+		}
+	}
+
+	// The 'if' runs three times, its 'return' twice.
+	if hits := p.Lines[2].Hits; hits != 5 {
+		t.Errorf("Expected 5 statements to run on line 2, got %d", hits)
+	}
+
+	var folded strings.Builder
+	p.WriteFolded(&folded)
+	expected := `<script> 5000
+<script>;A.f:6 3000
+<script>;A.f:6;fib:1 5000
+<script>;A.f:6;fib:1;fib:1 6000
+`
+	if folded.String() != expected {
+		t.Errorf("Expected folded stacks:\n%s\nGot:\n%s", expected, folded.String())
+	}
+}
+
+// A generator is profiled each time its body runs up to its next yield,
+// under the function asking for the value.
+func TestGenerators(t *testing.T) {
+	p := profile(t, `fun count(n) {
+    for (var i in range(n)) yield i;
+}
+fun consume() {
+    for (var x in count(2)) print x;
+}
+consume();
+`)
+
+	// It runs up to each of its two values, then to its end.
+	stats, ok := p.Functions["count:1"]
+	if !ok {
+		t.Fatalf("Expected count:1 to be profiled")
+	}
+	if stats.Calls != 3 {
+		t.Errorf("Expected count:1 to have 3 calls, got %d", stats.Calls)
+	}
+
+	var folded strings.Builder
+	p.WriteFolded(&folded)
+	expected := `<script> 5000
+<script>;consume:4 7000
+<script>;consume:4;count:1 6000
+`
+	if folded.String() != expected {
+		t.Errorf("Expected folded stacks:\n%s\nGot:\n%s", expected, folded.String())
+	}
+}
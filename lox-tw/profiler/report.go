@@ -0,0 +1,101 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The report only lists the hottest lines.
+const MAX_LINES = 20
+
+// WriteReport writes the time spent per function and per line, hottest
+// first, as a text table.
+func (p *Profiler) WriteReport(w io.Writer, source string) error {
+	lines := strings.Split(source, "\n")
+
+	functions := make([]*FunctionStats, 0, len(p.Functions))
+	for _, function := range p.Functions {
+		functions = append(functions, function)
+	}
+	sort.Slice(functions, func(a, b int) bool {
+		if functions[a].Self != functions[b].Self {
+			return functions[a].Self > functions[b].Self
+		}
+		return functions[a].String() < functions[b].String()
+	})
+
+	hottest := make([]*LineStats, 0, len(p.Lines))
+	for _, line := range p.Lines {
+		hottest = append(hottest, line)
+	}
+	sort.Slice(hottest, func(a, b int) bool {
+		if hottest[a].Self != hottest[b].Self {
+			return hottest[a].Self > hottest[b].Self
+		}
+		return hottest[a].Line < hottest[b].Line
+	})
+	hottest = hottest[:min(len(hottest), MAX_LINES)]
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Total time: %s\n\n", milliseconds(p.Total()))
+
+	fmt.Fprintf(&report, "%10s %12s %7s %12s  %s\n", "Calls", "Self", "Self%", "Cumulative", "Function")
+	for _, function := range functions {
+		fmt.Fprintf(&report, "%10d %12s %7s %12s  %s\n",
+			function.Calls,
+			milliseconds(function.Self),
+			p.percentage(function.Self),
+			milliseconds(function.Cumulative),
+			function,
+		)
+	}
+
+	fmt.Fprintf(&report, "\n%10s %12s %7s  %s\n", "Hits", "Self", "Self%", "Line")
+	for _, line := range hottest {
+		text := ""
+		if int(line.Line) <= len(lines) {
+			text = strings.TrimSpace(lines[line.Line-1])
+		}
+		fmt.Fprintf(&report, "%10d %12s %7s  %4d  %s\n",
+			line.Hits,
+			milliseconds(line.Self),
+			p.percentage(line.Self),
+			line.Line,
+			text,
+		)
+	}
+
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// WriteFolded writes the self time of every call stack in the folded format
+// read by flame graph tools, one 'frame;frame;frame microseconds' per line.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.Stacks))
+	for stack := range p.Stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, p.Stacks[stack].Microseconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Profiler) percentage(duration time.Duration) string {
+	if p.Total() == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(duration)/float64(p.Total()))
+}
+
+func milliseconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(duration)/float64(time.Millisecond))
+}
@@ -6,7 +6,7 @@ import (
 
 func (r *Resolver) VisitAssignExpr(expr ast.AssignExpr[any]) (any, error) {
 	value, err := expr.Value.Accept(r)
-	r.resolveLocal(expr, expr.Name)
+	r.resolveLocal(expr.Binding, expr.Name)
 
 	return value, err
 }
@@ -82,10 +82,11 @@ func (r *Resolver) VisitCallExpr(expr ast.CallExpr[any]) (any, error) {
 }
 
 func (r *Resolver) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
+	previousFunction := r.currentFunction
+	r.currentFunction = FUNCTION
 	r.beginScope()
-	for _, param := range expr.Parameters {
-		r.declare(param)
-		r.define(param)
+	if err := r.resolveParameters(expr.Parameters, expr.Defaults); err != nil {
+		return nil, err
 	}
 
 	for _, bodyStmt := range expr.Body {
@@ -95,13 +96,14 @@ func (r *Resolver) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
 	}
 
 	r.endScope()
+	r.currentFunction = previousFunction
 
 	return nil, nil
 }
 
 func (r *Resolver) VisitVarExpr(expr ast.VarExpr[any]) (any, error) {
 	if len(r.scopes) == 0 {
-		r.resolveLocal(expr, expr.Name)
+		r.resolveLocal(expr.Binding, expr.Name)
 
 		return nil, nil
 	}
@@ -113,7 +115,7 @@ func (r *Resolver) VisitVarExpr(expr ast.VarExpr[any]) (any, error) {
 		}
 	}
 
-	r.resolveLocal(expr, expr.Name)
+	r.resolveLocal(expr.Binding, expr.Name)
 
 	return nil, nil
 }
@@ -142,17 +144,40 @@ func (r *Resolver) VisitThisExpr(expr ast.ThisExpr[any]) (any, error) {
 		}
 	}
 
-	r.resolveLocal(expr, expr.Keyword)
+	r.resolveLocal(expr.Binding, expr.Keyword)
 
 	return nil, nil
 }
 
+func (r *Resolver) VisitInnerExpr(expr ast.InnerExpr[any]) (any, error) {
+	if r.currentClass == NONE_CLASS {
+		return nil, &ResolverError{
+			Token:   expr.Keyword,
+			Message: "Can't use 'inner' outside of a class.",
+		}
+	}
+
+	for _, argument := range expr.Arguments {
+		if _, err := argument.Accept(r); err != nil {
+			return nil, err
+		}
+	}
+
+	r.resolveLocal(expr.Binding, expr.Keyword)
+	return nil, nil
+}
+
 func (r *Resolver) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
 	if r.currentClass == NONE_CLASS {
 		return nil, &ResolverError{
 			Token:   expr.Keyword,
 			Message: "Can't use 'super' outside of a class.",
 		}
+	} else if r.currentClass == TRAIT {
+		return nil, &ResolverError{
+			Token:   expr.Keyword,
+			Message: "Can't use 'super' in a trait.",
+		}
 	} else if r.currentClass != SUBCLASS {
 		return nil, &ResolverError{
 			Token:   expr.Keyword,
@@ -160,6 +185,6 @@ func (r *Resolver) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
 		}
 	}
 
-	r.resolveLocal(expr, expr.Keyword)
+	r.resolveLocal(expr.Binding, expr.Keyword)
 	return nil, nil
 }
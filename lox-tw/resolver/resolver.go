@@ -1,6 +1,8 @@
 package resolver
 
 import (
+	"sort"
+
 	"lox-tw/ast"
 	"lox-tw/token"
 )
@@ -12,6 +14,7 @@ const (
 	FUNCTION
 	INITIALIZER
 	METHOD
+	SETTER
 )
 
 type ClassType uint8
@@ -20,32 +23,169 @@ const (
 	NONE_CLASS ClassType = iota
 	CLASS
 	SUBCLASS
+	TRAIT
+)
+
+type DeclarationKind uint8
+
+const (
+	VARIABLE_DECLARATION DeclarationKind = iota
+	PARAMETER_DECLARATION
+	FUNCTION_DECLARATION
+	CLASS_DECLARATION
+	METHOD_DECLARATION
+	TRAIT_DECLARATION
 )
 
+func (k DeclarationKind) String() string {
+	return [...]string{
+		"variable",
+		"parameter",
+		"function",
+		"class",
+		"method",
+		"trait",
+	}[k]
+}
+
 type Resolver struct {
 	scopes          []map[string]bool
+	slots           []map[string]int
 	currentFunction FunctionType
 	currentClass    ClassType
-	ExprToDepth     map[ast.Expr[any]]int
+
+	// Where every name was declared and which declaration each variable use
+	// refers to. Only tooling needs them, the interpreter reads the bindings
+	// of the nodes.
+	Declarations map[token.Token]DeclarationKind
+	declarations []map[string]token.Token
+	uses         map[token.Token]token.Token
+	globals      map[string]token.Token
+	globalUses   map[token.Token]bool
+
+	// The methods of every trait, by declaration, to find the conflicts
+	// between the traits of a class.
+	traits map[token.Token][]ast.FunctionStmt[any]
 }
 
 func NewResolver() *Resolver {
 	return &Resolver{
-		scopes:      make([]map[string]bool, 0),
-		ExprToDepth: make(map[ast.Expr[any]]int),
+		scopes:       make([]map[string]bool, 0),
+		slots:        make([]map[string]int, 0),
+		Declarations: make(map[token.Token]DeclarationKind),
+		declarations: make([]map[string]token.Token, 0),
+		uses:         make(map[token.Token]token.Token),
+		globals:      make(map[string]token.Token),
+		globalUses:   make(map[token.Token]bool),
+		traits:       make(map[token.Token][]ast.FunctionStmt[any]),
+	}
+}
+
+// NewResolverInScopes returns a resolver that resolves code as if it was
+// written inside the given scopes, outermost first, each listing its
+// variables in slot order. The debugger uses it to
+// evaluate expressions in a paused frame.
+func NewResolverInScopes(scopes [][]string) *Resolver {
+	r := NewResolver()
+	for _, names := range scopes {
+		r.beginScope()
+		for _, name := range names {
+			r.defineByLexeme(name)
+			if name == "this" && r.currentClass == NONE_CLASS {
+				r.currentClass = CLASS
+			}
+			if name == "super" {
+				r.currentClass = SUBCLASS
+			}
+		}
+	}
+
+	return r
+}
+
+// Resolve resolves every statement and returns all the errors found instead
+// of stopping at the first one.
+func (r *Resolver) Resolve(stmts []ast.Stmt[any]) []error {
+	var errors []error
+	for _, stmt := range stmts {
+		if err := stmt.Accept(r); err != nil {
+			errors = append(errors, err)
+
+			// The statement was left half resolved, start the next one from
+			// the top-level scope.
+			r.scopes = r.scopes[:0]
+			r.slots = r.slots[:0]
+			r.declarations = r.declarations[:0]
+			r.currentFunction = NONE
+			r.currentClass = NONE_CLASS
+		}
+	}
+
+	return errors
+}
+
+// DeclarationOf returns the token that declared the variable used at name. A
+// declaration is its own declaration.
+func (r *Resolver) DeclarationOf(name token.Token) (token.Token, bool) {
+	if declaration, ok := r.uses[name]; ok {
+		return declaration, true
+	}
+
+	if r.globalUses[name] {
+		declaration, ok := r.globals[name.Lexeme]
+		return declaration, ok
+	}
+
+	if _, ok := r.Declarations[name]; ok {
+		return name, true
+	}
+
+	return token.Token{}, false
+}
+
+// UsesOf returns, in source order, the variable uses that refer to the
+// declaration.
+func (r *Resolver) UsesOf(declaration token.Token) []token.Token {
+	var uses []token.Token
+	for use, usedDeclaration := range r.uses {
+		if usedDeclaration == declaration {
+			uses = append(uses, use)
+		}
 	}
+
+	if r.globals[declaration.Lexeme] == declaration {
+		for use := range r.globalUses {
+			if use.Lexeme == declaration.Lexeme {
+				uses = append(uses, use)
+			}
+		}
+	}
+
+	sort.Slice(uses, func(a, b int) bool {
+		return uses[a].Position < uses[b].Position
+	})
+	return uses
 }
 
 func (r *Resolver) beginScope() {
 	r.scopes = append(r.scopes, make(map[string]bool))
+	r.slots = append(r.slots, make(map[string]int))
+	r.declarations = append(r.declarations, make(map[string]token.Token))
 }
 
 func (r *Resolver) endScope() {
 	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.slots = r.slots[:len(r.slots)-1]
+	r.declarations = r.declarations[:len(r.declarations)-1]
 }
 
-func (r *Resolver) declare(name token.Token) error {
+func (r *Resolver) declare(name token.Token, kind DeclarationKind) error {
+	r.Declarations[name] = kind
+
 	if len(r.scopes) == 0 {
+		if _, exists := r.globals[name.Lexeme]; !exists {
+			r.globals[name.Lexeme] = name
+		}
 		return nil
 	}
 
@@ -58,6 +198,8 @@ func (r *Resolver) declare(name token.Token) error {
 	}
 
 	r.scopes[len(r.scopes)-1][name.Lexeme] = false
+	r.addSlot(name.Lexeme)
+	r.declarations[len(r.declarations)-1][name.Lexeme] = name
 
 	return nil
 }
@@ -73,13 +215,30 @@ func (r *Resolver) defineByLexeme(name string) {
 
 	scope := r.scopes[len(r.scopes)-1]
 	scope[name] = true
+	r.addSlot(name)
 }
 
-func (r *Resolver) resolveLocal(expr ast.Expr[any], name token.Token) {
+// addSlot gives a variable the next slot of the innermost scope, the one the
+// interpreter defines it in.
+func (r *Resolver) addSlot(name string) {
+	slots := r.slots[len(r.slots)-1]
+	if _, exists := slots[name]; !exists {
+		slots[name] = len(slots)
+	}
+}
+
+// resolveLocal fills the binding of a variable use, leaving globals unbound.
+func (r *Resolver) resolveLocal(binding *ast.Binding, name token.Token) {
+	*binding = ast.Binding{}
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		if _, valueExists := r.scopes[i][name.Lexeme]; valueExists {
-			r.ExprToDepth[expr] = len(r.scopes) - 1 - i
+			*binding = ast.Binding{Local: true, Depth: len(r.scopes) - 1 - i, Index: r.slots[i][name.Lexeme]}
+			if declaration, ok := r.declarations[i][name.Lexeme]; ok {
+				r.uses[name] = declaration
+			}
 			return
 		}
 	}
+
+	r.globalUses[name] = true
 }
@@ -1,11 +1,15 @@
 package resolver
 
 import (
+	"fmt"
+	"os"
+
 	"lox-tw/ast"
+	"lox-tw/token"
 )
 
 func (r *Resolver) VisitVarStmt(stmt ast.VarStmt[any]) error {
-	if err := r.declare(stmt.Name); err != nil {
+	if err := r.declare(stmt.Name, VARIABLE_DECLARATION); err != nil {
 		return err
 	}
 
@@ -54,6 +58,27 @@ func (r *Resolver) VisitWhileStmt(stmt ast.WhileStmt[any]) error {
 	return nil
 }
 
+// The variable of a for-in loop is declared in a scope of its own around the
+// body, created anew for each value.
+func (r *Resolver) VisitForInStmt(stmt ast.ForInStmt[any]) error {
+	if _, err := stmt.Iterable.Accept(r); err != nil {
+		return err
+	}
+
+	r.beginScope()
+	if err := r.declare(stmt.Name, VARIABLE_DECLARATION); err != nil {
+		return err
+	}
+	r.define(stmt.Name)
+
+	if err := stmt.Body.Accept(r); err != nil {
+		return err
+	}
+	r.endScope()
+
+	return nil
+}
+
 func (r *Resolver) VisitPrintStmt(stmt ast.PrintStmt[any]) error {
 	_, err := stmt.Expression.Accept(r)
 	return err
@@ -63,7 +88,7 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 	enclosingClass := r.currentClass
 	r.currentClass = CLASS
 
-	if err := r.declare(stmt.Name); err != nil {
+	if err := r.declare(stmt.Name, CLASS_DECLARATION); err != nil {
 		return err
 	}
 	r.define(stmt.Name)
@@ -75,6 +100,10 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 		}
 	}
 
+	if err := r.resolveTraits(stmt); err != nil {
+		return err
+	}
+
 	if stmt.Superclass != nil {
 		r.currentClass = SUBCLASS
 		stmt.Superclass.Accept(r)
@@ -85,7 +114,11 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 
 	r.beginScope()
 	r.defineByLexeme("this")
+	if os.Getenv("INNER_ENABLED") == "true" {
+		r.defineByLexeme("inner")
+	}
 	for _, method := range stmt.Methods {
+		r.Declarations[method.Name] = METHOD_DECLARATION
 		declaration := METHOD
 		if method.Name.Lexeme == "init" {
 			declaration = INITIALIZER
@@ -96,11 +129,24 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 		}
 	}
 	for _, globalMethod := range stmt.GlobalMethods {
+		r.Declarations[globalMethod.Name] = METHOD_DECLARATION
 		err := r.resolveFunction(globalMethod, METHOD)
 		if err != nil {
 			return err
 		}
 	}
+	for _, getter := range stmt.Getters {
+		r.Declarations[getter.Name] = METHOD_DECLARATION
+		if err := r.resolveFunction(getter, METHOD); err != nil {
+			return err
+		}
+	}
+	for _, setter := range stmt.Setters {
+		r.Declarations[setter.Name] = METHOD_DECLARATION
+		if err := r.resolveFunction(setter, SETTER); err != nil {
+			return err
+		}
+	}
 	r.endScope()
 
 	if stmt.Superclass != nil {
@@ -112,6 +158,68 @@ func (r *Resolver) VisitClassStmt(stmt ast.ClassStmt[any]) error {
 	return nil
 }
 
+// resolveTraits resolves the traits of a class, which can't provide methods
+// of the same name unless the class declares its own.
+func (r *Resolver) resolveTraits(stmt ast.ClassStmt[any]) error {
+	declared := make(map[string]bool)
+	for _, method := range stmt.Methods {
+		declared[method.Name.Lexeme] = true
+	}
+
+	provided := make(map[string]token.Token)
+	for _, trait := range stmt.Traits {
+		if _, err := trait.Accept(r); err != nil {
+			return err
+		}
+
+		declaration, _ := r.DeclarationOf(trait.Name)
+		for _, method := range r.traits[declaration] {
+			name := method.Name.Lexeme
+			if other, ok := provided[name]; ok && !declared[name] {
+				return &ResolverError{
+					Token:   trait.Name,
+					Message: fmt.Sprintf("Traits '%s' and '%s' both provide '%s', the class must override it.", other.Lexeme, trait.Name.Lexeme, name),
+				}
+			}
+			provided[name] = trait.Name
+		}
+	}
+
+	return nil
+}
+
+func (r *Resolver) VisitTraitStmt(stmt ast.TraitStmt[any]) error {
+	enclosingClass := r.currentClass
+	r.currentClass = TRAIT
+
+	if err := r.declare(stmt.Name, TRAIT_DECLARATION); err != nil {
+		return err
+	}
+	r.define(stmt.Name)
+	r.traits[stmt.Name] = stmt.Methods
+
+	r.beginScope()
+	r.defineByLexeme("this")
+	if os.Getenv("INNER_ENABLED") == "true" {
+		r.defineByLexeme("inner")
+	}
+	for _, method := range stmt.Methods {
+		r.Declarations[method.Name] = METHOD_DECLARATION
+		declaration := METHOD
+		if method.Name.Lexeme == "init" {
+			declaration = INITIALIZER
+		}
+		if err := r.resolveFunction(method, declaration); err != nil {
+			return err
+		}
+	}
+	r.endScope()
+
+	r.currentClass = enclosingClass
+
+	return nil
+}
+
 func (r *Resolver) VisitBlockStmt(stmt ast.BlockStmt[any]) error {
 	r.beginScope()
 	for _, statement := range stmt.Statements {
@@ -129,7 +237,7 @@ func (r *Resolver) VisitBreakStmt(stmt ast.BreakStmt[any]) error {
 }
 
 func (r *Resolver) VisitFunctionStmt(stmt ast.FunctionStmt[any]) error {
-	if err := r.declare(stmt.Name); err != nil {
+	if err := r.declare(stmt.Name, FUNCTION_DECLARATION); err != nil {
 		return err
 	}
 	r.define(stmt.Name)
@@ -138,15 +246,18 @@ func (r *Resolver) VisitFunctionStmt(stmt ast.FunctionStmt[any]) error {
 }
 
 func (r *Resolver) resolveFunction(stmt ast.FunctionStmt[any], functionType FunctionType) error {
+	if functionType == METHOD && stmt.Name.Lexeme == "toString" && len(stmt.Parameters) > 0 {
+		return &ResolverError{
+			Token:   stmt.Name,
+			Message: "A toString method can't have parameters.",
+		}
+	}
+
 	previousFunction := r.currentFunction
 	r.currentFunction = functionType
 	r.beginScope()
-	for _, param := range stmt.Parameters {
-		if err := r.declare(param); err != nil {
-			return err
-		}
-
-		r.define(param)
+	if err := r.resolveParameters(stmt.Parameters, stmt.Defaults); err != nil {
+		return err
 	}
 
 	for _, bodyStmt := range stmt.Body {
@@ -161,6 +272,38 @@ func (r *Resolver) resolveFunction(stmt ast.FunctionStmt[any], functionType Func
 	return nil
 }
 
+// resolveParameters declares the parameters of a function in its scope. The
+// default value of a parameter sees the parameters before it.
+func (r *Resolver) resolveParameters(parameters []token.Token, defaults []ast.Expr[any]) error {
+	for i, param := range parameters {
+		if i < len(defaults) && defaults[i] != nil {
+			if _, err := defaults[i].Accept(r); err != nil {
+				return err
+			}
+		}
+
+		if err := r.declare(param, PARAMETER_DECLARATION); err != nil {
+			return err
+		}
+		r.define(param)
+	}
+
+	return nil
+}
+
+func (r *Resolver) VisitAssertStmt(stmt ast.AssertStmt[any]) error {
+	if _, err := stmt.Condition.Accept(r); err != nil {
+		return err
+	}
+
+	if stmt.Message != nil {
+		_, err := stmt.Message.Accept(r)
+		return err
+	}
+
+	return nil
+}
+
 func (r *Resolver) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
 	if r.currentFunction == NONE {
 		return &ResolverError{
@@ -177,6 +320,13 @@ func (r *Resolver) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
 			}
 		}
 
+		if r.currentFunction == SETTER {
+			return &ResolverError{
+				Token:   stmt.Keyword,
+				Message: "Can't return a value from a setter.",
+			}
+		}
+
 		if _, err := stmt.Value.Accept(r); err != nil {
 			return err
 		}
@@ -184,3 +334,33 @@ func (r *Resolver) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
 
 	return nil
 }
+
+func (r *Resolver) VisitYieldStmt(stmt ast.YieldStmt[any]) error {
+	if r.currentFunction == NONE {
+		return &ResolverError{
+			Token:   stmt.Keyword,
+			Message: "Can't yield from top-level code.",
+		}
+	}
+
+	if r.currentFunction == INITIALIZER {
+		return &ResolverError{
+			Token:   stmt.Keyword,
+			Message: "Can't yield from an initializer.",
+		}
+	}
+
+	if r.currentFunction == SETTER {
+		return &ResolverError{
+			Token:   stmt.Keyword,
+			Message: "Can't yield from a setter.",
+		}
+	}
+
+	if stmt.Value != nil {
+		_, err := stmt.Value.Accept(r)
+		return err
+	}
+
+	return nil
+}
@@ -9,8 +9,21 @@ import (
 )
 
 func ScanTokens(source string) ([]token.Token, error) {
+	return scanTokens(source, false)
+}
+
+// ScanTokensWithTrivia works like ScanTokens but keeps comments and blank
+// lines, attaching them as trivia to the token that follows them.
+func ScanTokensWithTrivia(source string) ([]token.Token, error) {
+	return scanTokens(source, true)
+}
+
+func scanTokens(source string, keepTrivia bool) ([]token.Token, error) {
 	tokens := []token.Token{}
 
+	var trivia []token.Trivia
+	newlines := 0
+
 	position, line := uint(0), uint(1)
 	for !allCharactersParsed(source, position) {
 		scannedToken, err := scanToken(source, position, line)
@@ -19,13 +32,73 @@ func ScanTokens(source string) ([]token.Token, error) {
 		}
 
 		if scannedToken.Type != token.NOTHING {
+			if keepTrivia {
+				trivia = appendBlankLine(trivia, newlines, len(tokens) > 0 || len(trivia) > 0)
+				scannedToken.Trivia = takeTrivia(&trivia)
+				newlines = 0
+			}
 			tokens = append(tokens, scannedToken)
+		} else if keepTrivia {
+			text := source[position:scannedToken.Position]
+			if kind, ok := commentKind(text); ok {
+				trivia = appendBlankLine(trivia, newlines, len(tokens) > 0 || len(trivia) > 0)
+				trivia = append(trivia, token.Trivia{
+					Kind:     kind,
+					Text:     text,
+					Line:     line,
+					Trailing: newlines == 0 && len(tokens) > 0 && len(trivia) == 0,
+				})
+				newlines = 0
+			} else if text == "\n" {
+				newlines += 1
+			}
 		}
 
 		position, line = scannedToken.Position, scannedToken.Line
 	}
 
-	return append(tokens, token.EofToken(position+1, line)), nil
+	eof := token.EofToken(position+1, line)
+	if keepTrivia {
+		eof.Trivia = takeTrivia(&trivia)
+	}
+
+	return append(tokens, eof), nil
+}
+
+func commentKind(text string) (token.TriviaKind, bool) {
+	if len(text) < 2 {
+		return 0, false
+	}
+
+	if isSingleLineComment(text[0], text[1]) {
+		return token.LINE_COMMENT, true
+	}
+
+	if isMultiLineCommentStart(text[0], text[1]) {
+		return token.BLOCK_COMMENT, true
+	}
+
+	return 0, false
+}
+
+// Two or more line breaks between pieces of source mean there was at least
+// one blank line, which is kept as a single BLANK_LINE trivia.
+func appendBlankLine(trivia []token.Trivia, newlines int, afterContent bool) []token.Trivia {
+	if newlines < 2 || !afterContent {
+		return trivia
+	}
+
+	return append(trivia, token.Trivia{Kind: token.BLANK_LINE})
+}
+
+func takeTrivia(trivia *[]token.Trivia) *[]token.Trivia {
+	if len(*trivia) == 0 {
+		return nil
+	}
+
+	taken := *trivia
+	*trivia = nil
+	return &taken
 }
 
 func scanToken(source string, start uint, line uint) (token.Token, error) {
@@ -45,6 +118,16 @@ func scanToken(source string, start uint, line uint) (token.Token, error) {
 		return scanMultiLineComment(source, position, line)
 	}
 
+	if currentCharacter == '.' && nextCharacter == '.' && !allCharactersParsed(source, position+2) && source[position+2] == '.' {
+		return token.Token{
+			Type:     token.ELLIPSIS,
+			Lexeme:   "...",
+			Literal:  nil,
+			Line:     line,
+			Position: position + 3,
+		}, nil
+	}
+
 	tokenType := token.TrySingleCharTokenType(currentCharacter)
 	if tokenType != token.NOTHING {
 		return token.Token{
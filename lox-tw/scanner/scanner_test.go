@@ -46,6 +46,20 @@ func TestScanTokens(t *testing.T) {
 				token.EofToken(16, 1),
 			},
 		},
+		{
+			name:   "Ellipsis",
+			source: "f(...xs.y)",
+			expected: []token.Token{
+				{Type: token.IDENTIFIER, Lexeme: "f", Literal: nil, Line: 1, Position: 1},
+				{Type: token.LEFT_PAREN, Lexeme: "(", Literal: nil, Line: 1, Position: 2},
+				{Type: token.ELLIPSIS, Lexeme: "...", Literal: nil, Line: 1, Position: 5},
+				{Type: token.IDENTIFIER, Lexeme: "xs", Literal: nil, Line: 1, Position: 7},
+				{Type: token.DOT, Lexeme: ".", Literal: nil, Line: 1, Position: 8},
+				{Type: token.IDENTIFIER, Lexeme: "y", Literal: nil, Line: 1, Position: 9},
+				{Type: token.RIGHT_PAREN, Lexeme: ")", Literal: nil, Line: 1, Position: 10},
+				token.EofToken(11, 1),
+			},
+		},
 		{
 			name:   "Comment",
 			source: "// This is a comment\nvar y = 20;",
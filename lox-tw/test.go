@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"lox-tw/tester"
+)
+
+func runTest(arguments []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	interpreter := flags.String("interpreter", "", "run the tests with another `executable` instead of lox-tw")
+	jobs := flags.Int("j", runtime.NumCPU(), "number of tests run in parallel")
+	timeout := flags.Duration("timeout", 10*time.Second, "time after which a test fails")
+	verbose := flags.Bool("v", false, "list the tests that pass too")
+	discover := flags.Bool("discover", false, "run the top-level functions named test* instead of checking expectation comments")
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		fmt.Println("Usage: lox-tw test [--interpreter executable] [-j jobs] [--timeout duration] [-v] paths...")
+		fmt.Println("       lox-tw test --discover [-j jobs] [-v] paths...")
+		return 64
+	}
+
+	if *discover {
+		return runUnitTests(tester.Runner{Jobs: *jobs}, flags.Args(), *verbose)
+	}
+
+	if *interpreter == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		*interpreter = executable
+	}
+
+	runner := tester.Runner{Interpreter: *interpreter, Jobs: *jobs, Timeout: *timeout}
+	results, err := runner.Run(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 66
+	}
+
+	passed, failed, skipped := 0, 0, 0
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			skipped++
+		case result.Passed():
+			passed++
+			if *verbose {
+				fmt.Printf("PASS %s\n", result.Path)
+			}
+		default:
+			failed++
+			fmt.Printf("FAIL %s\n", result.Path)
+			for _, failure := range result.Failures {
+				fmt.Printf("    %s\n", strings.ReplaceAll(failure, "\n", "\n    "))
+			}
+			if result.Diff != "" {
+				fmt.Printf("    Output diff (- expected, + actual):\n")
+				fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimSuffix(result.Diff, "\n"), "\n", "\n    "))
+			}
+		}
+	}
+
+	fmt.Printf("%d passed, %d failed, %d skipped.\n", passed, failed, skipped)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runUnitTests(runner tester.Runner, paths []string, verbose bool) int {
+	results, err := runner.RunUnit(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 66
+	}
+
+	passed, failed := 0, 0
+	for _, result := range results {
+		name := result.Path
+		if result.Name != "" {
+			name += ": " + result.Name
+		}
+
+		if result.Err == nil {
+			passed++
+			if verbose {
+				fmt.Printf("PASS %s\n", name)
+			}
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %s\n", name)
+		fmt.Printf("    %s\n", strings.ReplaceAll(result.Err.Error(), "\n", "\n    "))
+		if result.Output != "" {
+			fmt.Printf("    Output:\n")
+			fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimSuffix(result.Output, "\n"), "\n", "\n    "))
+		}
+	}
+
+	fmt.Printf("%d passed, %d failed.\n", passed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
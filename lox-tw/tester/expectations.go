@@ -0,0 +1,203 @@
+package tester
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The comments understood by the craftinginterpreters test suite.
+var (
+	expectedOutputPattern       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectedErrorPattern        = regexp.MustCompile(`// (Error.*)`)
+	errorLinePattern            = regexp.MustCompile(`// \[((java|c) )?line (\d+)\] (Error.*)`)
+	expectedRuntimeErrorPattern = regexp.MustCompile(`// expect runtime error: (.+)`)
+	syntaxErrorPattern          = regexp.MustCompile(`\[.*line (\d+)\] (Error.+)`)
+	stackTracePattern           = regexp.MustCompile(`\[line (\d+)\]`)
+	nonTestPattern              = regexp.MustCompile(`// nontest`)
+)
+
+type ExpectedOutput struct {
+	Line int
+	Text string
+}
+
+// Expectations are what a test file expects from running it.
+type Expectations struct {
+	Output []ExpectedOutput
+
+	// Compile errors, formatted as '[line N] Error...'.
+	CompileErrors []string
+
+	RuntimeError     string
+	RuntimeErrorLine int
+
+	ExitCode int
+
+	// Files marked '// nontest' are not tests.
+	Skip bool
+}
+
+// ParseExpectations reads the expectations from the comments of a test file.
+// Errors expected from the C implementation only are ignored.
+func ParseExpectations(source string) (Expectations, error) {
+	var e Expectations
+
+	for i, line := range strings.Split(source, "\n") {
+		number := i + 1
+
+		if nonTestPattern.MatchString(line) {
+			return Expectations{Skip: true}, nil
+		}
+
+		if match := expectedOutputPattern.FindStringSubmatch(line); match != nil {
+			e.Output = append(e.Output, ExpectedOutput{Line: number, Text: match[1]})
+			continue
+		}
+
+		if match := expectedErrorPattern.FindStringSubmatch(line); match != nil {
+			e.CompileErrors = append(e.CompileErrors, fmt.Sprintf("[line %d] %s", number, match[1]))
+			e.ExitCode = 65
+			continue
+		}
+
+		if match := errorLinePattern.FindStringSubmatch(line); match != nil {
+			if match[2] != "c" {
+				errorLine, _ := strconv.Atoi(match[3])
+				e.CompileErrors = append(e.CompileErrors, fmt.Sprintf("[line %d] %s", errorLine, match[4]))
+				e.ExitCode = 65
+			}
+			continue
+		}
+
+		if match := expectedRuntimeErrorPattern.FindStringSubmatch(line); match != nil {
+			e.RuntimeError = match[1]
+			e.RuntimeErrorLine = number
+			e.ExitCode = 70
+		}
+	}
+
+	if len(e.CompileErrors) > 0 && e.RuntimeError != "" {
+		return e, fmt.Errorf("cannot expect both compile and runtime errors")
+	}
+
+	return e, nil
+}
+
+// Validate compares the result of running a test with its expectations and
+// returns every mismatch.
+func (e Expectations) Validate(stdout, stderr string, exitCode int) []string {
+	var failures []string
+	errorLines := lines(stderr)
+
+	if e.RuntimeError != "" {
+		failures = append(failures, e.validateRuntimeError(errorLines)...)
+	} else {
+		failures = append(failures, e.validateCompileErrors(errorLines)...)
+	}
+
+	if exitCode != e.ExitCode {
+		failure := fmt.Sprintf("Expected return code %d and got %d.", e.ExitCode, exitCode)
+		if len(errorLines) > 0 {
+			failure += " Stderr:\n" + strings.Join(errorLines[:min(len(errorLines), 10)], "\n")
+		}
+		failures = append(failures, failure)
+	}
+
+	return append(failures, e.validateOutput(lines(stdout))...)
+}
+
+func (e Expectations) validateRuntimeError(errorLines []string) []string {
+	if len(errorLines) < 2 {
+		return []string{fmt.Sprintf("Expected runtime error '%s' and got none.", e.RuntimeError)}
+	}
+
+	if errorLines[0] != e.RuntimeError {
+		return []string{fmt.Sprintf("Expected runtime error '%s' and got:\n%s", e.RuntimeError, errorLines[0])}
+	}
+
+	for _, line := range errorLines[1:] {
+		match := stackTracePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		if line, _ := strconv.Atoi(match[1]); line != e.RuntimeErrorLine {
+			return []string{fmt.Sprintf("Expected runtime error on line %d but was on line %d.", e.RuntimeErrorLine, line)}
+		}
+		return nil
+	}
+
+	return []string{"Expected stack trace and got:\n" + strings.Join(errorLines, "\n")}
+}
+
+func (e Expectations) validateCompileErrors(errorLines []string) []string {
+	var failures []string
+
+	expected := make(map[string]bool)
+	for _, err := range e.CompileErrors {
+		expected[err] = true
+	}
+
+	found := make(map[string]bool)
+	unexpected := 0
+	for _, line := range errorLines {
+		if match := syntaxErrorPattern.FindStringSubmatch(line); match != nil {
+			err := fmt.Sprintf("[line %s] %s", match[1], match[2])
+			if expected[err] {
+				found[err] = true
+				continue
+			}
+		} else if line == "" {
+			continue
+		}
+
+		if unexpected < 10 {
+			failures = append(failures, "Unexpected error:\n"+line)
+		}
+		unexpected++
+	}
+
+	if unexpected > 10 {
+		failures = append(failures, fmt.Sprintf("(truncated %d more...)", unexpected-10))
+	}
+
+	for _, err := range e.CompileErrors {
+		if !found[err] {
+			failures = append(failures, "Missing expected error: "+err)
+		}
+	}
+
+	return failures
+}
+
+func (e Expectations) validateOutput(outputLines []string) []string {
+	var failures []string
+
+	for i, line := range outputLines {
+		if i >= len(e.Output) {
+			failures = append(failures, fmt.Sprintf("Got output '%s' when none was expected.", line))
+			continue
+		}
+
+		if expected := e.Output[i]; expected.Text != line {
+			failures = append(failures, fmt.Sprintf("Expected output '%s' on line %d and got '%s'.", expected.Text, expected.Line, line))
+		}
+	}
+
+	for _, expected := range e.Output[min(len(outputLines), len(e.Output)):] {
+		failures = append(failures, fmt.Sprintf("Missing expected output '%s' on line %d.", expected.Text, expected.Line))
+	}
+
+	return failures
+}
+
+// lines splits an output in lines, ignoring the final newline.
+func lines(output string) []string {
+	output = strings.TrimSuffix(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
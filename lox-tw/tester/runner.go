@@ -0,0 +1,198 @@
+package tester
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of running a test file.
+type Result struct {
+	Path     string
+	Skipped  bool
+	Failures []string
+
+	// Diff between the expected and actual output, when they differ.
+	Diff string
+}
+
+func (r Result) Passed() bool {
+	return !r.Skipped && len(r.Failures) == 0
+}
+
+// Runner runs test files with an interpreter, each in its own process.
+type Runner struct {
+	Interpreter string
+	Jobs        int
+	Timeout     time.Duration
+}
+
+// Run runs the test files found in paths, directories being searched
+// recursively for .lox files. Results are returned in the order of the files.
+func (r Runner) Run(paths []string) ([]Result, error) {
+	files, err := findTests(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(files))
+	r.parallel(len(files), func(i int) {
+		results[i] = r.RunFile(files[i])
+	})
+
+	return results, nil
+}
+
+// parallel calls run with every index up to n, on as many goroutines as
+// there are jobs.
+func (r Runner) parallel(n int, run func(i int)) {
+	indexes := make(chan int)
+
+	var wait sync.WaitGroup
+	for range max(r.Jobs, 1) {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for i := range indexes {
+				run(i)
+			}
+		}()
+	}
+
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wait.Wait()
+}
+
+func findTests(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && filepath.Ext(file) == ".lox" {
+				files = append(files, file)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func (r Runner) RunFile(path string) Result {
+	result := Result{Path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		result.Failures = []string{err.Error()}
+		return result
+	}
+
+	expectations, err := ParseExpectations(string(content))
+	if err != nil {
+		result.Failures = []string{err.Error()}
+		return result
+	}
+	if expectations.Skip {
+		result.Skipped = true
+		return result
+	}
+
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, r.Interpreter, path)
+	command.Stdout, command.Stderr = &stdout, &stderr
+
+	exitCode := 0
+	err = command.Run()
+	if ctx.Err() != nil {
+		result.Failures = []string{fmt.Sprintf("Timed out after %s.", r.Timeout)}
+		return result
+	}
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		exitCode = exitError.ExitCode()
+	} else if err != nil {
+		result.Failures = []string{err.Error()}
+		return result
+	}
+
+	result.Failures = expectations.Validate(stdout.String(), stderr.String(), exitCode)
+
+	var expected []string
+	for _, output := range expectations.Output {
+		expected = append(expected, output.Text)
+	}
+	if actual := lines(stdout.String()); strings.Join(expected, "\n") != strings.Join(actual, "\n") {
+		result.Diff = Diff(expected, actual)
+	}
+
+	return result
+}
+
+// Diff returns the lines removed from a, prefixed by '-', and added in b,
+// prefixed by '+', around the lines they have in common.
+func Diff(a, b []string) string {
+	// lengths[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff.WriteString("  " + a[i] + "\n")
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lengths[i+1][j] >= lengths[i][j+1]):
+			diff.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return diff.String()
+}
@@ -0,0 +1,136 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		stdout   string
+		stderr   string
+		exitCode int
+		expected []string
+	}{
+		{
+			name:   "output",
+			source: "print 1; // expect: 1\nprint 2; // expect: 2\n",
+			stdout: "1\n2\n",
+		},
+		{
+			name:     "wrong output",
+			source:   "print 1; // expect: 1\nprint 2; // expect: 2\n",
+			stdout:   "1\n3\n4\n",
+			expected: []string{"Expected output '2' on line 2 and got '3'.", "Got output '4' when none was expected."},
+		},
+		{
+			name:     "missing output",
+			source:   "print 1; // expect: 1\nprint 2; // expect: 2\n",
+			stdout:   "1\n",
+			expected: []string{"Missing expected output '2' on line 2."},
+		},
+		{
+			name:     "runtime error",
+			source:   "print 1; // expect: 1\n-nil; // expect runtime error: Operand must be a number.\n",
+			stdout:   "1\n",
+			stderr:   "Operand must be a number.\n[line 2]\n",
+			exitCode: 70,
+		},
+		{
+			name:     "runtime error on another line",
+			source:   "-nil; // expect runtime error: Operand must be a number.\n",
+			stderr:   "Operand must be a number.\n[line 3]\n",
+			exitCode: 70,
+			expected: []string{"Expected runtime error on line 1 but was on line 3."},
+		},
+		{
+			name:     "compile errors",
+			source:   "var a = ; // Error at ';': Expect expression.\n// [line 3] Error at end: Expect ';' after value.\n// [c line 3] Error at end: Ignored.\nprint 1",
+			stderr:   "[line 1] Error at ';': Expect expression.\n[line 3] Error at end: Expect ';' after value.\n",
+			exitCode: 65,
+		},
+		{
+			name:     "unexpected compile error",
+			source:   "print a; // expect: 1\n",
+			stderr:   "[line 1] Error at 'a': Nope.\n",
+			exitCode: 65,
+			expected: []string{
+				"Unexpected error:\n[line 1] Error at 'a': Nope.",
+				"Expected return code 0 and got 65. Stderr:\n[line 1] Error at 'a': Nope.",
+				"Missing expected output '1' on line 1.",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectations, err := ParseExpectations(test.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			failures := expectations.Validate(test.stdout, test.stderr, test.exitCode)
+			if strings.Join(failures, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected failures:\n%s\nGot:\n%s", strings.Join(test.expected, "\n"), strings.Join(failures, "\n"))
+			}
+		})
+	}
+}
+
+func TestNonTest(t *testing.T) {
+	expectations, _ := ParseExpectations("// nontest\nprint 1; // expect: 2\n")
+	if !expectations.Skip {
+		t.Errorf("Expected the file to be skipped")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff([]string{"1", "2", "4"}, []string{"1", "3", "4", "5"})
+	expected := "  1\n- 2\n+ 3\n  4\n+ 5\n"
+	if diff != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, diff)
+	}
+}
+
+func TestRunUnitFile(t *testing.T) {
+	source := `var counter = 0;
+fun testFreshGlobals() {
+    counter = counter + 1;
+    assert counter == 1;
+}
+fun testAgain() {
+    counter = counter + 1;
+    assert counter == 1, "counter is " + "shared";
+}
+fun testFails() {
+    print "failing";
+    assert counter == 2, "counter is " + "0";
+}
+fun testWithParameter(a) {}
+fun helper() {}
+`
+	path := filepath.Join(t.TempDir(), "unit.lox")
+	os.WriteFile(path, []byte(source), 0644)
+
+	var results []string
+	for _, result := range RunUnitFile(path) {
+		line := result.Name + " passed"
+		if result.Err != nil {
+			line = result.Name + " failed: " + strings.ReplaceAll(result.Err.Error(), "\n", " ") + ", printed " + strings.TrimSpace(result.Output)
+		}
+		results = append(results, line)
+	}
+
+	expected := []string{
+		"testFreshGlobals passed",
+		"testAgain passed",
+		"testFails failed: Assertion failed: counter is 0 [line 12], printed failing",
+	}
+	if strings.Join(results, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(results, "\n"))
+	}
+}
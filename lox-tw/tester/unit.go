@@ -0,0 +1,124 @@
+package tester
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+
+	"lox-tw/ast"
+	"lox-tw/interpreter"
+	"lox-tw/parser"
+	"lox-tw/resolver"
+	"lox-tw/scanner"
+	"lox-tw/token"
+)
+
+// UnitResult is the outcome of a test function.
+type UnitResult struct {
+	Path string
+	Name string
+
+	// Err is nil when the test passed.
+	Err error
+
+	// What the file and the test printed.
+	Output string
+}
+
+// RunUnit runs the test functions of the files found in paths, see
+// RunUnitFile. Results are returned in the order of the files.
+func (r Runner) RunUnit(paths []string) ([]UnitResult, error) {
+	files, err := findTests(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsPerFile := make([][]UnitResult, len(files))
+	r.parallel(len(files), func(i int) {
+		resultsPerFile[i] = RunUnitFile(files[i])
+	})
+
+	var results []UnitResult
+	for _, fileResults := range resultsPerFile {
+		results = append(results, fileResults...)
+	}
+	return results, nil
+}
+
+// RunUnitFile runs every top-level function of a file whose name starts with
+// 'test' and takes no parameters. Each test gets a fresh global environment,
+// where the top-level statements of the file run before the test is called.
+// A test fails when it raises a runtime error, such as a failed assert.
+func RunUnitFile(path string) []UnitResult {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return []UnitResult{{Path: path, Err: err}}
+	}
+
+	stmts, err := load(string(content))
+	if err != nil {
+		return []UnitResult{{Path: path, Err: err}}
+	}
+
+	var results []UnitResult
+	for _, stmt := range stmts {
+		function, ok := stmt.(ast.FunctionStmt[any])
+		if !ok || !strings.HasPrefix(function.Name.Lexeme, "test") || len(function.Parameters) > 0 {
+			continue
+		}
+
+		var output bytes.Buffer
+		codeInterpreter := interpreter.NewInterpreter()
+		codeInterpreter.SetOutput(&output)
+
+		err := runUnit(codeInterpreter, stmts, function.Name)
+		results = append(results, UnitResult{Path: path, Name: function.Name.Lexeme, Err: err, Output: output.String()})
+	}
+
+	return results
+}
+
+func load(source string) ([]ast.Stmt[any], error) {
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, parseErrors := parser.ParseTokensToStmtsWithErrors(tokens)
+	if len(parseErrors) > 0 {
+		return nil, errors.Join(parseErrors...)
+	}
+
+	codeResolver := resolver.NewResolver()
+	if resolveErrors := codeResolver.Resolve(stmts); len(resolveErrors) > 0 {
+		return nil, errors.Join(resolveErrors...)
+	}
+
+	return stmts, nil
+}
+
+func runUnit(codeInterpreter *interpreter.Interpreter, stmts []ast.Stmt[any], name token.Token) error {
+	for _, stmt := range stmts {
+		err := codeInterpreter.Execute(stmt)
+		if _, ok := err.(*interpreter.BreakError); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	test, err := codeInterpreter.Environment().Get(name)
+	if err != nil {
+		return err
+	}
+
+	function, ok := test.(interpreter.Callable)
+	if !ok {
+		return &interpreter.RuntimeError{Token: name, Message: "'" + name.Lexeme + "' is not a function."}
+	}
+
+	_, err = function.Call(*codeInterpreter, nil)
+	return err
+}
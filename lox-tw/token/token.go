@@ -13,6 +13,9 @@ type Token struct {
 
 	Position uint
 	Line     uint
+
+	// Kept behind a pointer so tokens remain comparable.
+	Trivia *[]Trivia
 }
 
 func NilToken(pos, line uint) Token {
@@ -35,9 +35,14 @@ const (
 	LESS_EQUAL
 )
 
+// Prefixes rest parameters and spread arguments
+const (
+	ELLIPSIS TokenType = iota + LESS_EQUAL + 1
+)
+
 // Literals
 const (
-	IDENTIFIER TokenType = iota + LESS_EQUAL + 1
+	IDENTIFIER TokenType = iota + ELLIPSIS + 1
 	STRING
 	NUMBER
 )
@@ -61,6 +66,8 @@ const (
 	VAR
 	WHILE
 	BREAK
+	ASSERT
+	YIELD
 
 	EOF
 )
@@ -89,6 +96,7 @@ func (t TokenType) String() string {
 		"GREATER_EQUAL",
 		"LESS",
 		"LESS_EQUAL",
+		"ELLIPSIS",
 		"IDENTIFIER",
 		"STRING",
 		"NUMBER",
@@ -109,6 +117,8 @@ func (t TokenType) String() string {
 		"VAR",
 		"WHILE",
 		"BREAK",
+		"ASSERT",
+		"YIELD",
 		"EOF",
 	}[t]
 }
@@ -172,6 +182,8 @@ func TryKeywordTokenType(lexeme string) TokenType {
 		"var":    VAR,
 		"while":  WHILE,
 		"break":  BREAK,
+		"assert": ASSERT,
+		"yield":  YIELD,
 	}[lexeme]
 }
 
@@ -187,3 +199,13 @@ func (t TokenType) In(types ...TokenType) bool {
 func (t TokenType) NotIn(types ...TokenType) bool {
 	return !t.In(types...)
 }
+
+// ParseTokenType is the inverse of TokenType.String.
+func ParseTokenType(name string) (TokenType, bool) {
+	for t := NOTHING; t <= EOF; t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return NOTHING, false
+}
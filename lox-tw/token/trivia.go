@@ -0,0 +1,30 @@
+package token
+
+type TriviaKind uint8
+
+const (
+	LINE_COMMENT TriviaKind = iota
+	BLOCK_COMMENT
+	BLANK_LINE
+)
+
+// Trivia is source text that does not affect the program: comments and blank
+// lines. It is attached to the token that follows it.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Line uint
+
+	// Trailing is set for comments that start on the same line as the
+	// previous token, e.g. `var a = 1; // note`.
+	Trailing bool
+}
+
+// LeadingTrivia returns the trivia found before the token. It is always empty
+// unless the tokens were produced by scanner.ScanTokensWithTrivia.
+func (t Token) LeadingTrivia() []Trivia {
+	if t.Trivia == nil {
+		return nil
+	}
+	return *t.Trivia
+}
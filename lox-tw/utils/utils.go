@@ -1,5 +1,7 @@
 package utils
 
+import "fmt"
+
 func IsTruthy(value any) bool {
 	if value == nil {
 		return false
@@ -11,3 +13,15 @@ func IsTruthy(value any) bool {
 
 	return true
 }
+
+// ArityMessage reports a call with a wrong number of arguments to a callable
+// taking from min to max, or at least min when max is -1.
+func ArityMessage(min, max, count int) string {
+	if max < 0 {
+		return fmt.Sprintf("Expected at least %d arguments but got %d.", min, count)
+	}
+	if min == max {
+		return fmt.Sprintf("Expected %d arguments but got %d.", max, count)
+	}
+	return fmt.Sprintf("Expected %d to %d arguments but got %d.", min, max, count)
+}
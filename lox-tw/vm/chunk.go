@@ -0,0 +1,160 @@
+package vm
+
+type OpCode byte
+
+// Operands follow their instruction in the code: constants, globals and
+// property names are two byte indexes into the constant pool, locals and
+// upvalues one byte slots, jumps two byte offsets.
+const (
+	OP_CONSTANT OpCode = iota
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_GET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_GET_SUPER
+
+	OP_EQUAL
+	OP_GREATER
+	OP_GREATER_EQUAL
+	OP_LESS
+	OP_LESS_EQUAL
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NOT
+	OP_NEGATE
+
+	OP_PRINT
+	OP_JUMP
+	OP_JUMP_IF_FALSE
+	// Jumps only when the value is false itself, not nil, as the condition
+	// of a ternary.
+	OP_JUMP_IF_EXACTLY_FALSE
+	OP_LOOP
+	OP_CALL
+	// Calls with named or spread arguments, described by the CallArguments
+	// constant following the argument count.
+	OP_CALL_UNPACK
+	// Pops the argument of a parameter with a default value and jumps over
+	// the default when the call passed it.
+	OP_JUMP_IF_ARGUMENT
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
+	OP_RETURN
+	// Raises a failed assertion, with the message on the stack when its
+	// operand is 1.
+	OP_ASSERT_FAILED
+	// Replaces the iterable of a for-in loop on top of the stack by its
+	// iterator.
+	OP_ITERATOR
+
+	OP_CLASS
+	OP_INHERIT
+	OP_METHOD
+	OP_STATIC_METHOD
+	OP_GETTER
+	OP_SETTER
+	OP_TRAIT
+	// Copies the methods of the trait on top of the stack into the class
+	// below it.
+	OP_COMPOSE
+)
+
+func (op OpCode) String() string {
+	return [...]string{
+		"OP_CONSTANT",
+		"OP_NIL",
+		"OP_TRUE",
+		"OP_FALSE",
+		"OP_POP",
+		"OP_GET_LOCAL",
+		"OP_SET_LOCAL",
+		"OP_GET_GLOBAL",
+		"OP_DEFINE_GLOBAL",
+		"OP_SET_GLOBAL",
+		"OP_GET_UPVALUE",
+		"OP_SET_UPVALUE",
+		"OP_GET_PROPERTY",
+		"OP_SET_PROPERTY",
+		"OP_GET_SUPER",
+		"OP_EQUAL",
+		"OP_GREATER",
+		"OP_GREATER_EQUAL",
+		"OP_LESS",
+		"OP_LESS_EQUAL",
+		"OP_ADD",
+		"OP_SUBTRACT",
+		"OP_MULTIPLY",
+		"OP_DIVIDE",
+		"OP_NOT",
+		"OP_NEGATE",
+		"OP_PRINT",
+		"OP_JUMP",
+		"OP_JUMP_IF_FALSE",
+		"OP_JUMP_IF_EXACTLY_FALSE",
+		"OP_LOOP",
+		"OP_CALL",
+		"OP_CALL_UNPACK",
+		"OP_JUMP_IF_ARGUMENT",
+		"OP_CLOSURE",
+		"OP_CLOSE_UPVALUE",
+		"OP_RETURN",
+		"OP_ASSERT_FAILED",
+		"OP_ITERATOR",
+		"OP_CLASS",
+		"OP_INHERIT",
+		"OP_METHOD",
+		"OP_STATIC_METHOD",
+		"OP_GETTER",
+		"OP_SETTER",
+		"OP_TRAIT",
+		"OP_COMPOSE",
+	}[op]
+}
+
+// CallArguments describes the arguments of an OP_CALL_UNPACK: the name of
+// each one, empty for positional arguments, and whether it spreads a list.
+type CallArguments struct {
+	Names   []string
+	Spreads []bool
+}
+
+// Chunk is the bytecode of a function.
+type Chunk struct {
+	Code      []byte
+	Constants []any
+
+	// The source line of every byte of code.
+	Lines []uint
+}
+
+func (c *Chunk) Write(b byte, line uint) {
+	c.Code = append(c.Code, b)
+	c.Lines = append(c.Lines, line)
+}
+
+// AddConstant adds a value to the constant pool and returns its index.
+// Strings are only stored once.
+func (c *Chunk) AddConstant(value any) int {
+	if s, ok := value.(string); ok {
+		for i, constant := range c.Constants {
+			if constant == s {
+				return i
+			}
+		}
+	}
+
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
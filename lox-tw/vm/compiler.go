@@ -0,0 +1,359 @@
+package vm
+
+import (
+	"math"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+type local struct {
+	name  string
+	depth int
+
+	// Captured locals are moved off the stack when they go out of scope.
+	captured bool
+}
+
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// loop tracks the breaks of a while loop, patched to jump past its end.
+type loop struct {
+	scopeDepth int
+	breaks     []int
+}
+
+// Compiler compiles the statements of a function to bytecode. Programs must
+// have been resolved first: the compiler relies on the resolver to reject
+// invalid code and only reports the limits of the bytecode.
+type Compiler struct {
+	enclosing *Compiler
+	function  *Function
+
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+	loops      []*loop
+
+	// The line of the code being compiled.
+	line uint
+}
+
+func newCompiler(enclosing *Compiler, kind FunctionKind, name string) *Compiler {
+	c := &Compiler{
+		enclosing: enclosing,
+		function:  &Function{Name: name, Kind: kind},
+	}
+	if enclosing != nil {
+		c.line = enclosing.line
+	}
+
+	// The first slot holds the function being called, or the instance
+	// methods are called on.
+	slot := local{name: ""}
+	if kind == METHOD || kind == INITIALIZER || kind == SETTER {
+		slot.name = "this"
+	}
+	c.locals = append(c.locals, slot)
+
+	return c
+}
+
+// Compile compiles every top-level statement to a script of its own, so the
+// statements following one that fails at runtime can still run, as they do
+// with the tree-walker.
+func Compile(stmts []ast.Stmt[any]) ([]*Function, []error) {
+	var scripts []*Function
+	var errors []error
+
+	for _, stmt := range stmts {
+		c := newCompiler(nil, SCRIPT, "")
+		if err := stmt.Accept(c); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		c.emitReturn()
+		scripts = append(scripts, c.function)
+	}
+
+	return scripts, errors
+}
+
+func (c *Compiler) emit(op OpCode, operands ...byte) {
+	c.emitBytes(byte(op))
+	c.emitBytes(operands...)
+}
+
+func (c *Compiler) emitBytes(bytes ...byte) {
+	for _, b := range bytes {
+		c.function.Chunk.Write(b, c.line)
+	}
+}
+
+func (c *Compiler) emitShort(op OpCode, operand uint16) {
+	c.emit(op, byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) emitReturn() {
+	if c.function.Kind == INITIALIZER {
+		c.emit(OP_GET_LOCAL, 0)
+	} else if c.function.Kind == SETTER {
+		c.emit(OP_GET_LOCAL, 2)
+	} else {
+		c.emit(OP_NIL)
+	}
+	c.emit(OP_RETURN)
+}
+
+func (c *Compiler) makeConstant(value any, at token.Token) (uint16, error) {
+	constant := c.function.Chunk.AddConstant(value)
+	if constant > math.MaxUint16 {
+		return 0, &CompileError{Token: at, Message: "Too many constants in one chunk."}
+	}
+	return uint16(constant), nil
+}
+
+func (c *Compiler) emitConstant(value any, at token.Token) error {
+	constant, err := c.makeConstant(value, at)
+	if err != nil {
+		return err
+	}
+	c.emitShort(OP_CONSTANT, constant)
+	return nil
+}
+
+// emitName emits an instruction taking a name from the constant pool.
+func (c *Compiler) emitName(op OpCode, name token.Token) error {
+	constant, err := c.makeConstant(name.Lexeme, name)
+	if err != nil {
+		return err
+	}
+	c.emitShort(op, constant)
+	return nil
+}
+
+// emitJump emits a jump with a placeholder offset and returns where the
+// offset is, to be patched once the target is known.
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emit(op, 0xff, 0xff)
+	return len(c.function.Chunk.Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int, at token.Token) error {
+	jump := len(c.function.Chunk.Code) - offset - 2
+	if jump > math.MaxUint16 {
+		return &CompileError{Token: at, Message: "Too much code to jump over."}
+	}
+
+	c.function.Chunk.Code[offset] = byte(jump >> 8)
+	c.function.Chunk.Code[offset+1] = byte(jump)
+	return nil
+}
+
+func (c *Compiler) emitLoop(start int, at token.Token) error {
+	offset := len(c.function.Chunk.Code) - start + 3
+	if offset > math.MaxUint16 {
+		return &CompileError{Token: at, Message: "Loop body too large."}
+	}
+
+	c.emitShort(OP_LOOP, uint16(offset))
+	return nil
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		c.popLocal(c.locals[len(c.locals)-1])
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+func (c *Compiler) popLocal(l local) {
+	if l.captured {
+		c.emit(OP_CLOSE_UPVALUE)
+	} else {
+		c.emit(OP_POP)
+	}
+}
+
+func (c *Compiler) addLocal(name token.Token) error {
+	if len(c.locals) > math.MaxUint8 {
+		return &CompileError{Token: name, Message: "Too many local variables in function."}
+	}
+
+	c.locals = append(c.locals, local{name: name.Lexeme, depth: c.scopeDepth})
+	return nil
+}
+
+// defineVariable binds the value on top of the stack to a name, in the
+// current scope.
+func (c *Compiler) defineVariable(name token.Token) error {
+	if c.scopeDepth > 0 {
+		return c.addLocal(name)
+	}
+
+	return c.emitName(OP_DEFINE_GLOBAL, name)
+}
+
+func (c *Compiler) resolveLocal(name string) int {
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(name token.Token) (int, error) {
+	if c.enclosing == nil {
+		return -1, nil
+	}
+
+	if slot := c.enclosing.resolveLocal(name.Lexeme); slot != -1 {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(byte(slot), true, name)
+	}
+
+	index, err := c.enclosing.resolveUpvalue(name)
+	if index == -1 || err != nil {
+		return index, err
+	}
+
+	return c.addUpvalue(byte(index), false, name)
+}
+
+func (c *Compiler) addUpvalue(index byte, isLocal bool, name token.Token) (int, error) {
+	for i, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i, nil
+		}
+	}
+
+	if len(c.upvalues) > math.MaxUint8 {
+		return -1, &CompileError{Token: name, Message: "Too many closure variables in function."}
+	}
+
+	c.upvalues = append(c.upvalues, upvalue{index: index, isLocal: isLocal})
+	c.function.UpvalueCount = len(c.upvalues)
+	return len(c.upvalues) - 1, nil
+}
+
+// variable emits the instruction reading, or assigning when set is true,
+// a local, captured or global variable.
+func (c *Compiler) variable(name token.Token, set bool) error {
+	if slot := c.resolveLocal(name.Lexeme); slot != -1 {
+		if set {
+			c.emit(OP_SET_LOCAL, byte(slot))
+		} else {
+			c.emit(OP_GET_LOCAL, byte(slot))
+		}
+		return nil
+	}
+
+	index, err := c.resolveUpvalue(name)
+	if err != nil {
+		return err
+	}
+	if index != -1 {
+		if set {
+			c.emit(OP_SET_UPVALUE, byte(index))
+		} else {
+			c.emit(OP_GET_UPVALUE, byte(index))
+		}
+		return nil
+	}
+
+	if set {
+		return c.emitName(OP_SET_GLOBAL, name)
+	}
+	return c.emitName(OP_GET_GLOBAL, name)
+}
+
+// compileFunction compiles a function body and emits the closure creating
+// it at runtime.
+func (c *Compiler) compileFunction(kind FunctionKind, name string, at token.Token, parameters []token.Token, defaults []ast.Expr[any], ellipsis *token.Token, body []ast.Stmt[any]) error {
+	compiler := newCompiler(c, kind, name)
+	compiler.line = at.Line
+	compiler.beginScope()
+
+	// The rest parameter comes right after the others on the stack.
+	var rest *token.Token
+	if ellipsis != nil {
+		rest = &parameters[len(parameters)-1]
+		parameters = parameters[:len(parameters)-1]
+		compiler.function.Variadic = true
+	}
+
+	compiler.function.Arity = len(parameters)
+	for i, parameter := range parameters {
+		compiler.function.Parameters = append(compiler.function.Parameters, parameter.Lexeme)
+		if i < len(defaults) && defaults[i] != nil {
+			if err := compiler.defaultValue(i+1, defaults[i], parameter); err != nil {
+				return err
+			}
+		} else {
+			compiler.function.MinArity = i + 1
+		}
+
+		if err := compiler.addLocal(parameter); err != nil {
+			return err
+		}
+	}
+	if rest != nil {
+		if err := compiler.addLocal(*rest); err != nil {
+			return err
+		}
+	}
+	if kind == SETTER {
+		// Copies the value assigned, which the body may reassign.
+		compiler.locals = append(compiler.locals, local{name: "", depth: compiler.scopeDepth})
+		compiler.emit(OP_GET_LOCAL, 1)
+	}
+
+	for _, stmt := range body {
+		if err := stmt.Accept(compiler); err != nil {
+			return err
+		}
+	}
+	compiler.emitReturn()
+
+	constant, err := c.makeConstant(compiler.function, at)
+	if err != nil {
+		return err
+	}
+	c.emitShort(OP_CLOSURE, constant)
+	for _, upvalue := range compiler.upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emitBytes(isLocal, upvalue.index)
+	}
+
+	return nil
+}
+
+// defaultValue emits the code storing the default value of a parameter in
+// its slot when the call skipped it. The parameter isn't declared yet, so its
+// default only sees the parameters before it.
+func (c *Compiler) defaultValue(slot int, value ast.Expr[any], parameter token.Token) error {
+	c.line = parameter.Line
+	c.emit(OP_GET_LOCAL, byte(slot))
+	jump := c.emitJump(OP_JUMP_IF_ARGUMENT)
+
+	if _, err := value.Accept(c); err != nil {
+		return err
+	}
+	c.emit(OP_SET_LOCAL, byte(slot))
+	c.emit(OP_POP)
+
+	return c.patchJump(jump, parameter)
+}
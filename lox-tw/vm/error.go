@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"fmt"
+
+	"lox-tw/token"
+)
+
+// CompileError is raised when a program goes over a limit of the bytecode.
+type CompileError struct {
+	Token   token.Token
+	Message string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+type RuntimeError struct {
+	Line    uint
+	Message string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s\n[line %d]", e.Message, e.Line)
+}
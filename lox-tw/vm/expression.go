@@ -0,0 +1,244 @@
+package vm
+
+import (
+	"math"
+
+	"lox-tw/ast"
+	"lox-tw/token"
+)
+
+func (c *Compiler) VisitAssignExpr(expr ast.AssignExpr[any]) (any, error) {
+	if _, err := expr.Value.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Name.Line
+	return nil, c.variable(expr.Name, true)
+}
+
+func (c *Compiler) VisitGroupingExpr(expr ast.GroupingExpr[any]) (any, error) {
+	return expr.Expression.Accept(c)
+}
+
+func (c *Compiler) VisitTernaryExpr(expr ast.TernaryExpr[any]) (any, error) {
+	if _, err := expr.Condition.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Question.Line
+	falseJump := c.emitJump(OP_JUMP_IF_EXACTLY_FALSE)
+	c.emit(OP_POP)
+	if _, err := expr.TrueExpr.Accept(c); err != nil {
+		return nil, err
+	}
+
+	endJump := c.emitJump(OP_JUMP)
+	if err := c.patchJump(falseJump, expr.Question); err != nil {
+		return nil, err
+	}
+	c.emit(OP_POP)
+	if _, err := expr.FalseExpr.Accept(c); err != nil {
+		return nil, err
+	}
+
+	return nil, c.patchJump(endJump, expr.Question)
+}
+
+var binaryOps = map[token.TokenType][]OpCode{
+	token.PLUS:          {OP_ADD},
+	token.MINUS:         {OP_SUBTRACT},
+	token.STAR:          {OP_MULTIPLY},
+	token.SLASH:         {OP_DIVIDE},
+	token.GREATER:       {OP_GREATER},
+	token.GREATER_EQUAL: {OP_GREATER_EQUAL},
+	token.LESS:          {OP_LESS},
+	token.LESS_EQUAL:    {OP_LESS_EQUAL},
+	token.EQUAL_EQUAL:   {OP_EQUAL},
+	token.BANG_EQUAL:    {OP_EQUAL, OP_NOT},
+}
+
+func (c *Compiler) VisitBinaryExpr(expr ast.BinaryExpr[any]) (any, error) {
+	if _, err := expr.Left.Accept(c); err != nil {
+		return nil, err
+	}
+
+	// The comma operator drops its left operand before evaluating the right.
+	if expr.Operator.Type == token.COMMA {
+		c.emit(OP_POP)
+		return expr.Right.Accept(c)
+	}
+
+	if _, err := expr.Right.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Operator.Line
+	for _, op := range binaryOps[expr.Operator.Type] {
+		c.emit(op)
+	}
+
+	return nil, nil
+}
+
+func (c *Compiler) VisitUnaryExpr(expr ast.UnaryExpr[any]) (any, error) {
+	if _, err := expr.Right.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Operator.Line
+	switch expr.Operator.Type {
+	case token.MINUS:
+		c.emit(OP_NEGATE)
+	case token.BANG:
+		c.emit(OP_NOT)
+	}
+
+	return nil, nil
+}
+
+// Logical operators leave their left operand on the stack when it decides
+// the result.
+func (c *Compiler) VisitLogicalExpr(expr ast.LogicalExpr[any]) (any, error) {
+	if _, err := expr.Left.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Operator.Line
+	var endJump int
+	if expr.Operator.Type == token.OR {
+		elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+		endJump = c.emitJump(OP_JUMP)
+		if err := c.patchJump(elseJump, expr.Operator); err != nil {
+			return nil, err
+		}
+	} else {
+		endJump = c.emitJump(OP_JUMP_IF_FALSE)
+	}
+
+	c.emit(OP_POP)
+	if _, err := expr.Right.Accept(c); err != nil {
+		return nil, err
+	}
+
+	return nil, c.patchJump(endJump, expr.Operator)
+}
+
+func (c *Compiler) VisitLiteralExpr(expr ast.LiteralExpr[any]) (any, error) {
+	c.line = expr.Token.Line
+	switch expr.Value {
+	case nil:
+		c.emit(OP_NIL)
+	case true:
+		c.emit(OP_TRUE)
+	case false:
+		c.emit(OP_FALSE)
+	default:
+		return nil, c.emitConstant(expr.Value, expr.Token)
+	}
+
+	return nil, nil
+}
+
+func (c *Compiler) VisitNothingExpr(expr ast.NothingExpr[any]) (any, error) {
+	c.emit(OP_NIL)
+	return nil, nil
+}
+
+func (c *Compiler) VisitCallExpr(expr ast.CallExpr[any]) (any, error) {
+	if _, err := expr.Callee.Accept(c); err != nil {
+		return nil, err
+	}
+
+	if len(expr.Arguments) > math.MaxUint8 {
+		return nil, &CompileError{Token: expr.Parenthesis, Message: "Can't have more than 255 arguments."}
+	}
+	for _, argument := range expr.Arguments {
+		if _, err := argument.Accept(c); err != nil {
+			return nil, err
+		}
+	}
+
+	c.line = expr.Parenthesis.Line
+	arguments := &CallArguments{}
+	unpack := false
+	for i := range expr.Arguments {
+		name, spread := "", false
+		if i < len(expr.Names) && expr.Names[i] != nil {
+			name = expr.Names[i].Lexeme
+		}
+		if i < len(expr.Spreads) && expr.Spreads[i] != nil {
+			spread = true
+		}
+		arguments.Names = append(arguments.Names, name)
+		arguments.Spreads = append(arguments.Spreads, spread)
+		unpack = unpack || name != "" || spread
+	}
+
+	if !unpack {
+		c.emit(OP_CALL, byte(len(expr.Arguments)))
+		return nil, nil
+	}
+
+	constant, err := c.makeConstant(arguments, expr.Parenthesis)
+	if err != nil {
+		return nil, err
+	}
+	c.emit(OP_CALL_UNPACK, byte(len(expr.Arguments)))
+	c.emitBytes(byte(constant>>8), byte(constant))
+	return nil, nil
+}
+
+func (c *Compiler) VisitLambdaExpr(expr ast.LambdaExpr[any]) (any, error) {
+	c.line = expr.Keyword.Line
+	return nil, c.compileFunction(LAMBDA, "<lambda>", expr.Keyword, expr.Parameters, expr.Defaults, expr.Ellipsis, expr.Body)
+}
+
+func (c *Compiler) VisitVarExpr(expr ast.VarExpr[any]) (any, error) {
+	c.line = expr.Name.Line
+	return nil, c.variable(expr.Name, false)
+}
+
+func (c *Compiler) VisitGetExpr(expr ast.GetExpr[any]) (any, error) {
+	if _, err := expr.Object.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Name.Line
+	return nil, c.emitName(OP_GET_PROPERTY, expr.Name)
+}
+
+func (c *Compiler) VisitSetExpr(expr ast.SetExpr[any]) (any, error) {
+	if _, err := expr.Object.Accept(c); err != nil {
+		return nil, err
+	}
+	if _, err := expr.Value.Accept(c); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Name.Line
+	return nil, c.emitName(OP_SET_PROPERTY, expr.Name)
+}
+
+func (c *Compiler) VisitThisExpr(expr ast.ThisExpr[any]) (any, error) {
+	c.line = expr.Keyword.Line
+	return nil, c.variable(expr.Keyword, false)
+}
+
+func (c *Compiler) VisitInnerExpr(expr ast.InnerExpr[any]) (any, error) {
+	return nil, &CompileError{Token: expr.Keyword, Message: "Inner calls are only supported by the tree-walker."}
+}
+
+func (c *Compiler) VisitSuperExpr(expr ast.SuperExpr[any]) (any, error) {
+	c.line = expr.Keyword.Line
+	this := expr.Keyword
+	this.Lexeme = "this"
+	if err := c.variable(this, false); err != nil {
+		return nil, err
+	}
+	if err := c.variable(expr.Keyword, false); err != nil {
+		return nil, err
+	}
+
+	c.line = expr.Method.Line
+	return nil, c.emitName(OP_GET_SUPER, expr.Method)
+}
@@ -0,0 +1,371 @@
+package vm
+
+import (
+	"lox-tw/ast"
+)
+
+func (c *Compiler) VisitVarStmt(stmt ast.VarStmt[any]) error {
+	if _, err := stmt.Initializer.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Name.Line
+	return c.defineVariable(stmt.Name)
+}
+
+func (c *Compiler) VisitExpressionStmt(stmt ast.ExpressionStmt[any]) error {
+	if _, err := stmt.Expression.Accept(c); err != nil {
+		return err
+	}
+
+	c.emit(OP_POP)
+	return nil
+}
+
+func (c *Compiler) VisitIfStmt(stmt ast.IfStmt[any]) error {
+	if _, err := stmt.Condition.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Keyword.Line
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+	if err := stmt.ThenBranch.Accept(c); err != nil {
+		return err
+	}
+
+	elseJump := c.emitJump(OP_JUMP)
+	if err := c.patchJump(thenJump, stmt.Keyword); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+	if stmt.ElseBranch != nil {
+		if err := stmt.ElseBranch.Accept(c); err != nil {
+			return err
+		}
+	}
+
+	return c.patchJump(elseJump, stmt.Keyword)
+}
+
+func (c *Compiler) VisitWhileStmt(stmt ast.WhileStmt[any]) error {
+	start := len(c.function.Chunk.Code)
+	if _, err := stmt.Condition.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Keyword.Line
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+
+	current := &loop{scopeDepth: c.scopeDepth}
+	c.loops = append(c.loops, current)
+	if err := stmt.Body.Accept(c); err != nil {
+		return err
+	}
+	c.loops = c.loops[:len(c.loops)-1]
+
+	if err := c.emitLoop(start, stmt.Keyword); err != nil {
+		return err
+	}
+	if err := c.patchJump(exitJump, stmt.Keyword); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+
+	for _, jump := range current.breaks {
+		if err := c.patchJump(jump, stmt.Keyword); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// VisitForInStmt keeps the iterator in a hidden local, reads its done
+// property before each iteration and binds what next() returns to the loop
+// variable, a local of its own scope so closures capture one per iteration.
+func (c *Compiler) VisitForInStmt(stmt ast.ForInStmt[any]) error {
+	c.beginScope()
+	if _, err := stmt.Iterable.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Keyword.Line
+	c.emit(OP_ITERATOR)
+	if err := c.addLocal(stmt.Keyword); err != nil {
+		return err
+	}
+	iterator := byte(len(c.locals) - 1)
+	c.locals[iterator].name = ""
+
+	property := func(name string) error {
+		at := stmt.Keyword
+		at.Lexeme = name
+		c.emit(OP_GET_LOCAL, iterator)
+		return c.emitName(OP_GET_PROPERTY, at)
+	}
+
+	start := len(c.function.Chunk.Code)
+	if err := property("done"); err != nil {
+		return err
+	}
+	c.emit(OP_NOT)
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+
+	current := &loop{scopeDepth: c.scopeDepth}
+	c.loops = append(c.loops, current)
+	c.beginScope()
+	if err := property("next"); err != nil {
+		return err
+	}
+	c.emit(OP_CALL, 0)
+	if err := c.addLocal(stmt.Name); err != nil {
+		return err
+	}
+	if err := stmt.Body.Accept(c); err != nil {
+		return err
+	}
+	c.line = stmt.Keyword.Line
+	c.endScope()
+	c.loops = c.loops[:len(c.loops)-1]
+
+	if err := c.emitLoop(start, stmt.Keyword); err != nil {
+		return err
+	}
+	if err := c.patchJump(exitJump, stmt.Keyword); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+
+	for _, jump := range current.breaks {
+		if err := c.patchJump(jump, stmt.Keyword); err != nil {
+			return err
+		}
+	}
+	c.endScope()
+
+	return nil
+}
+
+func (c *Compiler) VisitPrintStmt(stmt ast.PrintStmt[any]) error {
+	if _, err := stmt.Expression.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Keyword.Line
+	c.emit(OP_PRINT)
+	return nil
+}
+
+func (c *Compiler) VisitClassStmt(stmt ast.ClassStmt[any]) error {
+	c.line = stmt.Name.Line
+	if err := c.emitName(OP_CLASS, stmt.Name); err != nil {
+		return err
+	}
+	if err := c.defineVariable(stmt.Name); err != nil {
+		return err
+	}
+
+	if stmt.Superclass != nil {
+		if _, err := stmt.Superclass.Accept(c); err != nil {
+			return err
+		}
+
+		// Methods capture the superclass as the 'super' local of a scope
+		// around them.
+		c.beginScope()
+		if err := c.addLocal(stmt.Superclass.Name); err != nil {
+			return err
+		}
+		c.locals[len(c.locals)-1].name = "super"
+
+		if err := c.variable(stmt.Name, false); err != nil {
+			return err
+		}
+		c.line = stmt.Superclass.Name.Line
+		c.emit(OP_INHERIT)
+	}
+
+	if err := c.variable(stmt.Name, false); err != nil {
+		return err
+	}
+
+	// Methods of traits are copied first, to be overridden by the ones the
+	// class declares.
+	for _, trait := range stmt.Traits {
+		if _, err := trait.Accept(c); err != nil {
+			return err
+		}
+		c.line = trait.Name.Line
+		c.emit(OP_COMPOSE)
+	}
+
+	for _, method := range stmt.Methods {
+		kind := METHOD
+		if method.Name.Lexeme == "init" {
+			kind = INITIALIZER
+		}
+		if err := c.compileFunction(kind, method.Name.Lexeme, method.Name, method.Parameters, method.Defaults, method.Ellipsis, method.Body); err != nil {
+			return err
+		}
+		if err := c.emitName(OP_METHOD, method.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, method := range stmt.GlobalMethods {
+		if err := c.compileFunction(METHOD, method.Name.Lexeme, method.Name, method.Parameters, method.Defaults, method.Ellipsis, method.Body); err != nil {
+			return err
+		}
+		if err := c.emitName(OP_STATIC_METHOD, method.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, getter := range stmt.Getters {
+		if err := c.compileFunction(METHOD, getter.Name.Lexeme, getter.Name, nil, nil, nil, getter.Body); err != nil {
+			return err
+		}
+		if err := c.emitName(OP_GETTER, getter.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, setter := range stmt.Setters {
+		if err := c.compileFunction(SETTER, setter.Name.Lexeme, setter.Name, setter.Parameters, setter.Defaults, setter.Ellipsis, setter.Body); err != nil {
+			return err
+		}
+		if err := c.emitName(OP_SETTER, setter.Name); err != nil {
+			return err
+		}
+	}
+
+	c.emit(OP_POP)
+	if stmt.Superclass != nil {
+		c.endScope()
+	}
+
+	return nil
+}
+
+func (c *Compiler) VisitTraitStmt(stmt ast.TraitStmt[any]) error {
+	c.line = stmt.Name.Line
+	if err := c.emitName(OP_TRAIT, stmt.Name); err != nil {
+		return err
+	}
+	if err := c.defineVariable(stmt.Name); err != nil {
+		return err
+	}
+	if err := c.variable(stmt.Name, false); err != nil {
+		return err
+	}
+
+	for _, method := range stmt.Methods {
+		kind := METHOD
+		if method.Name.Lexeme == "init" {
+			kind = INITIALIZER
+		}
+		if err := c.compileFunction(kind, method.Name.Lexeme, method.Name, method.Parameters, method.Defaults, method.Ellipsis, method.Body); err != nil {
+			return err
+		}
+		if err := c.emitName(OP_METHOD, method.Name); err != nil {
+			return err
+		}
+	}
+
+	c.emit(OP_POP)
+	return nil
+}
+
+func (c *Compiler) VisitBlockStmt(stmt ast.BlockStmt[any]) error {
+	c.beginScope()
+	for _, statement := range stmt.Statements {
+		if err := statement.Accept(c); err != nil {
+			return err
+		}
+	}
+	c.endScope()
+
+	return nil
+}
+
+// A break leaves the scopes of the loop body before jumping past the loop.
+func (c *Compiler) VisitBreakStmt(stmt ast.BreakStmt[any]) error {
+	current := c.loops[len(c.loops)-1]
+
+	c.line = stmt.Keyword.Line
+	for i := len(c.locals) - 1; i >= 0 && c.locals[i].depth > current.scopeDepth; i-- {
+		c.popLocal(c.locals[i])
+	}
+	current.breaks = append(current.breaks, c.emitJump(OP_JUMP))
+
+	return nil
+}
+
+// The message is only evaluated when the assertion fails.
+func (c *Compiler) VisitAssertStmt(stmt ast.AssertStmt[any]) error {
+	if _, err := stmt.Condition.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Keyword.Line
+	failJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+	endJump := c.emitJump(OP_JUMP)
+
+	if err := c.patchJump(failJump, stmt.Keyword); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+	hasMessage := byte(0)
+	if stmt.Message != nil {
+		if _, err := stmt.Message.Accept(c); err != nil {
+			return err
+		}
+		hasMessage = 1
+	}
+	c.line = stmt.Keyword.Line
+	c.emit(OP_ASSERT_FAILED, hasMessage)
+
+	return c.patchJump(endJump, stmt.Keyword)
+}
+
+func (c *Compiler) VisitFunctionStmt(stmt ast.FunctionStmt[any]) error {
+	c.line = stmt.Name.Line
+
+	// Locals are declared before the body is compiled, for it to call the
+	// function recursively.
+	if c.scopeDepth > 0 {
+		if err := c.addLocal(stmt.Name); err != nil {
+			return err
+		}
+		return c.compileFunction(FUNCTION, stmt.Name.Lexeme, stmt.Name, stmt.Parameters, stmt.Defaults, stmt.Ellipsis, stmt.Body)
+	}
+
+	if err := c.compileFunction(FUNCTION, stmt.Name.Lexeme, stmt.Name, stmt.Parameters, stmt.Defaults, stmt.Ellipsis, stmt.Body); err != nil {
+		return err
+	}
+	return c.emitName(OP_DEFINE_GLOBAL, stmt.Name)
+}
+
+func (c *Compiler) VisitReturnStmt(stmt ast.ReturnStmt[any]) error {
+	if stmt.Value == nil {
+		c.line = stmt.Keyword.Line
+		c.emitReturn()
+		return nil
+	}
+
+	if _, err := stmt.Value.Accept(c); err != nil {
+		return err
+	}
+
+	c.line = stmt.Keyword.Line
+	c.emit(OP_RETURN)
+	return nil
+}
+
+func (c *Compiler) VisitYieldStmt(stmt ast.YieldStmt[any]) error {
+	return &CompileError{Token: stmt.Keyword, Message: "Generators are only supported by the tree-walker."}
+}
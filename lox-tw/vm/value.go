@@ -0,0 +1,200 @@
+package vm
+
+import (
+	"time"
+
+	"lox-tw/interpreter"
+)
+
+// Values on the stack are the same Go values the tree-walker uses: nil,
+// bool, float64 and string, or a pointer to one of the objects below.
+
+type FunctionKind uint8
+
+const (
+	SCRIPT FunctionKind = iota
+	FUNCTION
+	LAMBDA
+	METHOD
+	INITIALIZER
+	// Setters return the value assigned, which they keep in a hidden local.
+	SETTER
+)
+
+// Function is a compiled function, shared by all the closures created from
+// it. It takes from MinArity to Arity arguments, the parameters with a
+// default value being optional, and any number more when it is variadic, in
+// a list held by the rest parameter after the others.
+type Function struct {
+	Name         string
+	Kind         FunctionKind
+	Arity        int
+	MinArity     int
+	Variadic     bool
+	Parameters   []string
+	UpvalueCount int
+	Chunk        Chunk
+}
+
+// maxArity returns the most arguments the function takes, -1 when it is
+// variadic.
+func (f *Function) maxArity() int {
+	if f.Variadic {
+		return -1
+	}
+	return f.Arity
+}
+
+// missingArgument stands on the stack for the argument of a parameter with a
+// default value that the call skipped.
+type missingArgument struct{}
+
+func (f *Function) String() string {
+	switch f.Kind {
+	case SCRIPT:
+		return "<script>"
+	case LAMBDA:
+		return "<lambda fn>"
+	}
+	return "<fn " + f.Name + ">"
+}
+
+// Upvalue is a variable captured by a closure. It points into the stack
+// while the variable is alive there, and holds the value once closed.
+type Upvalue struct {
+	slot   int
+	closed bool
+	value  any
+}
+
+type Closure struct {
+	Function *Function
+	Upvalues []*Upvalue
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+type Class struct {
+	Name       string
+	Superclass *Class
+	Methods    map[string]*Closure
+	Getters    map[string]*Closure
+	Setters    map[string]*Closure
+
+	// Methods declared with 'class' are looked up on this instance of the
+	// metaclass, when metaclasses are enabled.
+	instance *Instance
+}
+
+func NewClass(name string) *Class {
+	metaclass := &Class{Name: name + " metaclass", Methods: make(map[string]*Closure)}
+	return &Class{
+		Name:     name,
+		Methods:  make(map[string]*Closure),
+		Getters:  make(map[string]*Closure),
+		Setters:  make(map[string]*Closure),
+		instance: NewInstance(metaclass),
+	}
+}
+
+func (c *Class) FindMethod(name string) *Closure {
+	for class := c; class != nil; class = class.Superclass {
+		if method, ok := class.Methods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+func (c *Class) FindGetter(name string) *Closure {
+	for class := c; class != nil; class = class.Superclass {
+		if getter, ok := class.Getters[name]; ok {
+			return getter
+		}
+	}
+	return nil
+}
+
+func (c *Class) FindSetter(name string) *Closure {
+	for class := c; class != nil; class = class.Superclass {
+		if setter, ok := class.Setters[name]; ok {
+			return setter
+		}
+	}
+	return nil
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+// Trait is a bundle of methods, copied into the classes composed with it.
+type Trait struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func (t *Trait) String() string {
+	return t.Name
+}
+
+type Instance struct {
+	Class  *Class
+	Fields map[string]any
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{Class: class, Fields: make(map[string]any)}
+}
+
+func (i *Instance) String() string {
+	return i.Class.Name + " instance"
+}
+
+// BoundMethod is a method read from an instance, called with that instance
+// as 'this'.
+type BoundMethod struct {
+	Receiver any
+	Method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
+
+// Native is a function implemented in Go. Arity is -1 for natives taking any
+// number of arguments.
+type Native struct {
+	Arity    int
+	Function func(vm *VM, arguments []any) (any, error)
+}
+
+func (n *Native) String() string {
+	return "<native fn>"
+}
+
+func natives() map[string]any {
+	return map[string]any{
+		"clock": &Native{Arity: 0, Function: func(vm *VM, arguments []any) (any, error) {
+			return float64(time.Now().UnixMilli()), nil
+		}},
+		"str": &Native{Arity: 1, Function: func(vm *VM, arguments []any) (any, error) {
+			return vm.stringify(arguments[0])
+		}},
+		"list": &Native{Arity: -1, Function: func(vm *VM, arguments []any) (any, error) {
+			return interpreter.NewList(arguments), nil
+		}},
+		"map": &Native{Arity: 0, Function: func(vm *VM, arguments []any) (any, error) {
+			return interpreter.NewMap(), nil
+		}},
+		"range": &Native{Arity: -1, Function: func(vm *VM, arguments []any) (any, error) {
+			r, err := interpreter.NewRange(arguments)
+			if err != nil {
+				return nil, vm.error("%s", err)
+			}
+			return r, nil
+		}},
+	}
+}
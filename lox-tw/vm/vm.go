@@ -0,0 +1,798 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"lox-tw/interpreter"
+	"lox-tw/utils"
+)
+
+// MAX_FRAMES bounds the depth of calls, deeper recursion being reported as
+// a stack overflow.
+const MAX_FRAMES = 1 << 20
+
+type CallFrame struct {
+	closure *Closure
+	ip      int
+
+	// The stack slot of the function called, its locals follow.
+	base int
+}
+
+// VM runs compiled scripts on a stack. Globals outlive a script, for a
+// program to run as a sequence of scripts.
+type VM struct {
+	frames  []CallFrame
+	stack   []any
+	globals map[string]any
+
+	// The upvalues still pointing into the stack, ordered by slot.
+	openUpvalues []*Upvalue
+
+	metaclasses bool
+	stdout      io.Writer
+
+	// The instances whose toString method is running.
+	converting map[*Instance]bool
+}
+
+func NewVM() *VM {
+	return &VM{
+		globals:     natives(),
+		metaclasses: os.Getenv("METACLASSES_ENABLED") == "true",
+		stdout:      os.Stdout,
+		converting:  make(map[*Instance]bool),
+	}
+}
+
+// SetOutput changes where print statements write to.
+func (vm *VM) SetOutput(stdout io.Writer) {
+	vm.stdout = stdout
+}
+
+// Run runs a script returned by Compile. After a runtime error, the stack is
+// reset for the next script.
+func (vm *VM) Run(script *Function) error {
+	closure := &Closure{Function: script}
+	vm.push(closure)
+	err := vm.call(closure, 0)
+	if err == nil {
+		err = vm.run(0)
+	}
+
+	if err != nil {
+		vm.closeUpvalues(0)
+		vm.frames = vm.frames[:0]
+		vm.stack = vm.stack[:0]
+	}
+
+	return err
+}
+
+func (vm *VM) push(value any) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() any {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+func (vm *VM) error(format string, arguments ...any) error {
+	frame := &vm.frames[len(vm.frames)-1]
+	return &RuntimeError{
+		Line:    frame.closure.Function.Chunk.Lines[frame.ip-1],
+		Message: fmt.Sprintf(format, arguments...),
+	}
+}
+
+// run runs the frames on top of the given number of frames, until they
+// return. The result of the last one is left on the stack, unless it is a
+// script.
+func (vm *VM) run(depth int) error {
+	frame := &vm.frames[len(vm.frames)-1]
+	chunk := &frame.closure.Function.Chunk
+
+	readByte := func() byte {
+		b := chunk.Code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() uint16 {
+		frame.ip += 2
+		return uint16(chunk.Code[frame.ip-2])<<8 | uint16(chunk.Code[frame.ip-1])
+	}
+	readString := func() string {
+		return chunk.Constants[readShort()].(string)
+	}
+	// Frames are pushed and popped by calls and returns.
+	loadFrame := func() {
+		frame = &vm.frames[len(vm.frames)-1]
+		chunk = &frame.closure.Function.Chunk
+	}
+
+	for {
+		switch OpCode(readByte()) {
+		case OP_CONSTANT:
+			vm.push(chunk.Constants[readShort()])
+		case OP_NIL:
+			vm.push(nil)
+		case OP_TRUE:
+			vm.push(true)
+		case OP_FALSE:
+			vm.push(false)
+		case OP_POP:
+			vm.pop()
+
+		case OP_GET_LOCAL:
+			vm.push(vm.stack[frame.base+int(readByte())])
+		case OP_SET_LOCAL:
+			vm.stack[frame.base+int(readByte())] = vm.peek(0)
+		case OP_GET_GLOBAL:
+			name := readString()
+			value, ok := vm.globals[name]
+			if !ok {
+				return vm.error("Undefined variable '%s'.", name)
+			}
+			vm.push(value)
+		case OP_DEFINE_GLOBAL:
+			vm.globals[readString()] = vm.pop()
+		case OP_SET_GLOBAL:
+			name := readString()
+			if _, ok := vm.globals[name]; !ok {
+				return vm.error("Undefined variable '%s'.", name)
+			}
+			vm.globals[name] = vm.peek(0)
+		case OP_GET_UPVALUE:
+			upvalue := frame.closure.Upvalues[readByte()]
+			if upvalue.closed {
+				vm.push(upvalue.value)
+			} else {
+				vm.push(vm.stack[upvalue.slot])
+			}
+		case OP_SET_UPVALUE:
+			upvalue := frame.closure.Upvalues[readByte()]
+			if upvalue.closed {
+				upvalue.value = vm.peek(0)
+			} else {
+				vm.stack[upvalue.slot] = vm.peek(0)
+			}
+
+		case OP_GET_PROPERTY:
+			name := readString()
+			if object, ok := vm.peek(0).(interpreter.Object); ok {
+				value, err := vm.objectProperty(object, name)
+				if err != nil {
+					return err
+				}
+				vm.stack[len(vm.stack)-1] = value
+				break
+			}
+			instance, ok := vm.peek(0).(*Instance)
+			if class, isClass := vm.peek(0).(*Class); isClass && vm.metaclasses {
+				instance, ok = class.instance, true
+			}
+			if !ok {
+				return vm.error("Only instances have properties.")
+			}
+
+			if getter := vm.getter(instance, name); getter != nil {
+				vm.stack[len(vm.stack)-1] = instance
+				if err := vm.call(getter, 0); err != nil {
+					return err
+				}
+				loadFrame()
+				break
+			}
+
+			value, err := vm.getProperty(instance, name)
+			if err != nil {
+				return err
+			}
+			vm.pop()
+			vm.push(value)
+		case OP_SET_PROPERTY:
+			name := readString()
+			instance, ok := vm.peek(1).(*Instance)
+			if !ok {
+				return vm.error("Only instances have fields.")
+			}
+
+			if setter := instance.Class.FindSetter(name); setter != nil {
+				if err := vm.call(setter, 1); err != nil {
+					return err
+				}
+				loadFrame()
+				break
+			}
+
+			value := vm.pop()
+			instance.Fields[name] = value
+			vm.pop()
+			vm.push(value)
+		case OP_GET_SUPER:
+			name := readString()
+			superclass := vm.pop().(*Class)
+			receiver := vm.pop()
+
+			method := superclass.FindMethod(name)
+			if method == nil {
+				return vm.error("Undefined property '%s'.", name)
+			}
+			vm.push(&BoundMethod{Receiver: receiver, Method: method})
+
+		case OP_EQUAL:
+			called, err := vm.callOperator(OP_EQUAL, 1)
+			if !called && err == nil {
+				// Equality is symmetric, the right operand may overload it too.
+				top := len(vm.stack) - 1
+				vm.stack[top], vm.stack[top-1] = vm.stack[top-1], vm.stack[top]
+				called, err = vm.callOperator(OP_EQUAL, 1)
+			}
+			if err != nil {
+				return err
+			}
+			if called {
+				loadFrame()
+				break
+			}
+
+			b, a := vm.pop(), vm.pop()
+			vm.push(a == b)
+		case OP_GREATER, OP_GREATER_EQUAL, OP_LESS, OP_LESS_EQUAL, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE:
+			op := OpCode(chunk.Code[frame.ip-1])
+			a, ok := vm.peek(1).(float64)
+			b, ok2 := vm.peek(0).(float64)
+			if !ok || !ok2 {
+				if called, err := vm.callOperator(op, 1); called || err != nil {
+					if err != nil {
+						return err
+					}
+					loadFrame()
+					break
+				}
+				return vm.error("Operands must be numbers.")
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(arithmetic(op, a, b))
+		case OP_ADD:
+			switch a := vm.peek(1).(type) {
+			case float64:
+				if b, ok := vm.peek(0).(float64); ok {
+					vm.stack = vm.stack[:len(vm.stack)-2]
+					vm.push(a + b)
+					continue
+				}
+			case string:
+				if b, ok := vm.peek(0).(string); ok {
+					vm.stack = vm.stack[:len(vm.stack)-2]
+					vm.push(a + b)
+					continue
+				}
+			}
+			if called, err := vm.callOperator(OP_ADD, 1); called || err != nil {
+				if err != nil {
+					return err
+				}
+				loadFrame()
+				break
+			}
+
+			// Strings are concatenated with instances converted by toString.
+			_, leftString := vm.peek(1).(string)
+			_, rightString := vm.peek(0).(string)
+			if leftString && toString(vm.peek(0)) != nil || rightString && toString(vm.peek(1)) != nil {
+				b, a := vm.pop(), vm.pop()
+				left, err := vm.stringify(a)
+				if err != nil {
+					return err
+				}
+				right, err := vm.stringify(b)
+				if err != nil {
+					return err
+				}
+				loadFrame()
+				vm.push(left + right)
+				break
+			}
+			return vm.error("Operands must be two numbers or two strings.")
+		case OP_NOT:
+			vm.push(!utils.IsTruthy(vm.pop()))
+		case OP_NEGATE:
+			value, ok := vm.peek(0).(float64)
+			if !ok {
+				if called, err := vm.callOperator(OP_NEGATE, 0); called || err != nil {
+					if err != nil {
+						return err
+					}
+					loadFrame()
+					break
+				}
+				return vm.error("Operand must be a number.")
+			}
+			vm.stack[len(vm.stack)-1] = -value
+
+		case OP_PRINT:
+			text, err := vm.stringify(vm.pop())
+			if err != nil {
+				return err
+			}
+			loadFrame()
+			fmt.Fprintln(vm.stdout, text)
+		case OP_JUMP:
+			offset := readShort()
+			frame.ip += int(offset)
+		case OP_JUMP_IF_FALSE:
+			offset := readShort()
+			if !utils.IsTruthy(vm.peek(0)) {
+				frame.ip += int(offset)
+			}
+		case OP_JUMP_IF_EXACTLY_FALSE:
+			offset := readShort()
+			if value, ok := vm.peek(0).(bool); ok && !value {
+				frame.ip += int(offset)
+			}
+		case OP_LOOP:
+			offset := readShort()
+			frame.ip -= int(offset)
+		case OP_CALL:
+			argumentCount := int(readByte())
+			if err := vm.callValue(vm.peek(argumentCount), argumentCount); err != nil {
+				return err
+			}
+			loadFrame()
+		case OP_CALL_UNPACK:
+			argumentCount := int(readByte())
+			arguments := chunk.Constants[readShort()].(*CallArguments)
+			argumentCount, err := vm.unpackArguments(argumentCount, arguments)
+			if err != nil {
+				return err
+			}
+			if err := vm.callValue(vm.peek(argumentCount), argumentCount); err != nil {
+				return err
+			}
+			loadFrame()
+		case OP_JUMP_IF_ARGUMENT:
+			offset := readShort()
+			if _, ok := vm.pop().(missingArgument); !ok {
+				frame.ip += int(offset)
+			}
+		case OP_CLOSURE:
+			function := chunk.Constants[readShort()].(*Function)
+			closure := &Closure{Function: function, Upvalues: make([]*Upvalue, function.UpvalueCount)}
+			for i := range closure.Upvalues {
+				isLocal, index := readByte(), int(readByte())
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OP_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.stack = vm.stack[:frame.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				return nil
+			}
+
+			vm.push(result)
+			if len(vm.frames) == depth {
+				return nil
+			}
+			loadFrame()
+		case OP_ASSERT_FAILED:
+			message := "Assertion failed."
+			if readByte() == 1 {
+				message = "Assertion failed: " + interpreter.Stringify(vm.pop())
+			}
+			return vm.error("%s", message)
+		case OP_ITERATOR:
+			if err := vm.iterator(); err != nil {
+				return err
+			}
+			loadFrame()
+
+		case OP_CLASS:
+			vm.push(NewClass(readString()))
+		case OP_INHERIT:
+			superclass, ok := vm.peek(1).(*Class)
+			if !ok {
+				return vm.error("Superclass must be a class.")
+			}
+
+			class := vm.pop().(*Class)
+			class.Superclass = superclass
+			class.instance.Class.Superclass = superclass
+		case OP_METHOD:
+			method := vm.pop().(*Closure)
+			switch target := vm.peek(0).(type) {
+			case *Class:
+				target.Methods[readString()] = method
+			case *Trait:
+				target.Methods[readString()] = method
+			}
+		case OP_STATIC_METHOD:
+			method := vm.pop().(*Closure)
+			vm.peek(0).(*Class).instance.Class.Methods[readString()] = method
+		case OP_TRAIT:
+			vm.push(&Trait{Name: readString(), Methods: make(map[string]*Closure)})
+		case OP_COMPOSE:
+			trait, ok := vm.peek(0).(*Trait)
+			if !ok {
+				return vm.error("Can only compose traits.")
+			}
+
+			vm.pop()
+			maps.Copy(vm.peek(0).(*Class).Methods, trait.Methods)
+		case OP_GETTER:
+			getter := vm.pop().(*Closure)
+			vm.peek(0).(*Class).Getters[readString()] = getter
+		case OP_SETTER:
+			setter := vm.pop().(*Closure)
+			vm.peek(0).(*Class).Setters[readString()] = setter
+		}
+	}
+}
+
+func arithmetic(op OpCode, a, b float64) any {
+	switch op {
+	case OP_GREATER:
+		return a > b
+	case OP_GREATER_EQUAL:
+		return a >= b
+	case OP_LESS:
+		return a < b
+	case OP_LESS_EQUAL:
+		return a <= b
+	case OP_SUBTRACT:
+		return a - b
+	case OP_MULTIPLY:
+		return a * b
+	default:
+		return a / b
+	}
+}
+
+// operatorMethods are the methods of instances overloading the operators.
+var operatorMethods = map[OpCode]string{
+	OP_ADD:           "__add__",
+	OP_SUBTRACT:      "__sub__",
+	OP_MULTIPLY:      "__mul__",
+	OP_DIVIDE:        "__div__",
+	OP_GREATER:       "__gt__",
+	OP_GREATER_EQUAL: "__ge__",
+	OP_LESS:          "__lt__",
+	OP_LESS_EQUAL:    "__le__",
+	OP_EQUAL:         "__eq__",
+	OP_NEGATE:        "__neg__",
+}
+
+// callOperator calls the method overloading an operator when the operand
+// below its arguments is an instance of a class declaring it, and reports
+// whether it did. The operand becomes the receiver of the call.
+func (vm *VM) callOperator(op OpCode, argumentCount int) (bool, error) {
+	instance, ok := vm.peek(argumentCount).(*Instance)
+	if !ok {
+		return false, nil
+	}
+	method := instance.Class.FindMethod(operatorMethods[op])
+	if method == nil {
+		return false, nil
+	}
+
+	return true, vm.call(method, argumentCount)
+}
+
+// stringify converts a value like print does, calling the toString method of
+// instances declaring one. An instance converted again by its own toString is
+// shown as usual, instead of recursing forever.
+func (vm *VM) stringify(value any) (string, error) {
+	method := toString(value)
+	if method == nil || vm.converting[value.(*Instance)] {
+		return interpreter.Stringify(value), nil
+	}
+
+	instance := value.(*Instance)
+	vm.converting[instance] = true
+	defer delete(vm.converting, instance)
+
+	result, err := vm.callNested(method, instance)
+	if err != nil {
+		return "", err
+	}
+	return vm.stringify(result)
+}
+
+// toString returns the toString method of an instance, if its class declares
+// one.
+func toString(value any) *Closure {
+	if instance, ok := value.(*Instance); ok {
+		return instance.Class.FindMethod("toString")
+	}
+	return nil
+}
+
+// callNested calls a method without arguments from Go, in the middle of an
+// instruction, and runs it until it returns. The frame of the instruction
+// must be reloaded afterwards.
+func (vm *VM) callNested(method *Closure, receiver any) (any, error) {
+	depth := len(vm.frames)
+	vm.push(receiver)
+
+	if err := vm.call(method, 0); err != nil {
+		return nil, err
+	}
+	if err := vm.run(depth); err != nil {
+		return nil, err
+	}
+	return vm.pop(), nil
+}
+
+// getter returns the getter of a property, unless a field hides it.
+func (vm *VM) getter(instance *Instance, name string) *Closure {
+	if value, ok := instance.Fields[name]; ok && value != nil {
+		return nil
+	}
+	return instance.Class.FindGetter(name)
+}
+
+// getProperty reads a field, or binds a method, of an instance. Fields set
+// to nil are looked up as methods, as the tree-walker does.
+func (vm *VM) getProperty(instance *Instance, name string) (any, error) {
+	if value, ok := instance.Fields[name]; ok && value != nil {
+		return value, nil
+	}
+
+	if method := instance.Class.FindMethod(name); method != nil {
+		return &BoundMethod{Receiver: instance, Method: method}, nil
+	}
+
+	return nil, vm.error("Undefined property '%s'.", name)
+}
+
+func (vm *VM) callValue(callee any, argumentCount int) error {
+	switch callee := callee.(type) {
+	case *Closure:
+		return vm.call(callee, argumentCount)
+	case *BoundMethod:
+		vm.stack[len(vm.stack)-1-argumentCount] = callee.Receiver
+		return vm.call(callee.Method, argumentCount)
+	case *Class:
+		instance := NewInstance(callee)
+		vm.stack[len(vm.stack)-1-argumentCount] = instance
+		if initializer := callee.FindMethod("init"); initializer != nil {
+			return vm.call(initializer, argumentCount)
+		}
+		if argumentCount != 0 {
+			return vm.error("%s", utils.ArityMessage(0, 0, argumentCount))
+		}
+		return nil
+	case *Native:
+		if callee.Arity >= 0 && argumentCount != callee.Arity {
+			return vm.error("%s", utils.ArityMessage(callee.Arity, callee.Arity, argumentCount))
+		}
+		arguments := vm.stack[len(vm.stack)-argumentCount:]
+		result, err := callee.Function(vm, arguments)
+		if err != nil {
+			return err
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argumentCount-1]
+		vm.push(result)
+		return nil
+	}
+
+	return vm.error("Can only call functions and classes.")
+}
+
+// unpackArguments spreads the lists passed with an ellipsis, then binds the
+// named arguments, and returns the number of arguments on the stack
+// afterwards.
+func (vm *VM) unpackArguments(argumentCount int, arguments *CallArguments) (int, error) {
+	switch vm.peek(argumentCount).(type) {
+	case *Closure, *BoundMethod, *Class, *Native:
+	default:
+		return 0, vm.error("Can only call functions and classes.")
+	}
+
+	var values []any
+	var names []string
+	for i, value := range vm.stack[len(vm.stack)-argumentCount:] {
+		if !arguments.Spreads[i] {
+			values = append(values, value)
+			names = append(names, arguments.Names[i])
+			continue
+		}
+
+		list, ok := value.(*interpreter.List)
+		if !ok {
+			return 0, vm.error("Can only spread lists.")
+		}
+		values = append(values, list.Elements...)
+		names = append(names, make([]string, len(list.Elements))...)
+	}
+	vm.stack = append(vm.stack[:len(vm.stack)-argumentCount], values...)
+
+	if !slices.ContainsFunc(names, func(name string) bool { return name != "" }) {
+		return len(values), nil
+	}
+	return vm.bindArguments(vm.peek(len(values)), len(values), names)
+}
+
+// bindArguments moves the named arguments of a call to the position of their
+// parameter, leaving the skipped ones missing, and returns the number of
+// arguments on the stack afterwards. Positional arguments past the parameters
+// follow them, for the rest parameter.
+func (vm *VM) bindArguments(callee any, argumentCount int, names []string) (int, error) {
+	var function *Function
+	switch callee := callee.(type) {
+	case *Closure:
+		function = callee.Function
+	case *BoundMethod:
+		function = callee.Method.Function
+	case *Class:
+		if initializer := callee.FindMethod("init"); initializer != nil {
+			function = initializer.Function
+		} else {
+			function = &Function{}
+		}
+	default:
+		return 0, vm.error("Native functions don't take named arguments.")
+	}
+
+	if !function.Variadic && argumentCount > function.Arity {
+		return 0, vm.error("%s", utils.ArityMessage(function.MinArity, function.Arity, argumentCount))
+	}
+
+	arguments := make([]any, function.Arity)
+	for i := range arguments {
+		arguments[i] = missingArgument{}
+	}
+	for i, value := range vm.stack[len(vm.stack)-argumentCount:] {
+		if names[i] == "" && i >= function.Arity {
+			arguments = append(arguments, value)
+			continue
+		}
+		if names[i] == "" {
+			arguments[i] = value
+			continue
+		}
+
+		index := slices.Index(function.Parameters, names[i])
+		if index == -1 {
+			return 0, vm.error("Unexpected argument '%s'.", names[i])
+		}
+		if _, ok := arguments[index].(missingArgument); !ok {
+			return 0, vm.error("Argument '%s' is passed twice.", names[i])
+		}
+		arguments[index] = value
+	}
+
+	for i := range function.MinArity {
+		if _, ok := arguments[i].(missingArgument); ok {
+			return 0, vm.error("Missing argument '%s'.", function.Parameters[i])
+		}
+	}
+
+	vm.stack = append(vm.stack[:len(vm.stack)-argumentCount], arguments...)
+	return len(arguments), nil
+}
+
+func (vm *VM) call(closure *Closure, argumentCount int) error {
+	function := closure.Function
+	if argumentCount < function.MinArity || !function.Variadic && argumentCount > function.Arity {
+		return vm.error("%s", utils.ArityMessage(function.MinArity, function.maxArity(), argumentCount))
+	}
+	if len(vm.frames) == MAX_FRAMES {
+		return vm.error("Stack overflow.")
+	}
+
+	// The arguments past the parameters are collected in the rest parameter,
+	// and the default values of the ones left out are filled in by the
+	// function itself.
+	var rest *interpreter.List
+	if function.Variadic {
+		rest = interpreter.NewList(nil)
+		if argumentCount > function.Arity {
+			rest = interpreter.NewList(vm.stack[len(vm.stack)-argumentCount+function.Arity:])
+			vm.stack = vm.stack[:len(vm.stack)-argumentCount+function.Arity]
+		}
+	}
+	for range function.Arity - min(argumentCount, function.Arity) {
+		vm.push(missingArgument{})
+	}
+	argumentCount = function.Arity
+	if rest != nil {
+		vm.push(rest)
+		argumentCount++
+	}
+
+	vm.frames = append(vm.frames, CallFrame{
+		closure: closure,
+		base:    len(vm.stack) - 1 - argumentCount,
+	})
+	return nil
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	i := len(vm.openUpvalues)
+	for i > 0 && vm.openUpvalues[i-1].slot >= slot {
+		if vm.openUpvalues[i-1].slot == slot {
+			return vm.openUpvalues[i-1]
+		}
+		i--
+	}
+
+	upvalue := &Upvalue{slot: slot}
+	vm.openUpvalues = append(vm.openUpvalues, nil)
+	copy(vm.openUpvalues[i+1:], vm.openUpvalues[i:])
+	vm.openUpvalues[i] = upvalue
+	return upvalue
+}
+
+// closeUpvalues moves the variables captured from the stack, from slot
+// upwards, into their upvalues.
+func (vm *VM) closeUpvalues(slot int) {
+	i := len(vm.openUpvalues)
+	for i > 0 && vm.openUpvalues[i-1].slot >= slot {
+		upvalue := vm.openUpvalues[i-1]
+		upvalue.value = vm.stack[upvalue.slot]
+		upvalue.closed = true
+		i--
+	}
+	vm.openUpvalues = vm.openUpvalues[:i]
+}
+
+// objectProperty reads a field of a built-in object, as the length of a
+// list, or one of its methods, bound to the object.
+func (vm *VM) objectProperty(object interpreter.Object, name string) (any, error) {
+	if value, ok := object.Field(name); ok {
+		return value, nil
+	}
+
+	arity, ok := object.MethodArity(name)
+	if !ok {
+		return nil, vm.error("Undefined property '%s'.", name)
+	}
+	return &Native{Arity: arity, Function: func(vm *VM, arguments []any) (any, error) {
+		result, err := object.CallMethod(name, arguments)
+		if err != nil {
+			return nil, vm.error("%s", err)
+		}
+		return result, nil
+	}}, nil
+}
+
+// iterator replaces the iterable on top of the stack by its iterator. The
+// iterator method of an instance is called, its frame must be loaded
+// afterwards.
+func (vm *VM) iterator() error {
+	if instance, ok := vm.peek(0).(*Instance); ok && instance.Class.FindMethod("iterator") != nil {
+		method, err := vm.getProperty(instance, "iterator")
+		if err != nil {
+			return err
+		}
+		return vm.callValue(method, 0)
+	}
+
+	iterator, ok := interpreter.Iterate(vm.peek(0))
+	if !ok {
+		return vm.error("Can only iterate over lists, maps, strings, ranges and instances with an iterator method.")
+	}
+	vm.stack[len(vm.stack)-1] = iterator
+	return nil
+}
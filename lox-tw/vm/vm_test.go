@@ -0,0 +1,266 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"lox-tw/internal/testutil"
+)
+
+// walk runs a program on the tree-walker and returns what it prints,
+// followed by its runtime errors.
+func walk(t *testing.T, source string) string {
+	t.Helper()
+	return testutil.Run(t, source)
+}
+
+func run(t *testing.T, source string) string {
+	stmts := testutil.Parse(t, source)
+	scripts, errors := Compile(stmts)
+	if len(errors) > 0 {
+		t.Fatalf("Error compiling statements: %v", errors)
+	}
+
+	var output strings.Builder
+	machine := NewVM()
+	machine.SetOutput(&output)
+	for _, script := range scripts {
+		if err := machine.Run(script); err != nil {
+			output.WriteString(err.Error() + "\n")
+		}
+	}
+	return output.String()
+}
+
+func TestSameAsTreeWalker(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"Arithmetic", `print 1 + 2 * 3 - 4 / 8; print -(3); print "a" + "b"; print 0/0 == 0/0; print 1 != 2;`},
+		{"Comparisons", `print 1 < 2; print 2 <= 2; print 3 > 4; print 4 >= 5; print 0/0 <= 0/0;`},
+		{"Truthiness", `print !nil; print !0; print !""; print nil or "x"; print false and 1; print 1 and 2;`},
+		{"Ternary and comma", `print nil ? "yes" : "no"; print false ? "yes" : "no"; print (1, 2, 3);`},
+		{"Globals", `var a; print a; a = 1; var a = a + 1; print a;`},
+		{"Scopes", `var a = "global"; { var a = "outer"; { var a = "inner"; print a; } print a; } print a;`},
+		{"While and for", `var i = 0; while (i < 3) { print i; i = i + 1; } for (var j = 0; j < 2; j = j + 1) print j;`},
+		{"Break", `for (var i = 0; ; i = i + 1) { var x = i * 2; { var y = x; if (y > 4) break; } print x; } print "done";`},
+		{"Recursion", `fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); } print fib(15);`},
+		{"Local functions", `{ fun even(n) { if (n == 0) return true; return odd(n - 1); } fun odd(n) { if (n == 0) return false; return even(n - 1); } print even(10); }`},
+		{"Closures", `
+fun counter() { var i = 0; fun count() { i = i + 1; return i; } return count; }
+var a = counter(); var b = counter();
+a(); a(); b();
+print a(); print b();`},
+		{"Shared upvalues", `
+var get; var set;
+{ var x = 1; fun g() { return x; } fun s(v) { x = v; } get = g; set = s; }
+set(5); print get();`},
+		{"Loop variables are shared", `
+var fns;
+for (var i = 0; i < 3; i = i + 1) { var j = i; fun f() { return j + i; } if (i == 1) fns = f; }
+print fns();`},
+		{"Nested closures", `fun a() { var x = "x"; fun b() { fun c() { return x; } return c; } return b; } print a()()();`},
+		{"Lambdas", `var x = "x"; var show = fun (a, b) { print a + b + x; }; show("a", "b"); print show; print fun () {}();`},
+		{"Classes", `
+class Point {
+    init(x, y) { this.x = x; this.y = y; }
+    sum() { return this.x + this.y; }
+}
+var p = Point(1, 2);
+print p.sum(); print p; print Point; print p.sum;
+p.x = 10; print p.sum();
+print p.init(3, 4) == p; print p.x;`},
+		{"Bound methods", `
+class A { m() { return this; } }
+var a = A(); var m = a.m;
+print m() == a; print a.m == a.m;`},
+		{"Fields set to nil hide nothing", `class A { m() { return "method"; } } var a = A(); a.m = nil; print a.m();`},
+		{"Inheritance", `
+class A { init(n) { this.n = n; } name() { return "A" + this.n; } who() { return this.name(); } }
+class B < A { name() { return "B" + super.name(); } }
+class C < B { init() { super.init("c"); } }
+print C().who();`},
+		{"Super in closures", `
+class A { m() { return "A"; } }
+class B < A { m() { fun f() { return super.m() + "B"; } return f; } }
+print B().m()();`},
+		{"Getters and setters", `
+class Celsius {
+    init(degrees) { this.degrees = degrees; }
+    fahrenheit { return this.degrees * 9 / 5 + 32; }
+    fahrenheit=(value) { value = value - 32; this.degrees = value * 5 / 9; }
+}
+class Thermometer < Celsius { label { return "at " + this.fahrenheit; } }
+var t = Thermometer(100);
+print t.fahrenheit; print t.fahrenheit = 32; print t.degrees; print t.label;
+t.label = "hidden"; print t.label;
+print t.missing = 1;`},
+		{"Getter errors", `class A { broken { return -"a"; } } print A().broken; print "after";`},
+		{"Traits", `
+trait Named { name() { return "I am " + this.title(); } greet() { return "hi"; } }
+trait Counted { count() { return 1; } }
+class Base { greet() { return "base"; } }
+class Person < Base with Named, Counted { title() { return "a person"; } count() { return 2; } }
+var p = Person();
+print p.name(); print p.greet(); print p.count(); print Named;
+var NotTrait = "x";
+class Bad with NotTrait {}
+print Named();`},
+		{"Operator overloading", `
+class Money {
+    init(cents) { this.cents = cents; }
+    __add__(other) { return Money(this.cents + other.cents); }
+    __sub__(other) { return Money(this.cents - other.cents); }
+    __mul__(factor) { return Money(this.cents * factor); }
+    __div__(divisor) { return Money(this.cents / divisor); }
+    __neg__() { return Money(-this.cents); }
+    __eq__(other) { return other != nil and this.cents == other.cents; }
+    __lt__(other) { return this.cents < other.cents; }
+    __le__(other) { return this.cents <= other.cents; }
+    __gt__(other) { return this.cents > other.cents; }
+    __ge__(other) { return this.cents >= other.cents; }
+}
+var a = Money(100); var b = Money(250);
+print (a + b).cents; print (b - a).cents; print (a * 3).cents; print (b / 2).cents; print (-a).cents;
+print a == Money(100); print a != Money(100); print a == b; print nil == a;
+print a < b; print a <= b; print a > b; print a >= b;
+class Plain {} var p = Plain();
+print p == p; print p == Plain(); print p + p;
+class Wrong { __add__() {} } Wrong() + 1;`},
+		{"String conversion", `
+class Point {
+    init(x, y) { this.x = x; this.y = y; }
+    toString() { return "(" + str(this.x) + ", " + str(this.y) + ")"; }
+}
+class Loop { toString() { return "Loop(" + str(this) + ")"; } }
+class Plain {}
+print Point(1, 2); print "at " + Point(3, 4); print Point(5, 6) + "!";
+print Loop(); print str(Plain()) + str(nil) + str(1.5);
+class Broken { toString() { return -"a"; } }
+print Broken();
+print "a" + Plain();`},
+		{"Type annotations", `
+class Box {
+    init(size: Number) { this.size = size; }
+    area: Number { return this.size * this.size; }
+}
+fun describe(box: Box, unit): String { return str(box.area) + unit; }
+var label: String = describe(Box(3), "m2");
+var wrong: Number = "ignored at runtime";
+print label; print wrong;`},
+		{"Default and named arguments", `
+fun greet(name, greeting = "Hello", punct = "!") { print greeting + ", " + name + punct; }
+greet("Ada"); greet("Ada", "Hi"); greet("Ada", punct: "?"); greet(punct: ".", name: "Bob");
+class Point {
+    init(x, y = x * 2) { this.x = x; this.y = y; }
+    sum(z = this.x) { return this.x + this.y + z; }
+}
+var p = Point(y: 1, x: 2); print p.y; print Point(3).sum(); print p.sum(z: 10);
+fun outer(a, show = fun () { print a; }) { show(); } outer(5);
+greet(); greet("a", "b", "c", "d"); greet("a", mood: 1); greet("a", name: "b");
+greet(greeting: "Hi"); clock(at: 1); Point(z: 1);`},
+		{"Rest parameters and spread arguments", `
+fun log(format, ...args) { print format + str(args); }
+log("none "); log("some ", 1, "two", nil);
+fun add(a, b = 10, ...rest) { return a + b + rest.length; }
+var xs = list(1, 2, 3, 4);
+print add(1); print add(...xs); print add(b: 2, a: 1); print str(...list("one"));
+class Bag { init(name, ...items) { this.items = items; } }
+var bag = Bag("bag", 1, 2); print bag.items; bag.items.push(bag.items); print bag.items;
+var count = fun (...all) { print all.length; }; count(); count(...bag.items);
+print xs.get(3); print xs.set(0, "zero"); print xs; print list();
+add(); add(...1); add(rest: 1); 1(...xs); xs.get(4); xs.get(0.5); xs.pop();`},
+		{"Lambdas returning values", `
+var twice = fun (x) { return x * 2; };
+class A { init() { this.f = fun () { return "lambda"; }; } }
+print twice(2); print A().f();`},
+		{"For-in loops", `
+for (var x in list(1, "two")) print x;
+for (var c in "ab") print c;
+for (var i in range(4, 0, -2)) print i;
+var ages = map(); ages.set("ann", 31); ages.set("bob", 27);
+for (var name in ages) print name + " " + str(ages.get(name));
+print ages; print ages.length; print ages.remove("ann"); print ages.has("ann"); print range(3);
+class Countdown {
+    init(from) { this.from = from; }
+    iterator() { return CountdownIterator(this.from); }
+}
+class CountdownIterator {
+    init(n) { this.n = n; }
+    done { return this.n == 0; }
+    next() { this.n = this.n - 1; return this.n + 1; }
+}
+for (var n in Countdown(2)) { var twice = n * 2; print twice; }
+var printers = list();
+for (var i in range(3)) {
+    for (var j in range(3)) { if (j > i) break; printers.push(fun () { print str(i) + str(j); }); }
+    if (i == 1) break;
+}
+for (var p in printers) p();
+range(1, "2"); range(0, 1, 0); range(); ages.pop();`},
+		{"Initializer returns", `class A { init() { this.x = 1; return; } } print A().init().x;`},
+		{"Assertions", `assert true; assert 1 == 2, "one is " + "one"; print "next"; assert nil;`},
+		{"Runtime errors", `
+print "before";
+print -"a";
+print 1 < "b";
+print "a" + 1;
+print undefined;
+undefined = 1;
+nil();
+fun f(a) {} f();
+class A {} A(1);
+A().x;
+print nil.x;
+nil.x = 1;
+var NotClass = "A";
+class B < NotClass {}
+print "after";`},
+		{"Errors unwind calls", `
+fun inner() { var local = "x"; return local + 1; }
+fun outer() { return inner(); }
+outer();
+print "still running";`},
+		{"Upvalues survive errors", `
+var f;
+{ var x = "kept"; fun g() { return x; } f = g; print 1 + nil; }
+print f();`},
+		{"Clock", `print clock() > 0; print clock;`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := walk(t, test.source)
+			if actual := run(t, test.source); actual != expected {
+				t.Errorf("Expected the output of the tree-walker:\n%s\nGot:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestMetaclasses(t *testing.T) {
+	t.Setenv("METACLASSES_ENABLED", "true")
+
+	source := `
+class Math {
+    class square(n) { return n * n; }
+    class self() { return this; }
+}
+print Math.square(3);
+print Math.self();
+print Math.missing;`
+
+	expected := walk(t, source)
+	if actual := run(t, source); actual != expected {
+		t.Errorf("Expected the output of the tree-walker:\n%s\nGot:\n%s", expected, actual)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	output := run(t, `fun f() { f(); } f(); print "after";`)
+	expected := "Stack overflow.\n[line 1]\nafter\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}